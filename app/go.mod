@@ -4,19 +4,23 @@ go 1.23
 
 require (
 	github.com/AllenDang/giu v0.6.2
+	github.com/AllenDang/imgui-go v1.12.1-0.20220322114136-499bbf6a42ad
+	github.com/charmbracelet/lipgloss v0.6.0
 	github.com/f1gopher/f1gopherlib v1.0.1-0.20250204213939-97fae3f70445
+	github.com/gorilla/mux v1.8.0
 	github.com/hajimehoshi/go-mp3 v0.3.4
 	github.com/hajimehoshi/oto/v2 v2.3.1
+	github.com/sqweek/dialog v0.0.0-20220809060634-e981b270ebbf
+	github.com/ungerik/go-cairo v0.0.0-20220815093914-e24bd4259cef
+	go.uber.org/zap v1.24.0
 	golang.org/x/image v0.0.0-20220302094943-723b81ca9867
 )
 
 require (
 	github.com/AllenDang/go-findfont v0.0.0-20200702051237-9f180485aeb8 // indirect
-	github.com/AllenDang/imgui-go v1.12.1-0.20220322114136-499bbf6a42ad // indirect
 	github.com/TheTitanrain/w32 v0.0.0-20180517000239-4f5cfb03fabf // indirect
 	github.com/aymanbagabas/go-osc52 v1.0.3 // indirect
 	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
-	github.com/charmbracelet/lipgloss v0.6.0 // indirect
 	github.com/chzyer/readline v1.5.1 // indirect
 	github.com/f1gopher/signalr/v2 v2.0.0-20221210121059-1985aaf5fb97 // indirect
 	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3 // indirect
@@ -26,7 +30,6 @@ require (
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20220320163800-277f93cfa958 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/google/pprof v0.0.0-20230111200839-76d1ae5aea2b // indirect
-	github.com/gorilla/mux v1.8.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/ianlancetaylor/demangle v0.0.0-20220517205856-0058ec4f073c // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
@@ -38,11 +41,8 @@ require (
 	github.com/pkg/profile v1.7.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/sahilm/fuzzy v0.1.0 // indirect
-	github.com/sqweek/dialog v0.0.0-20220809060634-e981b270ebbf // indirect
-	github.com/ungerik/go-cairo v0.0.0-20220815093914-e24bd4259cef // indirect
 	go.uber.org/atomic v1.10.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
-	go.uber.org/zap v1.24.0 // indirect
 	golang.org/x/net v0.27.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
@@ -94,28 +94,65 @@ func (c *catching) Init(dataSrc f1gopherlib.F1GopherLib, config PanelConfig) {
 func (c *catching) Close() {}
 
 func (c *catching) ProcessDrivers(data Messages.Drivers) {
+	// The drivers can be sent again when someone is added or changes so keep the lap times so far
 	for x := range data.Drivers {
-		driver := &catchingInfo{
-			color:    data.Drivers[x].Color,
-			name:     data.Drivers[x].ShortName,
-			team:     data.Drivers[x].Team,
-			lapTimes: []time.Duration{},
-			visible:  true,
+		driver, exists := c.driverData[data.Drivers[x].Number]
+		if !exists {
+			driver = &catchingInfo{
+				lapTimes: []time.Duration{},
+				visible:  true,
+			}
+			c.driverData[data.Drivers[x].Number] = driver
 		}
-		c.driverData[data.Drivers[x].Number] = driver
 
-		c.driverNames = append(c.driverNames, data.Drivers[x].ShortName)
+		driver.color = data.Drivers[x].Color
+		driver.name = data.Drivers[x].ShortName
+		driver.team = data.Drivers[x].Team
 	}
 
+	c.driverNames = []string{}
+	for _, driver := range c.driverData {
+		c.driverNames = append(c.driverNames, driver.name)
+	}
 	sort.Strings(c.driverNames)
 
-	// +1 because 0 will be empty because positions aren't zero based
-	c.driverOrder = make([]int, len(c.driverNames)+1)
+	// The names may have moved so select the same drivers again
+	for x := range c.blocks {
+		if c.blocks[x].selectedDriver1Index != NothingSelected {
+			c.blocks[x].selectedDriver1Index = c.driverNameIndex(c.blocks[x].selectedDriver1Number)
+		}
+		if c.blocks[x].selectedDriver2Index != NothingSelected {
+			c.blocks[x].selectedDriver2Index = c.driverNameIndex(c.blocks[x].selectedDriver2Number)
+		}
+	}
+
+	// +1 because 0 will be empty because positions aren't zero based. Keep the order so far and put new drivers
+	// where they start.
+	order := make([]int, len(c.driverNames)+1)
+	copy(order, c.driverOrder)
+	c.driverOrder = order
 	for x := range data.Drivers {
-		c.driverOrder[data.Drivers[x].StartPosition] = data.Drivers[x].Number
+		position := data.Drivers[x].StartPosition
+		if position < len(c.driverOrder) && c.driverOrder[position] == 0 {
+			c.driverOrder[position] = data.Drivers[x].Number
+		}
 	}
 }
 
+func (c *catching) driverNameIndex(number int) int32 {
+	driver, exists := c.driverData[number]
+	if !exists {
+		return NothingSelected
+	}
+
+	for x := range c.driverNames {
+		if c.driverNames[x] == driver.name {
+			return int32(x)
+		}
+	}
+	return NothingSelected
+}
+
 func (c *catching) ProcessEvent(data Messages.Event) {
 	c.lap = data.CurrentLap
 }
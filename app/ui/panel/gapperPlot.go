@@ -98,35 +98,58 @@ func (g *gapperPlot) Init(dataSrc f1gopherlib.F1GopherLib, config PanelConfig) {
 }
 
 func (g *gapperPlot) ProcessDrivers(data Messages.Drivers) {
+	// The drivers can be sent again when someone is added or changes so keep the lap times so far
+	firstDrivers := len(g.driverData) == 0
 	for x := range data.Drivers {
-		driver := &gapperPlotInfo{
-			color:    data.Drivers[x].Color,
-			name:     data.Drivers[x].ShortName,
-			lapTimes: []float64{},
-			fastest:  math.MaxFloat64,
-			visible:  true,
+		driver, exists := g.driverData[data.Drivers[x].Number]
+		if !exists {
+			driver = &gapperPlotInfo{
+				lapTimes: []float64{},
+				fastest:  math.MaxFloat64,
+				visible:  true,
+			}
+			g.driverData[data.Drivers[x].Number] = driver
+			g.visibleDriversSelect.drivers = append(g.visibleDriversSelect.drivers, driver)
 		}
-		g.driverData[data.Drivers[x].Number] = driver
-		g.visibleDriversSelect.drivers = append(g.visibleDriversSelect.drivers, driver)
 
-		g.driverNames = append(g.driverNames, data.Drivers[x].ShortName)
+		driver.color = data.Drivers[x].Color
+		driver.name = data.Drivers[x].ShortName
 	}
 
+	g.driverNames = []string{}
+	for _, driver := range g.driverData {
+		g.driverNames = append(g.driverNames, driver.name)
+	}
 	sort.Strings(g.driverNames)
 
-	// After loading the drivers auto select the first driver in the list as a default
-	g.selectedDriver = 0
-	for num, driver := range g.driverData {
-		if driver.name == g.driverNames[g.selectedDriver] {
-			g.selectedDriverNumber = num
-			break
+	if firstDrivers {
+		// After loading the drivers auto select the first driver in the list as a default
+		g.selectedDriver = 0
+		for num, driver := range g.driverData {
+			if driver.name == g.driverNames[g.selectedDriver] {
+				g.selectedDriverNumber = num
+				break
+			}
+		}
+	} else if driver, exists := g.driverData[g.selectedDriverNumber]; exists {
+		// The names may have moved so select the same driver again
+		for x := range g.driverNames {
+			if g.driverNames[x] == driver.name {
+				g.selectedDriver = int32(x)
+				break
+			}
 		}
 	}
 
 	sort.Slice(g.visibleDriversSelect.drivers, func(i, j int) bool {
 		return g.visibleDriversSelect.drivers[i].name < g.visibleDriversSelect.drivers[j].name
 	})
-	g.visibleDriversSelect.visibleCount = len(g.visibleDriversSelect.drivers)
+	g.visibleDriversSelect.visibleCount = 0
+	for _, driver := range g.visibleDriversSelect.drivers {
+		if driver.visible {
+			g.visibleDriversSelect.visibleCount++
+		}
+	}
 }
 
 func (g *gapperPlot) ProcessEvent(data Messages.Event) {
@@ -74,16 +74,21 @@ func (r *racePosition) Init(dataSrc f1gopherlib.F1GopherLib, config PanelConfig)
 }
 
 func (r *racePosition) ProcessDrivers(data Messages.Drivers) {
+	// The drivers can be sent again when someone is added or changes so keep the positions so far
 	for x := range data.Drivers {
-		driverInfo := &info{
-			color:     data.Drivers[x].Color,
-			number:    data.Drivers[x].Number,
-			name:      data.Drivers[x].ShortName,
-			positions: []int{data.Drivers[x].StartPosition},
+		driverInfo, exists := r.driverData[data.Drivers[x].Number]
+		if !exists {
+			driverInfo = &info{
+				number:    data.Drivers[x].Number,
+				positions: []int{data.Drivers[x].StartPosition},
+			}
+
+			r.driverData[data.Drivers[x].Number] = driverInfo
+			r.orderedData = append(r.orderedData, driverInfo)
 		}
 
-		r.driverData[data.Drivers[x].Number] = driverInfo
-		r.orderedData = append(r.orderedData, driverInfo)
+		driverInfo.color = data.Drivers[x].Color
+		driverInfo.name = data.Drivers[x].ShortName
 	}
 
 	sort.Slice(r.orderedData, func(i, j int) bool {
@@ -0,0 +1,50 @@
+package Messages
+
+import (
+	"time"
+)
+
+type CarStateSource int
+
+const (
+	// The telemetry is from the sample and the location is interpolated
+	FromTelemetry CarStateSource = iota
+	// The location is from the sample and the telemetry is interpolated
+	FromLocation
+)
+
+func (c CarStateSource) String() string {
+	return [...]string{"Telemetry", "Location"}[c]
+}
+
+// CarState is the telemetry and location for a car at the same time. There is one for every telemetry and every
+// location sample with the other feed interpolated to the time of the sample. Every car has car states, including
+// samples while the car is turned off, whichever drivers are selected for the telemetry channel.
+type CarState struct {
+	Timestamp    time.Time
+	DriverNumber int
+	Source       CarStateSource
+
+	// False when there was no telemetry close enough in time to use
+	TelemetryKnown bool
+	RPM            int16
+	// km/h
+	Speed    float32
+	Gear     byte
+	Throttle float32
+	Brake    float32
+	BrakeOn  bool
+	DRS      bool
+	DRSState CarDRS
+
+	// False when there was no location close enough in time to use
+	LocationKnown    bool
+	X                float64
+	Y                float64
+	Z                float64
+	LapDistance      float64
+	LapFraction      float64
+	LapDistanceKnown bool
+	// Set when the location sample is the first after crossing the finish line to the time it was crossed
+	FinishLineCrossing time.Time
+}
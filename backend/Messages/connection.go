@@ -0,0 +1,28 @@
+package Messages
+
+import (
+	"time"
+)
+
+type ConnectionStatus int
+
+const (
+	Connecting ConnectionStatus = iota
+	Connected
+	Reconnecting
+	Disconnected
+)
+
+func (c ConnectionStatus) String() string {
+	return [...]string{"Connecting", "Connected", "Reconnecting", "Disconnected"}[c]
+}
+
+// ConnectionState is sent when the connection to the live data changes
+type ConnectionState struct {
+	Timestamp time.Time
+	Status    ConnectionStatus
+	// Which attempt this is when reconnecting, starting at 1
+	Attempt int
+	// Why the connection was lost or the last attempt failed
+	Error string
+}
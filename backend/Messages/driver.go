@@ -6,17 +6,25 @@ import (
 )
 
 type DriverInfo struct {
+	// The line when the driver was first in the list
 	StartPosition int
-	Name          string
-	ShortName     string
-	Number        int
-	Team          string
-	HexColor      string
-	Color         color.RGBA
+	// Current line in the driver list
+	Line      int
+	Name      string
+	ShortName string
+	Number    int
+	Team      string
+	HexColor  string
+	Color     color.RGBA
 }
 
 type Drivers struct {
 	Timestamp time.Time
 
+	// Every driver so far, in number order
 	Drivers []DriverInfo
+	// Driver numbers that are new in this update, which is everyone for the first list
+	Added []int
+	// Driver numbers whose details changed in this update
+	Changed []int
 }
@@ -0,0 +1,85 @@
+package Messages
+
+import (
+	"time"
+)
+
+type Kind int
+
+const (
+	WeatherKind Kind = iota
+	RaceControlMessageKind
+	TimingKind
+	EventKind
+	TelemetryKind
+	LocationKind
+	EventTimeKind
+	RadioKind
+	DriversKind
+	CarStateKind
+)
+
+func (k Kind) String() string {
+	return [...]string{"Weather", "RaceControlMessage", "Timing", "Event", "Telemetry", "Location", "EventTime", "Radio", "Drivers", "CarState"}[k]
+}
+
+// Payload is a message that can be sent in an envelope. Only the message types in this package are payloads.
+type Payload interface {
+	payloadKind() Kind
+	payloadTimestamp() time.Time
+}
+
+// Envelope wraps every message in the order the parser produced them, which the flow control keeps even though it
+// sends the different message types at different rates, so the interleaving between them can be replayed exactly. Envelopes are sent for every message, including ones that were dropped or coalesced on their
+// own channel because the consumer wasn't keeping up.
+type Envelope struct {
+	Kind      Kind
+	Timestamp time.Time
+	// Increases by one for each envelope sent so gaps show where envelopes were missed
+	Sequence uint64
+	Payload  Payload
+}
+
+// PayloadTimestamp is when the message happened
+func PayloadTimestamp(payload Payload) time.Time {
+	return payload.payloadTimestamp()
+}
+
+func CreateEnvelope(payload Payload, sequence uint64) Envelope {
+	return Envelope{
+		Kind:      payload.payloadKind(),
+		Timestamp: payload.payloadTimestamp(),
+		Sequence:  sequence,
+		Payload:   payload,
+	}
+}
+
+func (m Weather) payloadKind() Kind           { return WeatherKind }
+func (m Weather) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m RaceControlMessage) payloadKind() Kind           { return RaceControlMessageKind }
+func (m RaceControlMessage) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m Timing) payloadKind() Kind           { return TimingKind }
+func (m Timing) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m Event) payloadKind() Kind           { return EventKind }
+func (m Event) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m Telemetry) payloadKind() Kind           { return TelemetryKind }
+func (m Telemetry) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m Location) payloadKind() Kind           { return LocationKind }
+func (m Location) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m EventTime) payloadKind() Kind           { return EventTimeKind }
+func (m EventTime) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m Radio) payloadKind() Kind           { return RadioKind }
+func (m Radio) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m Drivers) payloadKind() Kind           { return DriversKind }
+func (m Drivers) payloadTimestamp() time.Time { return m.Timestamp }
+
+func (m CarState) payloadKind() Kind           { return CarStateKind }
+func (m CarState) payloadTimestamp() time.Time { return m.Timestamp }
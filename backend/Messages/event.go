@@ -14,10 +14,12 @@ const (
 	SprintSession
 	RaceSession
 	PreSeasonSession
+	// The short qualifying before a sprint, called the sprint shootout in 2023
+	SprintQualifyingSession
 )
 
 func (s SessionType) String() string {
-	return [...]string{"Practice 1", "Practice 2", "Practice 3", "Qualifying", "Sprint", "Race", "Pre-Season Test"}[s]
+	return [...]string{"Practice 1", "Practice 2", "Practice 3", "Qualifying", "Sprint", "Race", "Pre-Season Test", "Sprint Qualifying"}[s]
 }
 
 type EventType int
@@ -117,6 +119,8 @@ type Event struct {
 	Sector3Segments int
 	TotalSegments   int
 	SegmentFlags    [MaxSegments]FlagState
+	// The fastest sectors and mini-sectors by anyone, the ideal lap is the theoretical fastest lap
+	SessionBest BestSectors
 
 	PitExitOpen bool
 	TrackStatus FlagState
@@ -127,4 +131,9 @@ type Event struct {
 	ClockStopped     bool
 
 	DRSEnabled DRSState
+
+	// For qualifying, the number of cars that progress from this part and the slowest lap that does. The cutoff is
+	// 0 until enough cars have a time and there is no knockout in the last part.
+	ProgressingCars int
+	KnockoutCutoff  int64
 }
@@ -11,4 +11,12 @@ type Location struct {
 	X            float64
 	Y            float64
 	Z            float64
+
+	// Metres round the lap from the finish line and how far round the lap that is from 0 to 1. Only known once a lap
+	// has been recorded to measure against, which is the first lap a car completes without going into the pitlane.
+	LapDistance      float64
+	LapFraction      float64
+	LapDistanceKnown bool
+	// Set on the first location after crossing the finish line to the time it was crossed
+	FinishLineCrossing time.Time
 }
@@ -4,14 +4,106 @@ import (
 	"time"
 )
 
+type CarDRS int
+
+const (
+	CarDRSOff CarDRS = iota
+	// Close enough to the car ahead in an activation zone to open it
+	CarDRSEligible
+	CarDRSOpen
+	CarDRSUnknown
+)
+
+func (c CarDRS) String() string {
+	return [...]string{"Off", "Eligible", "Open", "Unknown"}[c]
+}
+
 type Telemetry struct {
 	Timestamp    time.Time
 	DriverNumber int
 
-	RPM      int16
-	Speed    float32
-	Gear     byte
+	RPM int16
+	// km/h
+	Speed float32
+	// 0 is neutral
+	Gear byte
+	// Percent, the feed sends more than 100 when it doesn't have a value
 	Throttle float32
-	Brake    float32
+	// As sent by the feed, which only has on or off rather than the pressure. 0 or 100, or 0 or 1 in older data.
+	Brake float32
+	// The brake is on
+	BrakeOn bool
+	// Open
 	DRS      bool
+	DRSState CarDRS
+	// The value from the feed the DRS state is worked out from
+	DRSValue byte
+}
+
+// TelemetryChannelInfo describes a telemetry value so a UI can draw it without knowing about each one
+type TelemetryChannelInfo struct {
+	Name string
+	// The Telemetry field with the value, for clients reading the messages as JSON
+	Field string
+	// Channel id in the CarData feed
+	FeedId string
+	Unit   string
+	Min    float64
+	Max    float64
+	// Only has a few values, like the gear, rather than a range
+	Discrete bool
+	Value    func(t Telemetry) float64 `json:"-"`
+}
+
+// TelemetryChannels is every channel in the telemetry. These are all the channels the car data feed sends, the parser
+// reports any other channel id as a parse error the first time it sees it so a new one would be noticed.
+var TelemetryChannels = []TelemetryChannelInfo{
+	{
+		Name:   "RPM",
+		Field:  "RPM",
+		FeedId: "0",
+		Unit:   "rpm",
+		Max:    15000,
+		Value:  func(t Telemetry) float64 { return float64(t.RPM) },
+	},
+	{
+		Name:   "Speed",
+		Field:  "Speed",
+		FeedId: "2",
+		Unit:   "km/h",
+		Max:    360,
+		Value:  func(t Telemetry) float64 { return float64(t.Speed) },
+	},
+	{
+		Name:     "Gear",
+		Field:    "Gear",
+		FeedId:   "3",
+		Max:      8,
+		Discrete: true,
+		Value:    func(t Telemetry) float64 { return float64(t.Gear) },
+	},
+	{
+		Name:   "Throttle",
+		Field:  "Throttle",
+		FeedId: "4",
+		Unit:   "%",
+		Max:    100,
+		Value:  func(t Telemetry) float64 { return float64(t.Throttle) },
+	},
+	{
+		Name:   "Brake",
+		Field:  "Brake",
+		FeedId: "5",
+		Unit:   "%",
+		Max:    100,
+		Value:  func(t Telemetry) float64 { return float64(t.Brake) },
+	},
+	{
+		Name:     "DRS",
+		Field:    "DRSState",
+		FeedId:   "45",
+		Max:      float64(CarDRSUnknown),
+		Discrete: true,
+		Value:    func(t Telemetry) float64 { return float64(t.DRSState) },
+	},
 }
@@ -1,6 +1,7 @@
 package Messages
 
 import (
+	"fmt"
 	"image/color"
 	"time"
 )
@@ -63,6 +64,33 @@ type PitStop struct {
 	PitlaneTime  time.Duration
 }
 
+// MiniSector is the status and time for a mini-sector on one lap
+type MiniSector struct {
+	Status SegmentType
+	// Milliseconds from passing the end of the previous mini-sector, 0 when it isn't known. The feed doesn't send
+	// mini-sector times so this is from when the status changed which is only as accurate as the timing messages.
+	Time int64
+}
+
+// LapSectors is the sector and mini-sector times for one lap
+type LapSectors struct {
+	Lap         int
+	Sector1     int64
+	Sector2     int64
+	Sector3     int64
+	MiniSectors [MaxSegments]MiniSector
+}
+
+// BestSectors are the fastest sector and mini-sector times, either for one driver or for everyone
+type BestSectors struct {
+	Sector1     int64
+	Sector2     int64
+	Sector3     int64
+	MiniSectors [MaxSegments]int64
+	// The best sectors added together, 0 until every sector has a time
+	IdealLap int64
+}
+
 type Timing struct {
 	Timestamp time.Time
 
@@ -78,8 +106,14 @@ type Timing struct {
 	TimeDiffToFastest       int64
 	TimeDiffToPositionAhead int64
 	GapToLeader             int64
+	// For lapped cars the gaps are in laps and the times are 0
+	IntervalLaps    int
+	GapToLeaderLaps int
+	// The lap the leader is on, only set for the leader
+	LeaderLap int
 
 	PreviousSegmentIndex   int
+	PreviousSegmentTime    time.Time
 	Segment                [MaxSegments]SegmentType
 	Sector1                int64
 	Sector1PersonalFastest bool
@@ -97,9 +131,22 @@ type Timing struct {
 	FastestLap        int64
 	OverallFastestLap bool
 
+	// The lap in progress, and the lap just finished which is only set in the message for the update that finished it
+	CurrentLapSectors LapSectors
+	CompletedLap      *LapSectors
+	PersonalBest      BestSectors
+
 	KnockedOutOfQualifying bool
 	ChequeredFlag          bool
 
+	// Best lap in each part of qualifying
+	QualifyingBestLaps [3]int64
+	// Would be knocked out if this part of qualifying ended now
+	InDropZone bool
+	// How much faster than their best lap in this part the driver needs to go to get out of the drop zone. 0 when
+	// not in the drop zone or without a time in this part.
+	TimeToProgress int64
+
 	Tire       TireType
 	LapsOnTire int
 	Lap        int
@@ -115,3 +162,34 @@ type Timing struct {
 	SpeedTrapPersonalFastest bool
 	SpeedTrapOverallFastest  bool
 }
+
+// FormatGap is a gap in milliseconds the way the timing screens show it, "+1 L" for a lapped car, otherwise
+// "+0.812" or "+1:02.003". Empty when there isn't a gap.
+func FormatGap(gap int64, laps int) string {
+	if laps > 0 {
+		return fmt.Sprintf("+%d L", laps)
+	}
+
+	if gap <= 0 {
+		return ""
+	}
+
+	duration := time.Duration(gap) * time.Millisecond
+	minutes := int(duration / time.Minute)
+	seconds := (duration % time.Minute).Seconds()
+	if minutes > 0 {
+		return fmt.Sprintf("+%d:%06.3f", minutes, seconds)
+	}
+
+	return fmt.Sprintf("+%.3f", seconds)
+}
+
+// GapToLeaderText is the gap to the leader for display
+func (t Timing) GapToLeaderText() string {
+	return FormatGap(t.GapToLeader, t.GapToLeaderLaps)
+}
+
+// IntervalText is the gap to the car ahead for display
+func (t Timing) IntervalText() string {
+	return FormatGap(t.TimeDiffToPositionAhead, t.IntervalLaps)
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+type Role int
+
+const (
+	NoRole Role = iota
+	// Viewer can list sessions and subscribe to them
+	Viewer
+	// Controller can also pause and skip through the shared sessions
+	Controller
+)
+
+func (r Role) String() string {
+	return [...]string{"None", "Viewer", "Controller"}[r]
+}
+
+const roleKey = "role"
+
+const apiKeyHeader = "X-API-Key"
+
+// Browsers can't set headers when opening a websocket so the key can also be passed as a query parameter
+const apiKeyQueryParam = "token"
+
+type Config struct {
+	// API keys mapped to the role they give. If there are no keys every request is rejected unless the config is
+	// insecure, then every request is treated as a controller.
+	Keys map[string]Role
+
+	// Origins allowed to make cross origin requests and open websockets. If there are none only requests without an
+	// origin are allowed unless the config is insecure, then every origin is allowed.
+	AllowedOrigins []string
+
+	// Insecure opens up everything that isn't configured, for development only
+	Insecure bool
+}
+
+func (c *Config) Enabled() bool {
+	return len(c.Keys) > 0
+}
+
+// Middleware works out the role for the request from the API key and rejects requests with a missing or unknown key.
+// Requests for the public paths are always allowed.
+func (c *Config) Middleware(publicPaths ...string) echo.MiddlewareFunc {
+	public := make(map[string]bool)
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if public[ctx.Path()] {
+				return next(ctx)
+			}
+
+			if !c.Enabled() {
+				if !c.Insecure {
+					return echo.NewHTTPError(http.StatusUnauthorized, "no API keys configured")
+				}
+
+				ctx.Set(roleKey, Controller)
+				return next(ctx)
+			}
+
+			role := c.roleForKey(requestKey(ctx))
+			if role == NoRole {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing or invalid API key")
+			}
+
+			ctx.Set(roleKey, role)
+			return next(ctx)
+		}
+	}
+}
+
+// RedactKey hides the API key query parameter in the request uri so it doesn't end up in the access log. The key is
+// still read from the parsed url. Must be used before the logger.
+func RedactKey() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			query := request.URL.Query()
+			if query.Has(apiKeyQueryParam) {
+				query.Set(apiKeyQueryParam, "redacted")
+				redacted := *request.URL
+				redacted.RawQuery = query.Encode()
+				request.RequestURI = redacted.RequestURI()
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// RequireRole rejects requests that don't have at least the given role. Must be used after Middleware.
+func RequireRole(role Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if RoleOf(ctx) < role {
+				return echo.NewHTTPError(http.StatusForbidden, role.String()+" role required")
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// RoleOf returns the role given to the request by Middleware
+func RoleOf(ctx echo.Context) Role {
+	role, _ := ctx.Get(roleKey).(Role)
+	return role
+}
+
+// CheckOrigin is true if the origin is allowed to open a websocket. Requests without an origin aren't from a browser
+// so are allowed and rely on the API key instead.
+func (c *Config) CheckOrigin(origin string) bool {
+	if len(origin) == 0 {
+		return true
+	}
+
+	if len(c.AllowedOrigins) == 0 {
+		return c.Insecure
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	origin = parsed.Scheme + "://" + parsed.Host
+
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) roleForKey(key string) Role {
+	if len(key) == 0 {
+		return NoRole
+	}
+
+	// Compare against every key so the time taken doesn't give away how close a guess was
+	result := NoRole
+	for known, role := range c.Keys {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(key)) == 1 {
+			result = role
+		}
+	}
+
+	return result
+}
+
+func requestKey(ctx echo.Context) string {
+	if key := ctx.Request().Header.Get(apiKeyHeader); len(key) > 0 {
+		return key
+	}
+
+	if value := ctx.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(value, "Bearer ") {
+		return strings.TrimPrefix(value, "Bearer ")
+	}
+
+	return ctx.QueryParam(apiKeyQueryParam)
+}
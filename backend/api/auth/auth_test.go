@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+func TestRedactKey(t *testing.T) {
+	var log bytes.Buffer
+	var key string
+
+	e := echo.New()
+	e.Use(RedactKey())
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{Format: "${uri}\n", Output: &log}))
+	e.GET("/historical/:eventName", func(c echo.Context) error {
+		key = c.QueryParam(apiKeyQueryParam)
+		return c.NoContent(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/historical/Bahrain%20Grand%20Prix?session=5&token=secret", nil)
+	e.ServeHTTP(httptest.NewRecorder(), request)
+
+	if key != "secret" {
+		t.Errorf("expected the handler to still get the key, got '%s'", key)
+	}
+	if strings.Contains(log.String(), "secret") || !strings.Contains(log.String(), "session=5") {
+		t.Errorf("expected only the key to be hidden in the log, got: %s", log.String())
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	config := Config{Keys: map[string]Role{"viewer": Viewer, "controller": Controller}}
+
+	tests := []struct {
+		name     string
+		required Role
+		key      string
+		expected int
+	}{
+		{"no key", Viewer, "", http.StatusUnauthorized},
+		{"unknown key", Viewer, "guess", http.StatusUnauthorized},
+		{"viewer can view", Viewer, "viewer", http.StatusOK},
+		{"controller can view", Viewer, "controller", http.StatusOK},
+		{"viewer can't control", Controller, "viewer", http.StatusForbidden},
+		{"controller can control", Controller, "controller", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(config.Middleware("/healthz"))
+			e.GET("/historical", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, RequireRole(test.required))
+
+			request := httptest.NewRequest(http.MethodGet, "/historical", nil)
+			if len(test.key) > 0 {
+				request.Header.Set(apiKeyHeader, test.key)
+			}
+			response := httptest.NewRecorder()
+			e.ServeHTTP(response, request)
+
+			if response.Code != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, response.Code)
+			}
+		})
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	config := Config{Keys: map[string]Role{"secret": Controller}}
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		value  string
+	}{
+		{"header", "/historical", apiKeyHeader, "secret"},
+		{"bearer token", "/historical", echo.HeaderAuthorization, "Bearer secret"},
+		{"query parameter", "/historical?token=secret", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(config.Middleware())
+			e.GET("/historical", func(c echo.Context) error {
+				if RoleOf(c) != Controller {
+					t.Errorf("expected the controller role, got %s", RoleOf(c))
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if len(test.header) > 0 {
+				request.Header.Set(test.header, test.value)
+			}
+			response := httptest.NewRecorder()
+			e.ServeHTTP(response, request)
+
+			if response.Code != http.StatusOK {
+				t.Errorf("expected the key to be accepted, got %d", response.Code)
+			}
+		})
+	}
+}
+
+func TestNoKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		insecure bool
+		expected int
+	}{
+		{"rejects everything", false, http.StatusUnauthorized},
+		{"insecure is controller", true, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{Insecure: test.insecure}
+
+			e := echo.New()
+			e.Use(config.Middleware("/healthz"))
+			e.GET("/healthz", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			e.POST("/historical/actions", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, RequireRole(Controller))
+
+			response := httptest.NewRecorder()
+			e.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/historical/actions", nil))
+			if response.Code != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, response.Code)
+			}
+
+			response = httptest.NewRecorder()
+			e.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if response.Code != http.StatusOK {
+				t.Errorf("expected public paths to be allowed, got %d", response.Code)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		origin   string
+		expected bool
+	}{
+		{"no origin", Config{}, "", true},
+		{"nothing allowed", Config{}, "https://example.com", false},
+		{"insecure allows everything", Config{Insecure: true}, "https://example.com", true},
+		{"allowed", Config{AllowedOrigins: []string{"https://example.com/"}}, "https://EXAMPLE.com/page", true},
+		{"not allowed", Config{AllowedOrigins: []string{"https://example.com"}, Insecure: true}, "https://other.com", false},
+		{"wildcard", Config{AllowedOrigins: []string{"*"}}, "https://other.com", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if allowed := test.config.CheckOrigin(test.origin); allowed != test.expected {
+				t.Errorf("expected %v for '%s', got %v", test.expected, test.origin, allowed)
+			}
+		})
+	}
+}
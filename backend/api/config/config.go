@@ -0,0 +1,233 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/api/auth"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the server configuration. Values are read in order from the defaults, the config file, environment
+// variables and then command line flags with later values replacing earlier ones.
+type Config struct {
+	ListenAddress string `yaml:"listenAddress"`
+	CacheDir      string `yaml:"cacheDir"`
+
+	// Serve over HTTPS when both are set
+	TLSCertFile string `yaml:"tlsCertFile"`
+	TLSKeyFile  string `yaml:"tlsKeyFile"`
+
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	ViewerKeys     []string `yaml:"viewerKeys"`
+	ControllerKeys []string `yaml:"controllerKeys"`
+	// Start without any keys or allowed origins, which lets anyone control sessions from any site. For development
+	// only.
+	Insecure bool `yaml:"insecure"`
+
+	// Optional JSON or YAML file of sessions to add to the built in calendar
+	CalendarFile string `yaml:"calendarFile"`
+	// Download the livetiming index for the current year on start up to pick up sessions missing from the built in
+	// calendar
+	RefreshCalendar bool `yaml:"refreshCalendar"`
+
+	// How long to wait for requests to finish when shutting down
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	// Optional mirror of the livetiming archive to download sessions from instead
+	MirrorUrl string `yaml:"mirrorUrl"`
+	// How long to wait for the archive to respond
+	HttpTimeout time.Duration `yaml:"httpTimeout"`
+}
+
+func Default() Config {
+	return Config{
+		ListenAddress:   ":3000",
+		CacheDir:        "./.cache",
+		ShutdownTimeout: time.Second * 10,
+		HttpTimeout:     time.Second * 30,
+	}
+}
+
+// Load builds the config from the file given by -config or F1_CONFIG, the F1_* environment variables and the command
+// line flags
+func Load(args []string) (Config, error) {
+	config := Default()
+
+	flags := flag.NewFlagSet("f1gopher", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("F1_CONFIG"), "JSON or YAML config file")
+	listen := flags.String("listen", "", "address to listen on")
+	cache := flags.String("cache", "", "folder to cache session data in")
+	tlsCert := flags.String("tls-cert", "", "TLS certificate file")
+	tlsKey := flags.String("tls-key", "", "TLS key file")
+	origins := flags.String("allowed-origins", "", "comma separated origins allowed to make browser requests")
+	calendar := flags.String("calendar", "", "JSON or YAML file of extra calendar sessions")
+	refreshCalendar := flags.Bool("refresh-calendar", false, "download this year's sessions from the livetiming index")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 0, "how long to wait for requests to finish when shutting down")
+	mirror := flags.String("mirror", "", "mirror of the livetiming archive to download sessions from")
+	httpTimeout := flags.Duration("http-timeout", 0, "how long to wait for the archive to respond")
+	insecure := flags.Bool("insecure", false, "allow starting without API keys or allowed origins, for development only")
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	if len(*configFile) > 0 {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return config, err
+		}
+
+		// YAML is a superset of JSON so this handles both
+		if err = yaml.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("reading config file '%s': %w", *configFile, err)
+		}
+	}
+
+	setString(&config.ListenAddress, os.Getenv("F1_LISTEN"))
+	setString(&config.CacheDir, os.Getenv("F1_CACHE"))
+	setString(&config.TLSCertFile, os.Getenv("F1_TLS_CERT"))
+	setString(&config.TLSKeyFile, os.Getenv("F1_TLS_KEY"))
+	setList(&config.AllowedOrigins, os.Getenv("F1_ALLOWED_ORIGINS"))
+	setList(&config.ViewerKeys, os.Getenv("F1_VIEWER_KEYS"))
+	setList(&config.ControllerKeys, os.Getenv("F1_CONTROLLER_KEYS"))
+	setString(&config.CalendarFile, os.Getenv("F1_CALENDAR"))
+	if value := os.Getenv("F1_REFRESH_CALENDAR"); len(value) > 0 {
+		refresh, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid F1_REFRESH_CALENDAR '%s': %w", value, err)
+		}
+		config.RefreshCalendar = refresh
+	}
+	if value := os.Getenv("F1_SHUTDOWN_TIMEOUT"); len(value) > 0 {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid F1_SHUTDOWN_TIMEOUT '%s': %w", value, err)
+		}
+		config.ShutdownTimeout = timeout
+	}
+	if value := os.Getenv("F1_INSECURE"); len(value) > 0 {
+		insecureValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid F1_INSECURE '%s': %w", value, err)
+		}
+		config.Insecure = insecureValue
+	}
+	setString(&config.MirrorUrl, os.Getenv("F1_MIRROR_URL"))
+	if value := os.Getenv("F1_HTTP_TIMEOUT"); len(value) > 0 {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid F1_HTTP_TIMEOUT '%s': %w", value, err)
+		}
+		config.HttpTimeout = timeout
+	}
+
+	setString(&config.ListenAddress, *listen)
+	setString(&config.CacheDir, *cache)
+	setString(&config.TLSCertFile, *tlsCert)
+	setString(&config.TLSKeyFile, *tlsKey)
+	setList(&config.AllowedOrigins, *origins)
+	setString(&config.CalendarFile, *calendar)
+	if *refreshCalendar {
+		config.RefreshCalendar = true
+	}
+	if *shutdownTimeout > 0 {
+		config.ShutdownTimeout = *shutdownTimeout
+	}
+	setString(&config.MirrorUrl, *mirror)
+	if *insecure {
+		config.Insecure = true
+	}
+	if *httpTimeout > 0 {
+		config.HttpTimeout = *httpTimeout
+	}
+
+	return config, config.validate()
+}
+
+func (c *Config) UseTLS() bool {
+	return len(c.TLSCertFile) > 0 && len(c.TLSKeyFile) > 0
+}
+
+// Auth is the authentication settings for the server
+func (c *Config) Auth() auth.Config {
+	result := auth.Config{
+		Keys:           make(map[string]auth.Role),
+		AllowedOrigins: c.AllowedOrigins,
+		Insecure:       c.Insecure,
+	}
+
+	for _, key := range c.ViewerKeys {
+		result.Keys[key] = auth.Viewer
+	}
+
+	for _, key := range c.ControllerKeys {
+		result.Keys[key] = auth.Controller
+	}
+
+	return result
+}
+
+func (c *Config) validate() error {
+	if len(c.ListenAddress) == 0 {
+		return fmt.Errorf("no listen address")
+	}
+
+	if (len(c.TLSCertFile) > 0) != (len(c.TLSKeyFile) > 0) {
+		return fmt.Errorf("both a TLS certificate and key are needed")
+	}
+
+	for _, file := range []string{c.TLSCertFile, c.TLSKeyFile, c.CalendarFile} {
+		if len(file) == 0 {
+			continue
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+
+	// Fail closed rather than let anyone control sessions from any site
+	if !c.Insecure {
+		if len(c.ViewerKeys) == 0 && len(c.ControllerKeys) == 0 {
+			return fmt.Errorf("no API keys, set viewerKeys or controllerKeys or use -insecure for development")
+		}
+		if len(c.AllowedOrigins) == 0 {
+			return fmt.Errorf("no allowed origins, set allowedOrigins or use -insecure for development")
+		}
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdown timeout must be greater than 0")
+	}
+
+	if c.HttpTimeout <= 0 {
+		return fmt.Errorf("http timeout must be greater than 0")
+	}
+
+	return nil
+}
+
+func setString(value *string, newValue string) {
+	if len(newValue) > 0 {
+		*value = newValue
+	}
+}
+
+func setList(value *[]string, newValue string) {
+	if len(newValue) == 0 {
+		return
+	}
+
+	result := make([]string, 0)
+	for _, item := range strings.Split(newValue, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			result = append(result, item)
+		}
+	}
+	*value = result
+}
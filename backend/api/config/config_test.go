@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte("listenAddress: \":4000\"\ncacheDir: file-cache\nmirrorUrl: https://file.example/\nhttpTimeout: 5s\nviewerKeys: [file-key]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		args   []string
+		verify func(t *testing.T, config Config)
+	}{
+		{
+			name: "defaults",
+			verify: func(t *testing.T, config Config) {
+				if config.ListenAddress != ":3000" || config.CacheDir != "./.cache" || config.HttpTimeout != 30*time.Second {
+					t.Errorf("expected the defaults, got: %+v", config)
+				}
+			},
+		},
+		{
+			name: "file replaces defaults",
+			args: []string{"-config", file},
+			verify: func(t *testing.T, config Config) {
+				if config.ListenAddress != ":4000" || config.CacheDir != "file-cache" || config.HttpTimeout != 5*time.Second {
+					t.Errorf("expected the file values, got: %+v", config)
+				}
+				if config.ShutdownTimeout != 10*time.Second {
+					t.Errorf("expected the default for values not in the file, got: %v", config.ShutdownTimeout)
+				}
+			},
+		},
+		{
+			name: "file from the environment",
+			env:  map[string]string{"F1_CONFIG": file},
+			verify: func(t *testing.T, config Config) {
+				if config.ListenAddress != ":4000" {
+					t.Errorf("expected the file values, got: %+v", config)
+				}
+			},
+		},
+		{
+			name: "environment replaces file",
+			env:  map[string]string{"F1_LISTEN": ":5000", "F1_VIEWER_KEYS": "a, b,,c", "F1_HTTP_TIMEOUT": "7s"},
+			args: []string{"-config", file},
+			verify: func(t *testing.T, config Config) {
+				if config.ListenAddress != ":5000" || config.HttpTimeout != 7*time.Second {
+					t.Errorf("expected the environment values, got: %+v", config)
+				}
+				if len(config.ViewerKeys) != 3 || config.ViewerKeys[0] != "a" || config.ViewerKeys[2] != "c" {
+					t.Errorf("expected the environment keys, got: %v", config.ViewerKeys)
+				}
+				if config.CacheDir != "file-cache" {
+					t.Errorf("expected the file value for values not in the environment, got: %s", config.CacheDir)
+				}
+			},
+		},
+		{
+			name: "flags replace environment",
+			env:  map[string]string{"F1_LISTEN": ":5000", "F1_MIRROR_URL": "https://env.example/"},
+			args: []string{"-config", file, "-listen", ":6000", "-http-timeout", "9s"},
+			verify: func(t *testing.T, config Config) {
+				if config.ListenAddress != ":6000" || config.HttpTimeout != 9*time.Second {
+					t.Errorf("expected the flag values, got: %+v", config)
+				}
+				if config.MirrorUrl != "https://env.example/" {
+					t.Errorf("expected the environment value for values not in the flags, got: %s", config.MirrorUrl)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"F1_CONFIG", "F1_LISTEN", "F1_VIEWER_KEYS", "F1_HTTP_TIMEOUT", "F1_MIRROR_URL"} {
+				t.Setenv(name, test.env[name])
+			}
+			// Keys and origins are checked in TestLoadFailsClosed
+			t.Setenv("F1_INSECURE", "true")
+
+			config, err := Load(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.verify(t, config)
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{name: "unknown flag", args: []string{"-unknown"}},
+		{name: "missing config file", args: []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}},
+		{name: "only a certificate", args: []string{"-tls-cert", "cert.pem"}},
+		{name: "invalid timeout", env: map[string]string{"F1_SHUTDOWN_TIMEOUT": "soon"}},
+		{name: "invalid refresh", env: map[string]string{"F1_REFRESH_CALENDAR": "maybe"}},
+		{name: "invalid insecure", env: map[string]string{"F1_INSECURE": "perhaps"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("F1_INSECURE", "true")
+			for _, name := range []string{"F1_CONFIG", "F1_SHUTDOWN_TIMEOUT", "F1_REFRESH_CALENDAR", "F1_INSECURE"} {
+				if value, exists := test.env[name]; exists {
+					t.Setenv(name, value)
+				}
+			}
+
+			if _, err := Load(test.args); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadFailsClosed(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		valid bool
+	}{
+		{name: "nothing set"},
+		{name: "no origins", env: map[string]string{"F1_VIEWER_KEYS": "key"}},
+		{name: "no keys", args: []string{"-allowed-origins", "https://example.com"}},
+		{name: "keys and origins", env: map[string]string{"F1_CONTROLLER_KEYS": "key"}, args: []string{"-allowed-origins", "https://example.com"}, valid: true},
+		{name: "insecure flag", args: []string{"-insecure"}, valid: true},
+		{name: "insecure environment", env: map[string]string{"F1_INSECURE": "true"}, valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"F1_CONFIG", "F1_VIEWER_KEYS", "F1_CONTROLLER_KEYS", "F1_ALLOWED_ORIGINS", "F1_INSECURE"} {
+				t.Setenv(name, test.env[name])
+			}
+
+			config, err := Load(test.args)
+			if test.valid && err != nil {
+				t.Errorf("expected the config to be valid, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("expected an error, got %+v", config)
+			}
+			if test.valid && config.Auth().Insecure != config.Insecure {
+				t.Errorf("expected the auth config to be insecure when the config is")
+			}
+		})
+	}
+}
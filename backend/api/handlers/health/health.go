@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+)
+
+// Health reports whether the server is alive and whether it is ready to take requests
+type Health struct {
+	ready atomic.Bool
+
+	checksLock sync.Mutex
+	checks     map[string]func() error
+}
+
+func CreateHealth() *Health {
+	return &Health{
+		checks: make(map[string]func() error),
+	}
+}
+
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// AddCheck adds a check that has to pass for the server to be ready
+func (h *Health) AddCheck(name string, check func() error) {
+	h.checksLock.Lock()
+	defer h.checksLock.Unlock()
+	h.checks[name] = check
+}
+
+// HandleHealthz is always OK while the server is running
+func (h *Health) HandleHealthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleReadyz is OK once the server has started, until it starts shutting down, while all the checks pass
+func (h *Health) HandleReadyz(c echo.Context) error {
+	result := make(map[string]string)
+	status := http.StatusOK
+
+	if h.ready.Load() {
+		result["status"] = "ready"
+	} else {
+		result["status"] = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+
+	h.checksLock.Lock()
+	defer h.checksLock.Unlock()
+	for name, check := range h.checks {
+		if err := check(); err != nil {
+			result[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			result[name] = "ok"
+		}
+	}
+
+	return c.JSON(status, result)
+}
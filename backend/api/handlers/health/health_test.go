@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+func TestReadyz(t *testing.T) {
+	failing := errors.New("failing")
+
+	tests := []struct {
+		name     string
+		ready    bool
+		check    error
+		expected int
+	}{
+		{"not started", false, nil, http.StatusServiceUnavailable},
+		{"ready", true, nil, http.StatusOK},
+		{"check failing", true, failing, http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := CreateHealth()
+			status.SetReady(test.ready)
+			status.AddCheck("cache", func() error { return test.check })
+
+			e := echo.New()
+			response := httptest.NewRecorder()
+			err := status.HandleReadyz(e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), response))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Code != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, response.Code)
+			}
+		})
+	}
+}
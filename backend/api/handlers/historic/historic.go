@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/f1gopher/f1gopherlib/Messages"
 	providers "github.com/f1gopher/f1gopherlib/internal/providers"
 	"github.com/labstack/echo"
 )
@@ -17,3 +19,66 @@ func HandleHistoric(c echo.Context) error {
 
 	return nil
 }
+
+// HandleCalendar returns the sessions grouped into meetings. Supported query parameters are:
+//
+//	year     - only meetings for the year
+//	country  - only meetings in the country
+//	track    - only meetings at the track
+//	type     - only sessions of the type, either the name or number, can be repeated
+//	search   - meeting name, country or track contains the text
+//	upcoming - include live and upcoming sessions
+func HandleCalendar(cache string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return calendar(c, cache)
+	}
+}
+
+func calendar(c echo.Context, cache string) error {
+	filter := providers.CalendarFilter{
+		Country: c.QueryParam("country"),
+		Track:   c.QueryParam("track"),
+		Search:  c.QueryParam("search"),
+	}
+
+	if value := c.QueryParam("year"); len(value) > 0 {
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid year: "+value)
+		}
+		filter.Year = year
+	}
+
+	for _, value := range c.QueryParams()["type"] {
+		sessionType, ok := providers.ParseSessionType(value)
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid session type: "+value)
+		}
+		filter.SessionTypes = append(filter.SessionTypes, sessionType)
+	}
+
+	if value := c.QueryParam("upcoming"); len(value) > 0 {
+		upcoming, err := strconv.ParseBool(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid upcoming: "+value)
+		}
+		filter.IncludeUpcoming = upcoming
+	}
+
+	return c.JSON(http.StatusOK, providers.Calendar(filter, cache))
+}
+
+// HandleSessionTypes returns the names of the session types so clients can build filters
+func HandleSessionTypes(c echo.Context) error {
+	result := make(map[int]string)
+	for x := Messages.Practice1Session; x <= Messages.SprintQualifyingSession; x++ {
+		result[int(x)] = x.String()
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// HandleTelemetryChannels describes each telemetry value so clients can draw them without knowing about each one
+func HandleTelemetryChannels(c echo.Context) error {
+	return c.JSON(http.StatusOK, Messages.TelemetryChannels)
+}
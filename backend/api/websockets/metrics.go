@@ -0,0 +1,24 @@
+package api
+
+import (
+	"github.com/f1gopher/f1gopherlib/metrics"
+)
+
+var clientsConnected = metrics.Default.GaugeVec(
+	"f1gopher_websocket_clients",
+	"Websocket clients currently watching a session")
+
+var sessionsActive = metrics.Default.GaugeVec(
+	"f1gopher_websocket_sessions",
+	"Shared replays currently running")
+
+var sendLatency = metrics.Default.HistogramVec(
+	"f1gopher_websocket_send_seconds",
+	"Time taken to send a message to a websocket client",
+	metrics.DefaultBuckets,
+	"dataType")
+
+var sendErrors = metrics.Default.CounterVec(
+	"f1gopher_websocket_send_errors_total",
+	"Messages that couldn't be sent to a websocket client",
+	"dataType")
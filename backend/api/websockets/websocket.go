@@ -1,12 +1,18 @@
 package api
 
 import (
-	"fmt"
+	"errors"
+	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/api/auth"
 	"github.com/f1gopher/f1gopherlib/flowControl"
 	"github.com/f1gopher/f1gopherlib/internal/parser"
 	providers "github.com/f1gopher/f1gopherlib/internal/providers"
+	"github.com/f1gopher/f1gopherlib/lapComparison"
 	"github.com/labstack/echo"
 	"golang.org/x/net/websocket"
 )
@@ -16,179 +22,558 @@ type DataStruct struct {
 	Data     any    `json:"data"`
 }
 
-func HandleHistoricalWs(c echo.Context) error {
-	eventName := c.Param("eventName")
-	for _, r := range providers.RaceHistory() {
-		if r.Name == eventName {
-			const dataSources = parser.EventTime | parser.Timing | parser.Event | parser.RaceControl |
-				parser.TeamRadio | parser.Weather | parser.Location | parser.Telemetry | parser.Drivers
-			replayConnection, _ := providers.CreateReplay(
-				dataSources,
-				r,
-				"./.cache", flowControl.Realtime)
-			if replayConnection == nil {
-				fmt.Println("There is no replay session.")
-				return nil
-			}
-
-			websocket.Handler(func(ws *websocket.Conn) {
-				defer ws.Close()
-				for {
-					var dataLock sync.Mutex
-					// Send session data
-					dataLock.Lock()
-					err := websocket.JSON.Send(ws, &DataStruct{
-						DataType: "SESSION",
-						Data:     replayConnection.Session(),
-					})
-					if err != nil {
-						ws.Close()
-						c.Logger().Debug(err)
-					}
-					dataLock.Unlock()
-					// General data
-					dataLock.Lock()
-					err2 := websocket.JSON.Send(ws, &DataStruct{
-						DataType: "GENERAL",
-						Data:     replayConnection.TimeLostInPitlane(),
-					})
-					if err2 != nil {
-						ws.Close()
-						c.Logger().Debug(err2)
-					}
-					dataLock.Unlock()
-					// Circuit data
-					dataLock.Lock()
-					err3 := websocket.JSON.Send(ws, &DataStruct{
-						DataType: "INFORMATION",
-						Data:     replayConnection.CircuitTimezone().String(),
-					})
-					if err3 != nil {
-						ws.Close()
-						c.Logger().Debug(err3)
-					}
-					dataLock.Unlock()
-
-					go func() {
-						for {
-							select {
-							case msg := <-replayConnection.Drivers():
-								dataLock.Lock()
-								var numbers []int
-								for _, driver := range msg.Drivers {
-									numbers = append(numbers, driver.Number)
-								}
-								replayConnection.SelectTelemetrySources(numbers)
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "DRIVERS",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-
-							case msg := <-replayConnection.Timing():
-								dataLock.Lock()
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "TIMING",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-							// 	// TODO - sometimes get empty records on shutdown so filter these out
-							// 	// if msg.Position == 0 {
-							// 	// 	continue
-							// 	// }
-
-							case msg := <-replayConnection.Event():
-								dataLock.Lock()
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "EVENT",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-
-							case msg := <-replayConnection.Time():
-								dataLock.Lock()
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "TIME",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-
-							case msg := <-replayConnection.RaceControlMessages():
-								dataLock.Lock()
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "RACE_CONTROL",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-
-							case <-replayConnection.Weather():
-								// for x := range d.panels {
-								// 	d.panels[x].ProcessWeather(msg)
-								// }
-
-							case <-replayConnection.Radio():
-								// for x := range d.panels {
-								// 	d.panels[x].ProcessRadio(msg)
-								// }
-
-							case msg := <-replayConnection.Location():
-								dataLock.Lock()
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "LOCATION",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-
-							case msg := <-replayConnection.Telemetry():
-								dataLock.Lock()
-								err := websocket.JSON.Send(ws, &DataStruct{
-									DataType: "TELEMETRY",
-									Data:     msg,
-								})
-								if err != nil {
-									c.Logger().Debug(err)
-									break
-								}
-								dataLock.Unlock()
-							}
-						}
-					}()
-
-					// Read
-					msg := ""
-					err = websocket.Message.Receive(ws, &msg)
-					if err != nil {
-						c.Logger().Error(err)
-					}
-					fmt.Printf("%s\n", msg)
+type actions struct {
+	SkipToStart   bool
+	Skip5Secs     bool
+	SkipMinute    bool
+	Skip10Minutes bool
+	Pause         bool
+}
+
+// Hub shares one replay for each session between all the websocket clients watching it so that a controller
+// pausing or skipping affects everyone
+type Hub struct {
+	cache string
+	auth  *auth.Config
+
+	// Creates the replay for the first client watching a session
+	createReplay func(event providers.RaceEvent, cache string) (providers.F1Lib, error)
+
+	sessionsLock sync.Mutex
+	sessions     map[string]*sharedSession
+	closed       bool
+}
+
+type sharedSession struct {
+	key string
+
+	// Closed once the replay has been created, or failed to be created, by the first client to join. Until then
+	// replay, data, laps and lapData aren't set.
+	ready    chan struct{}
+	startErr error
+
+	replay providers.F1Lib
+	data   providers.Subscription
+
+	// Every lap played so far so they can be compared
+	laps    *lapComparison.Laps
+	lapData providers.Subscription
+
+	clientsLock sync.Mutex
+	clients     map[*client]bool
+
+	closing chan struct{}
+}
+
+type client struct {
+	ws        *websocket.Conn
+	writeLock sync.Mutex
+}
+
+func CreateHub(cache string, authConfig *auth.Config) *Hub {
+	return &Hub{
+		cache:        cache,
+		auth:         authConfig,
+		createReplay: createReplay,
+		sessions:     make(map[string]*sharedSession),
+	}
+}
+
+// Register adds the routes for watching, controlling and comparing laps from the shared sessions with the role each
+// needs. The auth middleware must already be in use.
+func (h *Hub) Register(e *echo.Echo) {
+	e.GET("/historical/:eventName", h.HandleHistoricalWs, auth.RequireRole(auth.Viewer))
+	e.POST("/historical/:eventName/actions", h.HandleActions, auth.RequireRole(auth.Controller))
+	e.GET("/historical/:eventName/compare", h.HandleLapComparison, auth.RequireRole(auth.Viewer))
+}
+
+func (h *Hub) HandleHistoricalWs(c echo.Context) error {
+	event, err := findEvent(c)
+	if err != nil {
+		return err
+	}
+
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			if !h.auth.CheckOrigin(req.Header.Get("Origin")) {
+				return errors.New("origin not allowed")
+			}
+
+			var originErr error
+			config.Origin, originErr = websocket.Origin(config, req)
+			return originErr
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			current := &client{ws: ws}
+			session, err := h.join(event, current)
+			if err != nil {
+				c.Logger().Error(err)
+				return
+			}
+			defer h.leave(session, current)
+
+			// Send session data
+			if !current.send("SESSION", session.replay.Session()) ||
+				// General data
+				!current.send("GENERAL", session.replay.TimeLostInPitlane()) ||
+				// Circuit data
+				!current.send("INFORMATION", session.replay.CircuitTimezone().String()) {
+				return
+			}
+
+			// Nothing is expected from the client but we need to read to know when it goes away
+			for {
+				msg := ""
+				if err := websocket.Message.Receive(ws, &msg); err != nil {
+					c.Logger().Debug(err)
+					return
 				}
-			}).ServeHTTP(c.Response(), c.Request())
+			}
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+// HandleActions pauses or skips through the shared replay for a session that is being watched
+func (h *Hub) HandleActions(c echo.Context) error {
+	event, err := findEvent(c)
+	if err != nil {
+		return err
+	}
+
+	session, exists := h.watching(event)
+	if !exists {
+		return echo.NewHTTPError(http.StatusNotFound, "nobody is watching: "+event.Name)
+	}
+
+	var a actions
+	if err := c.Bind(&a); err != nil {
+		return err
+	}
+
+	replay := session.replay
+	if a.SkipToStart {
+		replay.SkipToSessionStart()
+	} else if a.SkipMinute {
+		replay.IncrementTime(time.Minute * 1)
+	} else if a.Skip10Minutes {
+		replay.IncrementTime(time.Minute * 10)
+	} else if a.Skip5Secs {
+		replay.IncrementTime(time.Second * 5)
+	} else if a.Pause {
+		replay.TogglePause()
+	}
+
+	return c.JSON(http.StatusCreated, replay.IsPaused())
+}
+
+// The distance between the points in a lap comparison unless the request gives one, in metres
+const defaultComparisonStep = 10.0
+
+// HandleLapComparison lines up two laps that have been played in a session that is being watched. Query parameters
+// are:
+//
+//	driver      - driver number for the reference lap
+//	lap         - reference lap number
+//	otherDriver - driver number for the lap to compare, the reference driver if not given
+//	otherLap    - lap number to compare
+//	step        - metres between each point, 10 if not given and at least 1
+func (h *Hub) HandleLapComparison(c echo.Context) error {
+	event, err := findEvent(c)
+	if err != nil {
+		return err
+	}
+
+	session, exists := h.watching(event)
+	if !exists {
+		return echo.NewHTTPError(http.StatusNotFound, "nobody is watching: "+event.Name)
+	}
+
+	driver, err := intParam(c, "driver")
+	if err != nil {
+		return err
+	}
+	lap, err := intParam(c, "lap")
+	if err != nil {
+		return err
+	}
+	otherDriver := driver
+	if len(c.QueryParam("otherDriver")) > 0 {
+		if otherDriver, err = intParam(c, "otherDriver"); err != nil {
+			return err
+		}
+	}
+	otherLap, err := intParam(c, "otherLap")
+	if err != nil {
+		return err
+	}
+
+	step := defaultComparisonStep
+	if value := c.QueryParam("step"); len(value) > 0 {
+		step, err = strconv.ParseFloat(value, 64)
+		if err != nil || !(step >= lapComparison.MinStep) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid step: "+value)
+		}
+	}
+
+	reference, exists := session.laps.Lap(driver, lap)
+	if !exists {
+		return echo.NewHTTPError(http.StatusNotFound, "no telemetry for lap "+strconv.Itoa(lap)+" of "+strconv.Itoa(driver))
+	}
+	other, exists := session.laps.Lap(otherDriver, otherLap)
+	if !exists {
+		return echo.NewHTTPError(http.StatusNotFound, "no telemetry for lap "+strconv.Itoa(otherLap)+" of "+strconv.Itoa(otherDriver))
+	}
+
+	return c.JSON(http.StatusOK, lapComparison.Compare(reference, other, step))
+}
+
+func intParam(c echo.Context, name string) (int, error) {
+	value := c.QueryParam(name)
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+": "+value)
+	}
+	return result, nil
+}
+
+// Close stops every shared replay, disconnects all the clients and refuses any new clients
+func (h *Hub) Close() {
+	h.sessionsLock.Lock()
+	h.closed = true
+	sessions := h.sessions
+	h.sessions = make(map[string]*sharedSession)
+	h.sessionsLock.Unlock()
+
+	for _, session := range sessions {
+		session.close()
+	}
+}
+
+func findEvent(c echo.Context) (providers.RaceEvent, error) {
+	eventName := c.Param("eventName")
+
+	// An optional session type picks a session from the meeting, otherwise the last session of the meeting is used
+	var sessionType *Messages.SessionType
+	if value := c.QueryParam("session"); len(value) > 0 {
+		parsed, ok := providers.ParseSessionType(value)
+		if !ok {
+			return providers.RaceEvent{}, echo.NewHTTPError(http.StatusBadRequest, "invalid session type: "+value)
+		}
+		sessionType = &parsed
+	}
+
+	// Meetings keep the same name every year so without a year the most recent one is used
+	year := 0
+	if len(c.QueryParam("year")) > 0 {
+		parsed, err := intParam(c, "year")
+		if err != nil {
+			return providers.RaceEvent{}, err
+		}
+		year = parsed
+	}
+
+	event, exists := providers.FindSession(year, eventName, sessionType)
+	if !exists {
+		return providers.RaceEvent{}, echo.NewHTTPError(http.StatusNotFound, "no session found for: "+eventName)
+	}
+
+	return event, nil
+}
+
+// join adds the client to the shared session for the event, starting the replay if nobody is watching it yet
+func (h *Hub) join(event providers.RaceEvent, c *client) (*sharedSession, error) {
+	h.sessionsLock.Lock()
+	if h.closed {
+		h.sessionsLock.Unlock()
+		return nil, errors.New("server is shutting down")
+	}
+
+	session, exists := h.sessions[event.Url()]
+	if !exists {
+		session = &sharedSession{
+			key:     event.Url(),
+			ready:   make(chan struct{}),
+			clients: make(map[*client]bool),
+			closing: make(chan struct{}),
+		}
+		h.sessions[session.key] = session
+	}
+	// Added while holding the lock so the last client leaving can't close the session before this one is counted
+	session.add(c)
+	h.sessionsLock.Unlock()
+
+	// Creating the replay downloads the session so is done without the lock, anyone else joining waits for it
+	if !exists {
+		session.startErr = session.start(h.createReplay, event, h.cache)
+		if session.startErr != nil {
+			h.sessionsLock.Lock()
+			if current, exists := h.sessions[session.key]; exists && current == session {
+				delete(h.sessions, session.key)
+			}
+			h.sessionsLock.Unlock()
 		}
+		close(session.ready)
+	}
+
+	<-session.ready
+	if session.startErr != nil {
+		session.remove(c)
+		return nil, session.startErr
+	}
+
+	return session, nil
+}
+
+// watching returns the session for the event if someone is watching it and the replay has started
+func (h *Hub) watching(event providers.RaceEvent) (*sharedSession, bool) {
+	h.sessionsLock.Lock()
+	session, exists := h.sessions[event.Url()]
+	h.sessionsLock.Unlock()
+	if !exists {
+		return nil, false
 	}
 
+	select {
+	case <-session.ready:
+		return session, session.startErr == nil
+	default:
+		return nil, false
+	}
+}
+
+func (h *Hub) leave(session *sharedSession, c *client) {
+	if session.remove(c) > 0 {
+		return
+	}
+
+	// Last one out stops the replay
+	h.sessionsLock.Lock()
+	defer h.sessionsLock.Unlock()
+
+	// Someone may have joined while we were waiting for the lock
+	if current, exists := h.sessions[session.key]; !exists || current != session || session.count() > 0 {
+		return
+	}
+
+	delete(h.sessions, session.key)
+	session.close()
+}
+
+func createReplay(event providers.RaceEvent, cache string) (providers.F1Lib, error) {
+	const dataSources = parser.EventTime | parser.Timing | parser.Event | parser.RaceControl |
+		parser.Location | parser.Telemetry | parser.Drivers | parser.CarState
+	return providers.CreateReplay(
+		dataSources,
+		event,
+		cache,
+		flowControl.Realtime)
+}
+
+func (s *sharedSession) start(
+	createReplay func(event providers.RaceEvent, cache string) (providers.F1Lib, error),
+	event providers.RaceEvent,
+	cache string) error {
+
+	replay, err := createReplay(event, cache)
+	if err != nil {
+		return err
+	}
+
+	s.replay = replay
+	s.data = replay.Subscribe(providers.SubscriptionFilter{
+		Channels: []flowControl.Channel{
+			flowControl.DriversChannel,
+			flowControl.TimingChannel,
+			flowControl.EventChannel,
+			flowControl.EventTimeChannel,
+			flowControl.RaceControlChannel,
+			flowControl.LocationChannel,
+			flowControl.TelemetryChannel,
+		},
+		Policies: flowControl.DefaultPolicies(flowControl.Realtime),
+	})
+	s.laps = lapComparison.CreateLaps()
+	s.lapData = replay.Subscribe(providers.SubscriptionFilter{
+		Channels: []flowControl.Channel{flowControl.TimingChannel, flowControl.CarStateChannel},
+	})
+	sessionsActive.With().Inc()
+
+	go s.broadcast()
+	go s.collectLaps()
+
 	return nil
 }
+
+func (s *sharedSession) add(c *client) {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	if !s.clients[c] {
+		clientsConnected.With().Inc()
+	}
+	s.clients[c] = true
+}
+
+func (s *sharedSession) remove(c *client) int {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	if s.clients[c] {
+		clientsConnected.With().Dec()
+	}
+	delete(s.clients, c)
+	return len(s.clients)
+}
+
+func (s *sharedSession) count() int {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	return len(s.clients)
+}
+
+func (s *sharedSession) close() {
+	// The hub can be closed while the replay is still being created
+	<-s.ready
+	close(s.closing)
+	if s.startErr != nil {
+		return
+	}
+	sessionsActive.With().Dec()
+
+	// Broadcasting carries on until the replay has stopped so it isn't left blocked writing to a full channel
+	s.replay.Close()
+
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+	for c := range s.clients {
+		c.ws.Close()
+	}
+}
+
+// broadcast sends the replay data to every client until the replay is closed
+func (s *sharedSession) broadcast() {
+	for {
+		select {
+		case msg, ok := <-s.data.Drivers():
+			if !ok {
+				return
+			}
+			if s.isClosing() {
+				continue
+			}
+			var numbers []int
+			for _, driver := range msg.Drivers {
+				numbers = append(numbers, driver.Number)
+			}
+			s.replay.SelectTelemetrySources(numbers)
+			s.send("DRIVERS", msg)
+
+		case msg, ok := <-s.data.Timing():
+			if !ok {
+				return
+			}
+			s.send("TIMING", msg)
+
+		case msg, ok := <-s.data.Event():
+			if !ok {
+				return
+			}
+			s.send("EVENT", msg)
+
+		case msg, ok := <-s.data.Time():
+			if !ok {
+				return
+			}
+			s.send("TIME", msg)
+
+		case msg, ok := <-s.data.RaceControlMessages():
+			if !ok {
+				return
+			}
+			s.send("RACE_CONTROL", msg)
+
+		case msg, ok := <-s.data.Location():
+			if !ok {
+				return
+			}
+			s.send("LOCATION", msg)
+
+		case msg, ok := <-s.data.Telemetry():
+			if !ok {
+				return
+			}
+			s.send("TELEMETRY", msg)
+		}
+	}
+}
+
+// collectLaps keeps the timing and car states for the lap comparisons until the replay is closed
+func (s *sharedSession) collectLaps() {
+	defer s.laps.Clear()
+
+	for {
+		select {
+		case msg, ok := <-s.lapData.Timing():
+			if !ok {
+				return
+			}
+			s.laps.AddTiming(msg)
+
+		case msg, ok := <-s.lapData.CarState():
+			if !ok {
+				return
+			}
+			s.laps.AddCarState(msg)
+		}
+	}
+}
+
+func (s *sharedSession) isClosing() bool {
+	select {
+	case <-s.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *sharedSession) send(dataType string, data any) {
+	if s.isClosing() {
+		return
+	}
+
+	s.clientsLock.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.clientsLock.Unlock()
+
+	for _, c := range clients {
+		if !c.send(dataType, data) {
+			// Closing makes the receive in the handler fail which removes the client
+			c.ws.Close()
+		}
+	}
+}
+
+func (c *client) send(dataType string, data any) bool {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	start := time.Now()
+	err := websocket.JSON.Send(c.ws, &DataStruct{
+		DataType: dataType,
+		Data:     data,
+	})
+	if err != nil {
+		sendErrors.With(dataType).Inc()
+		return false
+	}
+
+	sendLatency.With(dataType).Observe(time.Since(start).Seconds())
+	return true
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/api/auth"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	providers "github.com/f1gopher/f1gopherlib/internal/providers"
+	"github.com/f1gopher/f1gopherlib/recording"
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+)
+
+const testOrigin = "http://localhost:5173"
+
+type testServer struct {
+	hub     *Hub
+	server  *httptest.Server
+	event   providers.RaceEvent
+	created atomic.Int32
+}
+
+// startServer serves the hub with a short recording standing in for every session so nothing is downloaded
+func startServer(t *testing.T) *testServer {
+	file := filepath.Join(t.TempDir(), "session.f1rec")
+	writer, err := recording.Create(file, recording.Info{Name: "Test", Session: Messages.RaceSession, Timezone: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 3, 17, 4, 0, 0, 0, time.UTC)
+	err = writer.Write(Messages.CreateEnvelope(Messages.Event{Timestamp: start, CurrentLap: 1, Status: Messages.Started}, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &auth.Config{
+		Keys:           map[string]auth.Role{"viewer": auth.Viewer, "controller": auth.Controller},
+		AllowedOrigins: []string{testOrigin},
+	}
+
+	result := &testServer{
+		hub:   CreateHub(t.TempDir(), config),
+		event: providers.RaceHistory()[0],
+	}
+	result.hub.createReplay = func(event providers.RaceEvent, cache string) (providers.F1Lib, error) {
+		result.created.Add(1)
+		return providers.CreateRecordingReplay(file, flowControl.StraightThrough)
+	}
+
+	e := echo.New()
+	e.Use(config.Middleware())
+	result.hub.Register(e)
+	result.server = httptest.NewServer(e)
+
+	t.Cleanup(func() {
+		result.hub.Close()
+		result.server.Close()
+	})
+
+	return result
+}
+
+func (s *testServer) url(path string, key string) string {
+	query := url.Values{}
+	query.Set("year", strconv.Itoa(s.event.RaceTime.Year()))
+	query.Set("session", strconv.Itoa(int(s.event.Type)))
+	if len(key) > 0 {
+		query.Set("token", key)
+	}
+
+	return s.server.URL + "/historical/" + url.PathEscape(s.event.Name) + path + "?" + query.Encode()
+}
+
+func (s *testServer) watch(t *testing.T, key string) *websocket.Conn {
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(s.url("", key), "http"), "", testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The session is sent once the client has joined
+	var msg DataStruct
+	if err = websocket.JSON.Receive(ws, &msg); err != nil || msg.DataType != "SESSION" {
+		t.Fatalf("expected the session, got %+v: %v", msg, err)
+	}
+
+	return ws
+}
+
+func (s *testServer) clients() int {
+	s.hub.sessionsLock.Lock()
+	defer s.hub.sessionsLock.Unlock()
+
+	result := 0
+	for _, session := range s.hub.sessions {
+		result += session.count()
+	}
+	return result
+}
+
+func eventually(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", description)
+}
+
+func TestRoles(t *testing.T) {
+	s := startServer(t)
+
+	// Actions and comparisons need someone watching
+	s.watch(t, "viewer")
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		expected int
+	}{
+		{"no key can't compare", http.MethodGet, "/compare", "", http.StatusUnauthorized},
+		{"viewer can compare", http.MethodGet, "/compare", "viewer", http.StatusNotFound},
+		{"no key can't control", http.MethodPost, "/actions", "", http.StatusUnauthorized},
+		{"viewer can't control", http.MethodPost, "/actions", "viewer", http.StatusForbidden},
+		{"controller can control", http.MethodPost, "/actions", "controller", http.StatusCreated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := s.url(test.path, test.key)
+			if test.path == "/compare" {
+				// No laps have been played so there is nothing to compare
+				target += "&driver=1&lap=2&otherLap=3"
+			}
+
+			request, err := http.NewRequest(test.method, target, strings.NewReader(`{"Pause":true}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, response.StatusCode)
+			}
+		})
+	}
+
+	// Opening the websocket needs a key and an allowed origin
+	if _, err := websocket.Dial("ws"+strings.TrimPrefix(s.url("", ""), "http"), "", testOrigin); err == nil {
+		t.Error("expected watching without a key to be refused")
+	}
+	if _, err := websocket.Dial("ws"+strings.TrimPrefix(s.url("", "viewer"), "http"), "", "https://other.example"); err == nil {
+		t.Error("expected watching from another origin to be refused")
+	}
+}
+
+func TestLapComparisonStep(t *testing.T) {
+	s := startServer(t)
+	s.watch(t, "viewer")
+
+	tests := []struct {
+		step     string
+		expected int
+	}{
+		{"0.000000001", http.StatusBadRequest},
+		{"0.5", http.StatusBadRequest},
+		{"0", http.StatusBadRequest},
+		{"-10", http.StatusBadRequest},
+		{"NaN", http.StatusBadRequest},
+		{"fast", http.StatusBadRequest},
+		// No laps have been played so there is nothing to compare
+		{"1", http.StatusNotFound},
+		{"", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.step, func(t *testing.T) {
+			target := s.url("/compare", "viewer") + "&driver=1&lap=2&otherLap=3"
+			if len(test.step) > 0 {
+				target += "&step=" + url.QueryEscape(test.step)
+			}
+
+			response, err := http.Get(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestSharedSessionJoinLeaveClose(t *testing.T) {
+	s := startServer(t)
+
+	first := s.watch(t, "viewer")
+	second := s.watch(t, "controller")
+	eventually(t, "both clients to join", func() bool { return s.clients() == 2 })
+	if created := s.created.Load(); created != 1 {
+		t.Errorf("expected both clients to share one replay, got %d", created)
+	}
+
+	// The replay keeps going while anyone is watching
+	first.Close()
+	eventually(t, "the first client to leave", func() bool { return s.clients() == 1 })
+	if _, watching := s.hub.watching(s.event); !watching {
+		t.Error("expected the session to carry on for the other client")
+	}
+
+	// Last one out stops the replay
+	second.Close()
+	eventually(t, "the session to close", func() bool {
+		_, watching := s.hub.watching(s.event)
+		return !watching
+	})
+
+	// Watching again starts a new replay
+	third := s.watch(t, "viewer")
+	if created := s.created.Load(); created != 2 {
+		t.Errorf("expected a new replay, got %d", created)
+	}
+
+	// Closing the hub disconnects everyone and refuses anyone new
+	s.hub.Close()
+	var msg DataStruct
+	for {
+		if err := websocket.JSON.Receive(third, &msg); err != nil {
+			break
+		}
+	}
+	if s.clients() != 0 {
+		t.Errorf("expected no sessions after closing, got %d clients", s.clients())
+	}
+
+	late, err := websocket.Dial("ws"+strings.TrimPrefix(s.url("", "viewer"), "http"), "", testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = websocket.JSON.Receive(late, &msg); err == nil {
+		t.Errorf("expected a client joining after closing to be disconnected, got %+v", msg)
+	}
+	if created := s.created.Load(); created != 2 {
+		t.Errorf("expected no replay after closing, got %d", created)
+	}
+}
@@ -1,9 +1,9 @@
 package connection
 
 import (
-	"bufio"
 	"context"
-	"os"
+	"errors"
+	"io"
 	"sync"
 	"time"
 
@@ -11,9 +11,9 @@ import (
 )
 
 type archivedLive struct {
-	log         *f1log.F1GopherLibLog
-	path        string
-	archiveFile *bufio.Scanner
+	log     *f1log.F1GopherLibLog
+	path    string
+	capture *CaptureReader
 
 	dataFeed chan Payload
 
@@ -37,50 +37,43 @@ func CreateArchivedLive(
 }
 
 func (a *archivedLive) Connect() (error, <-chan Payload) {
-	f, err := os.Open(a.path)
+	var err error
+	a.capture, err = OpenCapture(a.path)
 	if err != nil {
 		a.log.Errorf("Archived Live can't open file '%s': %s", a.path, err)
 		return err, nil
 	}
-	a.archiveFile = bufio.NewScanner(f)
 
+	a.wg.Add(1)
 	go a.readEntries()
 
 	return nil, a.dataFeed
 }
 
 func (a *archivedLive) readEntries() {
-	a.wg.Add(1)
 	defer a.wg.Done()
+	defer a.capture.Close()
 
 	// Will read entries as fast as possible until the channel is full
 	// and then wait. Flow control for message timing is handled elsewhere
-	for a.archiveFile.Scan() {
-
-		select {
-		case <-a.ctx.Done():
+	for {
+		data, err := a.capture.Next()
+		if errors.Is(err, io.EOF) {
 			return
-		default:
 		}
-
-		line1 := a.archiveFile.Text()
-
-		if !a.archiveFile.Scan() {
-			a.log.Error("Archived Live unexpected EOF, missing second line")
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			a.log.Warn("Archived Live ends part way through a message, capture was cut short")
 			return
 		}
-		line2 := []byte(a.archiveFile.Text())
-
-		if !a.archiveFile.Scan() {
-			a.log.Error("Archived Live unexpected EOF, missing third line")
+		if err != nil {
+			a.log.Errorf("Archived Live read error: %v", err)
 			return
 		}
-		line3 := a.archiveFile.Text()
 
-		a.dataFeed <- Payload{
-			Name:      line1,
-			Data:      line2,
-			Timestamp: line3,
+		select {
+		case <-a.ctx.Done():
+			return
+		case a.dataFeed <- data:
 		}
 	}
 }
@@ -1,9 +1,9 @@
 package connection
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 
@@ -15,16 +15,26 @@ type AssetStore interface {
 }
 
 type assets struct {
-	log   *f1log.F1GopherLibLog
-	url   string
-	cache string
+	ctx    context.Context
+	log    *f1log.F1GopherLibLog
+	client *HttpClient
+	url    string
+	cache  string
 }
 
-func CreateAssetStore(url string, cache string, log *f1log.F1GopherLibLog) AssetStore {
+func CreateAssetStore(
+	ctx context.Context,
+	url string,
+	cache string,
+	client *HttpClient,
+	log *f1log.F1GopherLibLog) AssetStore {
+
 	return &assets{
-		log:   log,
-		url:   url,
-		cache: cache,
+		ctx:    ctx,
+		log:    log,
+		client: client,
+		url:    url,
+		cache:  cache,
 	}
 }
 
@@ -35,46 +45,23 @@ func (a *assets) TeamRadio(file string) ([]byte, error) {
 		// If file matching url doesn't exist then retrieve
 		cachedFile := filepath.Join(a.cache, "TeamRadio", file)
 		cachedFile, _ = filepath.Abs(cachedFile)
-		f, err := os.Open(cachedFile)
-
-		if os.IsNotExist(err) {
-			f.Close()
 
-			var resp *http.Response
-			resp, err = http.Get(url)
-			if err != nil {
+		if _, err := os.Stat(cachedFile); errors.Is(err, os.ErrNotExist) {
+			if err = a.client.Download(a.ctx, url, cachedFile); err != nil {
 				a.log.Errorf("Fetching team radio for '%s': %v", url, err)
 				return nil, err
 			}
-			defer resp.Body.Close()
-
-			scanner := bufio.NewScanner(resp.Body)
-
-			err = os.MkdirAll(filepath.Dir(cachedFile), 0755)
-
-			// Write body to file - using url as name
-			var newFile *os.File
-			newFile, err = os.Create(cachedFile)
-			defer newFile.Close()
-			for scanner.Scan() {
-				_, err = newFile.Write(scanner.Bytes())
-
-				// need newline for scanner to split
-				newFile.WriteString("\n")
-			}
-			f, err = os.Open(cachedFile)
 		}
 
-		return io.ReadAll(bufio.NewReader(f))
+		return os.ReadFile(cachedFile)
 	}
 
-	var resp *http.Response
-	resp, err := http.Get(url)
+	body, err := a.client.Get(a.ctx, url)
 	if err != nil {
 		a.log.Errorf("Fetching team radio for '%s': %v", url, err)
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
-	return io.ReadAll(bufio.NewReader(resp.Body))
+	return io.ReadAll(body)
 }
@@ -0,0 +1,304 @@
+package connection
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// A capture is the raw live feed stored as:
+//
+//	F1GOCAP <version>
+//	header JSON
+//	<name length> <timestamp length> <data length>
+//	<name><timestamp><data>                           (repeated)
+//
+// Every record is followed by a new line. The lengths mean the data can contain anything, including new lines. The
+// whole file is gzip compressed when the path ends in .gz. Archives made before captures had a header are three
+// lines per message (name, data and timestamp) and can still be read.
+
+const CaptureVersion = 1
+
+const captureMagic = "F1GOCAP"
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// CaptureHeader describes the session that was captured
+type CaptureHeader struct {
+	EventName         string
+	Session           Messages.SessionType
+	Track             string
+	TrackYear         int
+	Country           string
+	Timezone          string
+	StartTime         time.Time
+	RaceTime          time.Time
+	TimeLostInPitlane time.Duration
+	Url               string
+	LibraryVersion    string
+	Created           time.Time
+}
+
+type CaptureWriter struct {
+	file       *os.File
+	buffered   *bufio.Writer
+	compressor *gzip.Writer
+	output     io.Writer
+}
+
+// CreateCapture starts a new capture file, compressed if the path ends in .gz
+func CreateCapture(path string, header CaptureHeader) (*CaptureWriter, error) {
+	if header.LibraryVersion == "" {
+		header.LibraryVersion = libraryVersion()
+	}
+	if header.Created.IsZero() {
+		header.Created = time.Now().UTC()
+	}
+
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &CaptureWriter{
+		file:     file,
+		buffered: bufio.NewWriter(file),
+	}
+	w.output = w.buffered
+	if strings.HasSuffix(path, ".gz") {
+		w.compressor = gzip.NewWriter(w.buffered)
+		w.output = w.compressor
+	}
+
+	if _, err = fmt.Fprintf(w.output, "%s %d\n%s\n", captureMagic, CaptureVersion, headerData); err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write adds the message to the capture. Each message is flushed to the file so little is lost if we crash.
+func (w *CaptureWriter) Write(data Payload) error {
+	_, err := fmt.Fprintf(w.output, "%d %d %d\n%s%s%s\n",
+		len(data.Name),
+		len(data.Timestamp),
+		len(data.Data),
+		data.Name,
+		data.Timestamp,
+		data.Data)
+	if err != nil {
+		return err
+	}
+
+	return w.flush()
+}
+
+func (w *CaptureWriter) Close() error {
+	var err error
+	if w.compressor != nil {
+		err = w.compressor.Close()
+	}
+	if flushErr := w.buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (w *CaptureWriter) flush() error {
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+	return w.buffered.Flush()
+}
+
+type CaptureReader struct {
+	file  *os.File
+	input *bufio.Reader
+
+	header CaptureHeader
+	// False for archives made before captures had a header
+	hasHeader bool
+}
+
+// OpenCapture reads a capture file or an archive in the old three line format, compressed or not
+func OpenCapture(path string) (*CaptureReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &CaptureReader{
+		file:  file,
+		input: bufio.NewReader(file),
+	}
+
+	if start, _ := r.input.Peek(len(gzipMagic)); bytes.Equal(start, gzipMagic) {
+		decompressor, err := gzip.NewReader(r.input)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("reading capture '%s': %w", path, err)
+		}
+		r.input = bufio.NewReader(decompressor)
+	}
+
+	if start, _ := r.input.Peek(len(captureMagic) + 1); string(start) == captureMagic+" " {
+		if err = r.readHeader(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("reading capture '%s': %w", path, err)
+		}
+	}
+
+	return r, nil
+}
+
+// ReadCaptureHeader gives the header of the capture and false if it is an old archive without one
+func ReadCaptureHeader(path string) (CaptureHeader, bool, error) {
+	r, err := OpenCapture(path)
+	if err != nil {
+		return CaptureHeader{}, false, err
+	}
+	defer r.Close()
+
+	return r.header, r.hasHeader, nil
+}
+
+func (r *CaptureReader) Header() (CaptureHeader, bool) {
+	return r.header, r.hasHeader
+}
+
+// Next is the next message and io.EOF at the end of the capture. A capture that was cut short part way through a
+// message gives io.ErrUnexpectedEOF.
+func (r *CaptureReader) Next() (Payload, error) {
+	if !r.hasHeader {
+		return r.nextLines()
+	}
+
+	line, err := r.input.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(line) > 0 {
+			return Payload{}, io.ErrUnexpectedEOF
+		}
+		return Payload{}, err
+	}
+
+	var nameLength, timestampLength, dataLength int
+	if _, err = fmt.Sscanf(line, "%d %d %d\n", &nameLength, &timestampLength, &dataLength); err != nil {
+		return Payload{}, fmt.Errorf("invalid capture record '%s': %w", strings.TrimSpace(line), err)
+	}
+	if nameLength < 0 || timestampLength < 0 || dataLength < 0 {
+		return Payload{}, fmt.Errorf("invalid capture record '%s'", strings.TrimSpace(line))
+	}
+
+	record := make([]byte, nameLength+timestampLength+dataLength+1)
+	if _, err = io.ReadFull(r.input, record); err != nil {
+		return Payload{}, io.ErrUnexpectedEOF
+	}
+	if record[len(record)-1] != '\n' {
+		return Payload{}, errors.New("capture record is not followed by a new line")
+	}
+
+	return Payload{
+		Name:      string(record[:nameLength]),
+		Timestamp: string(record[nameLength : nameLength+timestampLength]),
+		Data:      record[nameLength+timestampLength : len(record)-1],
+	}, nil
+}
+
+func (r *CaptureReader) Close() error {
+	return r.file.Close()
+}
+
+func (r *CaptureReader) readHeader() error {
+	line, err := r.input.ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(line, captureMagic+" %d\n", &version); err != nil {
+		return err
+	}
+	if version != CaptureVersion {
+		return fmt.Errorf("unsupported capture version %d", version)
+	}
+
+	headerData, err := r.input.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(headerData, &r.header); err != nil {
+		return err
+	}
+
+	r.hasHeader = true
+	return nil
+}
+
+// nextLines reads a message from an archive in the old format of name, data and timestamp lines
+func (r *CaptureReader) nextLines() (Payload, error) {
+	var lines [3]string
+	for x := range lines {
+		line, err := r.input.ReadString('\n')
+		switch {
+		case err == nil:
+		case errors.Is(err, io.EOF) && x == len(lines)-1 && len(line) > 0:
+			// The last timestamp doesn't need a new line
+		case errors.Is(err, io.EOF) && x == 0 && len(line) == 0:
+			return Payload{}, io.EOF
+		case errors.Is(err, io.EOF):
+			return Payload{}, io.ErrUnexpectedEOF
+		default:
+			return Payload{}, err
+		}
+		lines[x] = strings.TrimRight(line, "\r\n")
+	}
+
+	return Payload{
+		Name:      lines[0],
+		Data:      []byte(lines[1]),
+		Timestamp: lines[2],
+	}, nil
+}
+
+func libraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	const module = "github.com/f1gopher/f1gopherlib"
+	if info.Main.Path == module {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == module {
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+}
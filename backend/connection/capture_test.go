@@ -0,0 +1,144 @@
+package connection
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+var testPayloads = []Payload{
+	{Name: CatchupFile, Data: []byte(`{"R":{"SessionInfo":{}}}`)},
+	{Name: TimingDataFile, Data: []byte("{\"Lines\":{\"1\":{}}}\r\n\r\nsplit"), Timestamp: "2024-03-02T15:00:00.123Z"},
+	{Name: RaceControlMessagesFile, Data: []byte{}, Timestamp: "2024-03-02T15:00:01Z"},
+}
+
+func readAll(t *testing.T, reader *CaptureReader) []Payload {
+	var result []Payload
+	for {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return result
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, data)
+	}
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	for _, name := range []string{"capture.f1cap", "capture.f1cap.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			header := CaptureHeader{
+				EventName: "Bahrain Grand Prix",
+				Session:   Messages.RaceSession,
+				Track:     "Sakhir",
+				Timezone:  "Asia/Bahrain",
+				StartTime: time.Date(2024, 3, 2, 15, 0, 0, 0, time.UTC),
+			}
+
+			writer, err := CreateCapture(path, header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, data := range testPayloads {
+				if err = writer.Write(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err = writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := OpenCapture(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			readHeader, hasHeader := reader.Header()
+			if !hasHeader {
+				t.Fatal("capture has no header")
+			}
+			if readHeader.EventName != header.EventName || readHeader.Session != header.Session ||
+				!readHeader.StartTime.Equal(header.StartTime) || readHeader.LibraryVersion == "" {
+				t.Errorf("unexpected header: %+v", readHeader)
+			}
+
+			if result := readAll(t, reader); !reflect.DeepEqual(result, testPayloads) {
+				t.Errorf("got %q, want %q", result, testPayloads)
+			}
+		})
+	}
+}
+
+func TestCaptureCutShort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.f1cap")
+
+	writer, err := CreateCapture(path, CaptureHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range testPayloads {
+		if err = writer.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Close()
+
+	stat, _ := os.Stat(path)
+	if err = os.Truncate(path, stat.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := OpenCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for x := 0; x < len(testPayloads)-1; x++ {
+		if _, err = reader.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = reader.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestCaptureReadsOldArchives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+
+	old := "TimingData\r\n{\"Lines\":{}}\r\n2024-03-02T15:00:00.123Z\r\n" +
+		"Catchup\r\n{\"R\":{}}\r\n\r\n"
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := OpenCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if _, hasHeader := reader.Header(); hasHeader {
+		t.Error("old archive shouldn't have a header")
+	}
+
+	expected := []Payload{
+		{Name: TimingDataFile, Data: []byte(`{"Lines":{}}`), Timestamp: "2024-03-02T15:00:00.123Z"},
+		{Name: CatchupFile, Data: []byte(`{"R":{}}`)},
+	}
+	if result := readAll(t, reader); !reflect.DeepEqual(result, expected) {
+		t.Errorf("got %q, want %q", result, expected)
+	}
+}
@@ -2,6 +2,8 @@ package connection
 
 import (
 	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
 )
 
 type Payload struct {
@@ -17,3 +19,8 @@ type Connection interface {
 
 	JumpToStart() time.Time
 }
+
+// StateReporter is a connection that can be lost and remade, like the live data
+type StateReporter interface {
+	States() <-chan Messages.ConnectionState
+}
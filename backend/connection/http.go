@@ -0,0 +1,296 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/f1gopher/f1gopherlib/f1log"
+)
+
+// LiveTimingStaticUrl is the official archive of session data, replaced by the mirror url when one is set
+const LiveTimingStaticUrl = "https://livetiming.formula1.com/static/"
+
+// ErrNotFound is returned when the server doesn't have the file
+var ErrNotFound = errors.New("not found")
+
+type HttpOptions struct {
+	// How long to wait for the server to respond. Reading the body isn't limited because replays stream the data for
+	// the length of the session. Defaults to 30 seconds.
+	Timeout time.Duration
+	// How long a download can go without receiving any data before it is tried again. Defaults to the timeout.
+	IdleTimeout time.Duration
+	// How many times to retry a request or download when the server has an error, can't be reached or the download
+	// fails part way through. Defaults to 3, negative never retries.
+	Retries int
+	// How long to wait before the first retry, doubling for each retry after. Defaults to half a second.
+	RetryWait time.Duration
+	// The most requests and downloads at the same time. Defaults to 4.
+	MaxConcurrent int
+	// Defaults to f1gopherlib/<version>
+	UserAgent string
+	// Used instead of LiveTimingStaticUrl for every request
+	MirrorUrl string
+}
+
+// HttpClient fetches the archived data and assets
+type HttpClient struct {
+	log         *f1log.F1GopherLibLog
+	client      *http.Client
+	userAgent   string
+	mirrorUrl   string
+	retries     int
+	retryWait   time.Duration
+	idleTimeout time.Duration
+
+	// Holds a slot for each request or download in progress
+	slots chan struct{}
+}
+
+func CreateHttpClient(options HttpOptions, log *f1log.F1GopherLibLog) *HttpClient {
+	if options.Timeout <= 0 {
+		options.Timeout = time.Second * 30
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = options.Timeout
+	}
+	if options.Retries == 0 {
+		options.Retries = 3
+	}
+	if options.RetryWait <= 0 {
+		options.RetryWait = time.Millisecond * 500
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = 4
+	}
+	if len(options.UserAgent) == 0 {
+		options.UserAgent = "f1gopherlib/" + libraryVersion()
+	}
+	if len(options.MirrorUrl) > 0 && !strings.HasSuffix(options.MirrorUrl, "/") {
+		options.MirrorUrl += "/"
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: options.Timeout, KeepAlive: time.Second * 30}).DialContext
+	transport.TLSHandshakeTimeout = options.Timeout
+	transport.ResponseHeaderTimeout = options.Timeout
+
+	return &HttpClient{
+		log:         log,
+		client:      &http.Client{Transport: transport},
+		userAgent:   options.UserAgent,
+		mirrorUrl:   options.MirrorUrl,
+		retries:     max(options.Retries, 0),
+		retryWait:   options.RetryWait,
+		idleTimeout: options.IdleTimeout,
+		slots:       make(chan struct{}, options.MaxConcurrent),
+	}
+}
+
+// Url is where a request for the url actually goes, which is the mirror for anything in the official archive
+func (c *HttpClient) Url(url string) string {
+	if len(c.mirrorUrl) > 0 && strings.HasPrefix(url, LiveTimingStaticUrl) {
+		return c.mirrorUrl + strings.TrimPrefix(url, LiveTimingStaticUrl)
+	}
+	return url
+}
+
+// Get requests the url and gives the body, which the caller has to close. Only the request counts towards the
+// concurrency limit so the body can be read for as long as needed.
+func (c *HttpClient) Get(ctx context.Context, url string) (io.ReadCloser, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	return c.get(ctx, url)
+}
+
+// Download saves the body for the url to the file. The file only appears once the download has finished so a failed
+// download doesn't leave a partial file behind. A download that stops receiving data or fails part way through is
+// started again.
+func (c *HttpClient) Download(ctx context.Context, url string, file string) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	err = c.retry(ctx, func() error {
+		return c.download(ctx, url, temp)
+	})
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), file)
+}
+
+// download makes one attempt at writing the body to the file, replacing anything an earlier attempt wrote
+func (c *HttpClient) download(ctx context.Context, url string, file *os.File) error {
+	requestCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	body, err := c.request(requestCtx, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err = file.Truncate(0); err != nil {
+		return backoff.Permanent(err)
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return backoff.Permanent(err)
+	}
+
+	// Cancelling the request makes the blocked read fail
+	idle := time.AfterFunc(c.idleTimeout, cancel)
+	defer idle.Stop()
+	reader := &idleReader{body: body, idle: idle, timeout: c.idleTimeout}
+
+	_, err = io.Copy(file, reader)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return backoff.Permanent(ctx.Err())
+	case requestCtx.Err() != nil:
+		return fmt.Errorf("downloading '%s': no data for %v", c.Url(url), c.idleTimeout)
+	case errors.Is(err, reader.err):
+		return fmt.Errorf("downloading '%s': %w", c.Url(url), err)
+	default:
+		// Writing the file failed, which trying again won't fix
+		return backoff.Permanent(fmt.Errorf("downloading '%s': %w", c.Url(url), err))
+	}
+}
+
+// idleReader pushes back the idle timer every time data is read
+type idleReader struct {
+	body    io.Reader
+	idle    *time.Timer
+	timeout time.Duration
+	err     error
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	count, err := r.body.Read(p)
+	if count > 0 {
+		r.idle.Reset(r.timeout)
+	}
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return count, err
+}
+
+func (c *HttpClient) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := c.retry(ctx, func() error {
+		var err error
+		body, err = c.request(ctx, url)
+		return err
+	})
+	return body, err
+}
+
+// retry tries again with backoff until the attempt works, fails permanently or the retries run out
+func (c *HttpClient) retry(ctx context.Context, attempt func() error) error {
+	policy := backoff.WithContext(
+		backoff.WithMaxRetries(backoff.NewExponentialBackOff(backoff.WithInitialInterval(c.retryWait)), uint64(c.retries)),
+		ctx)
+
+	return backoff.RetryNotify(
+		attempt,
+		policy,
+		func(err error, wait time.Duration) {
+			httpRetries.With().Inc()
+			c.log.Warnf("Request failed, trying again in %v: %v", wait, err)
+		})
+}
+
+// request makes one attempt at requesting the url
+func (c *HttpClient) request(ctx context.Context, url string) (io.ReadCloser, error) {
+	url = c.Url(url)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return notFoundBody(resp)
+
+	case resp.StatusCode >= http.StatusInternalServerError:
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching '%s': %s", url, resp.Status)
+
+	// The archive says forbidden for files that don't exist
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, backoff.Permanent(fmt.Errorf("fetching '%s': %w", url, ErrNotFound))
+
+	default:
+		resp.Body.Close()
+		return nil, backoff.Permanent(fmt.Errorf("fetching '%s': %s", url, resp.Status))
+	}
+}
+
+// notFoundBody checks for the archive's not found message being sent as a successful response
+func notFoundBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.ContentLength != int64(len(NotFoundResponse)) {
+		return resp.Body, nil
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if string(content) == NotFoundResponse {
+		return nil, backoff.Permanent(fmt.Errorf("fetching '%s': %w", resp.Request.URL, ErrNotFound))
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (c *HttpClient) acquire(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.slots <- struct{}{}:
+		return nil
+	}
+}
+
+func (c *HttpClient) release() {
+	<-c.slots
+}
@@ -0,0 +1,205 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/f1log"
+)
+
+func createTestClient(options HttpOptions) *HttpClient {
+	options.RetryWait = time.Millisecond
+	return CreateHttpClient(options, f1log.CreateLog())
+}
+
+func TestHttpRetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.UserAgent(), "f1gopherlib/") {
+			t.Errorf("unexpected user agent: %s", req.UserAgent())
+		}
+
+		if requests.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	body, err := createTestClient(HttpOptions{}).Get(context.Background(), server.URL+"/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	data, _ := io.ReadAll(body)
+	if string(data) != "data" || requests.Load() != 3 {
+		t.Errorf("expected the data after 3 requests, got '%s' after %d", data, requests.Load())
+	}
+}
+
+func TestHttpGivesUpAfterRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := createTestClient(HttpOptions{Retries: 2}).Get(context.Background(), server.URL); err == nil {
+		t.Error("expected an error once the retries ran out")
+	}
+	if requests.Load() != 3 {
+		t.Errorf("expected the request and 2 retries, got %d requests", requests.Load())
+	}
+}
+
+func TestHttpNotFoundIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		if req.URL.Path == "/archive" {
+			w.Write([]byte(NotFoundResponse))
+			return
+		}
+		http.NotFound(w, req)
+	}))
+	defer server.Close()
+
+	client := createTestClient(HttpOptions{})
+	for _, path := range []string{"/missing", "/archive"} {
+		requests.Store(0)
+		if _, err := client.Get(context.Background(), server.URL+path); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected not found, got %v", path, err)
+		}
+		if requests.Load() != 1 {
+			t.Errorf("%s: expected 1 request, got %d", path, requests.Load())
+		}
+	}
+}
+
+func TestHttpMirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	}))
+	defer server.Close()
+
+	client := createTestClient(HttpOptions{MirrorUrl: server.URL + "/mirror"})
+
+	body, err := client.Get(context.Background(), LiveTimingStaticUrl+"2030/Index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	if data, _ := io.ReadAll(body); string(data) != "/mirror/2030/Index.json" {
+		t.Errorf("unexpected mirror path: %s", data)
+	}
+
+	if url := client.Url(server.URL + "/other"); url != server.URL+"/other" {
+		t.Errorf("only the archive should be mirrored, got %s", url)
+	}
+}
+
+func TestHttpDownloadLimit(t *testing.T) {
+	var active, most atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			previous := most.Load()
+			if current <= previous || most.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 20)
+		w.Write([]byte(req.URL.Path))
+	}))
+	defer server.Close()
+
+	client := createTestClient(HttpOptions{MaxConcurrent: 2})
+	folder := t.TempDir()
+
+	var wg sync.WaitGroup
+	for x := 0; x < 6; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			name := fmt.Sprintf("file%d", x)
+			if err := client.Download(context.Background(), server.URL+"/"+name, filepath.Join(folder, name)); err != nil {
+				t.Error(err)
+			}
+		}(x)
+	}
+	wg.Wait()
+
+	if most.Load() > 2 {
+		t.Errorf("expected at most 2 downloads at once, got %d", most.Load())
+	}
+
+	files, _ := os.ReadDir(folder)
+	if len(files) != 6 {
+		t.Errorf("expected 6 files, got %d", len(files))
+	}
+	for _, file := range files {
+		if data, _ := os.ReadFile(filepath.Join(folder, file.Name())); string(data) != "/"+file.Name() {
+			t.Errorf("unexpected content for %s: %s", file.Name(), data)
+		}
+	}
+}
+
+func TestHttpDownloadRetriesStalledBody(t *testing.T) {
+	var requests atomic.Int32
+	var alwaysStall atomic.Bool
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", "8")
+
+		// The first attempt sends half the body then stops sending
+		if requests.Add(1) == 1 || alwaysStall.Load() {
+			w.Write([]byte("data"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-req.Context().Done():
+			case <-stalled:
+			}
+			return
+		}
+		w.Write([]byte("complete"))
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	client := createTestClient(HttpOptions{IdleTimeout: time.Millisecond * 50})
+	file := filepath.Join(t.TempDir(), "file")
+
+	if err := client.Download(context.Background(), server.URL+"/file", file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "complete" || requests.Load() != 2 {
+		t.Errorf("expected the whole file after 2 requests, got '%s' after %d", data, requests.Load())
+	}
+
+	// A download that keeps stalling gives up and leaves no file behind
+	alwaysStall.Store(true)
+	other := filepath.Join(t.TempDir(), "other")
+	if err := createTestClient(HttpOptions{IdleTimeout: time.Millisecond * 50, Retries: 1}).Download(context.Background(), server.URL+"/other", other); err == nil {
+		t.Error("expected the stalled download to fail")
+	}
+	if _, err := os.Stat(other); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no file after a failed download, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package connection
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var lineBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// ConvertCapture writes the messages in a capture to <Feed>.jsonStream files in the folder in the same layout as the
+// archived data that replays use. Each line is the time since the start of the data followed by the message.
+//
+// The catchup sent when connecting becomes the first line of each feed. The start of the data is the first message
+// or the time in the catchup clock if that is earlier so the clock, which replays use to find the session times,
+// stays in step with everything else. A catchup after reconnecting is placed where the connection came back and the
+// parser ignores the race control messages and radio that it repeats.
+func ConvertCapture(capture string, folder string) error {
+	dataStart, err := captureDataStart(capture)
+	if err != nil {
+		return err
+	}
+
+	reader, err := OpenCapture(capture)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err = os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	streams := make(map[string]*bufio.Writer)
+	files := make([]*os.File, 0, len(OrderedFiles))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	// Every feed gets a file, even if it is empty, so the replay doesn't try to download the missing ones
+	for _, name := range OrderedFiles {
+		file, err := os.Create(filepath.Join(folder, name+".jsonStream"))
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		streams[name] = bufio.NewWriter(file)
+	}
+
+	clockLines := 0
+	var lastClock string
+	writeLine := func(name string, timestamp time.Time, data string) error {
+		stream, exists := streams[name]
+		if !exists {
+			return nil
+		}
+
+		// New lines in JSON are only ever white space and would split the message
+		data = lineBreaks.Replace(data)
+
+		offset := timestamp.Sub(dataStart)
+		if offset < 0 {
+			offset = 0
+		}
+
+		line := fmt.Sprintf("%02d:%02d:%02d.%03d%s\n",
+			int(offset.Hours()),
+			int(offset.Minutes())%60,
+			int(offset.Seconds())%60,
+			offset.Milliseconds()%1000,
+			data)
+
+		if name == ExtrapolatedClockFile {
+			clockLines++
+			lastClock = line
+		}
+
+		_, err := stream.WriteString(line)
+		return err
+	}
+
+	// A catchup after reconnecting happens at the time of the message before it
+	catchupTime := dataStart
+	for {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if data.Name == CatchupFile {
+			err = convertCatchup(data, catchupTime, writeLine)
+		} else {
+			var timestamp time.Time
+			timestamp, err = time.Parse(time.RFC3339Nano, data.Timestamp)
+			if err != nil {
+				return fmt.Errorf("invalid timestamp for %s: '%s'", data.Name, data.Timestamp)
+			}
+			if timestamp.After(catchupTime) {
+				catchupTime = timestamp
+			}
+
+			err = writeLine(data.Name, timestamp, streamData(data.Name, data.Data))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replays use the first two clock lines for when the data and the session start
+	switch clockLines {
+	case 0:
+		return fmt.Errorf("capture '%s' has no %s data so the session times can't be found", capture, ExtrapolatedClockFile)
+	case 1:
+		if _, err = streams[ExtrapolatedClockFile].WriteString(lastClock); err != nil {
+			return err
+		}
+	}
+
+	for _, stream := range streams {
+		if err = stream.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func convertCatchup(data Payload, timestamp time.Time, writeLine func(string, time.Time, string) error) error {
+	var feeds map[string]json.RawMessage
+	if err := json.Unmarshal(data.Data, &feeds); err != nil {
+		return fmt.Errorf("invalid catchup data: %w", err)
+	}
+
+	for _, name := range OrderedFiles {
+		feed, exists := feeds[name]
+		if !exists {
+			continue
+		}
+
+		// The clock is only moved on so it stays in order with the clock messages before it
+		feedTime := timestamp
+		if name == ExtrapolatedClockFile {
+			if clockTime := catchupClockTime(feed, timestamp); clockTime.After(timestamp) {
+				feedTime = clockTime
+			}
+		}
+
+		if err := writeLine(name, feedTime, string(feed)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamData is the message as it appears in an archived file where compressed data is a quoted string
+func streamData(name string, data []byte) string {
+	if strings.HasSuffix(name, ".z") && !strings.HasPrefix(string(data), "\"") {
+		return "\"" + string(data) + "\""
+	}
+	return string(data)
+}
+
+// captureDataStart is the time of the first message or the catchup clock if it is earlier
+func captureDataStart(capture string) (time.Time, error) {
+	reader, err := OpenCapture(capture)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer reader.Close()
+
+	var start time.Time
+	var clock json.RawMessage
+	for start.IsZero() {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return time.Time{}, fmt.Errorf("capture '%s' has no timestamped messages", capture)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if data.Name == CatchupFile {
+			var feeds map[string]json.RawMessage
+			if json.Unmarshal(data.Data, &feeds) == nil {
+				clock = feeds[ExtrapolatedClockFile]
+			}
+			continue
+		}
+
+		start, err = time.Parse(time.RFC3339Nano, data.Timestamp)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp for %s: '%s'", data.Name, data.Timestamp)
+		}
+	}
+
+	if clock != nil {
+		if clockTime := catchupClockTime(clock, start); clockTime.Before(start) {
+			start = clockTime
+		}
+	}
+
+	return start.Truncate(time.Millisecond), nil
+}
+
+func catchupClockTime(clock json.RawMessage, fallback time.Time) time.Time {
+	var value struct {
+		Utc string
+	}
+	if err := json.Unmarshal(clock, &value); err != nil {
+		return fallback
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, value.Utc)
+	if err != nil {
+		return fallback
+	}
+	return timestamp
+}
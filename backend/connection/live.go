@@ -2,148 +2,345 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+	"github.com/f1gopher/f1gopherlib/Messages"
 	"github.com/f1gopher/f1gopherlib/f1log"
 	"github.com/f1gopher/signalr/v2"
-	"golang.org/x/sync/errgroup"
 )
 
+const liveUrl = "https://livetiming.formula1.com/signalr"
+
+// If nothing arrives for this long the connection is treated as lost. The heartbeat feed is sent every few seconds.
+const liveStallTimeout = time.Minute
+
+var errLiveStalled = errors.New("no live data received")
+
 type live struct {
 	log     *f1log.F1GopherLibLog
-	archive *os.File
+	url     string
+	archive *CaptureWriter
 	ctx     context.Context
 	wg      *sync.WaitGroup
-	c2      *signalr.Conn
-	client  *signalr.Client
 
 	dataFeed chan Payload
+	states   chan Messages.ConnectionState
+}
+
+// liveSession is a single connection to the live data, replaced with a new one when reconnecting
+type liveSession struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *signalr.Client
+	stream *signalr.CallbackStream
+
+	// Why the session ended, set before the context is cancelled
+	errLock sync.Mutex
+	err     error
 }
 
 func CreateLive(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog) *live {
+	return CreateLiveFromUrl(ctx, wg, log, liveUrl)
+}
+
+// CreateLiveFromUrl connects to a different SignalR server, such as the livetest stand in
+func CreateLiveFromUrl(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog, url string) *live {
 	return &live{
 		ctx:      ctx,
 		wg:       wg,
 		log:      log,
+		url:      url,
 		dataFeed: make(chan Payload, 1000),
+		states:   make(chan Messages.ConnectionState, 10),
 		archive:  nil,
 	}
 }
 
-func CreateArchivingLive(ctx context.Context, wg *sync.WaitGroup, log *f1log.F1GopherLibLog, archiveFile string) (*live, error) {
-	archive, err := os.Create(fmt.Sprintf("%s_%d.txt", archiveFile, time.Now().UnixMilli()))
+// CreateArchivingLive captures the live data to a file named after the archive file. The capture is gzip compressed
+// if the archive file ends in .gz.
+func CreateArchivingLive(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	archiveFile string,
+	header CaptureHeader) (*live, error) {
+
+	extension := ".f1cap"
+	if strings.HasSuffix(archiveFile, ".gz") {
+		archiveFile = strings.TrimSuffix(archiveFile, ".gz")
+		extension += ".gz"
+	}
+
+	archive, err := CreateCapture(fmt.Sprintf("%s_%d%s", archiveFile, time.Now().UnixMilli(), extension), header)
 	if err != nil {
 		return nil, err
 	}
 
-	return &live{
-		ctx:      ctx,
-		wg:       wg,
-		log:      log,
-		dataFeed: make(chan Payload, 1000),
-		archive:  archive,
-	}, nil
+	l := CreateLive(ctx, wg, log)
+	l.archive = archive
+	return l, nil
 }
 
+// Connect fails if the first connection can't be made. After that the connection is remade whenever it is lost
+// until the context is cancelled.
 func (l *live) Connect() (error, <-chan Payload) {
-	var err error
-
-	// Prepare a SignalR client.
-	l.c2, err = signalr.Dial(
-		l.ctx,
-		"https://livetiming.formula1.com/signalr",
-		`[{"name":"streaming"}]`,
-	)
+	l.setState(Messages.Connecting, 0, nil)
+
+	session, err := l.dial()
 	if err != nil {
 		l.log.Errorf("Connect to live failed: %v", err)
+		l.setState(Messages.Disconnected, 0, err)
+		l.closeArchive()
 		return err, nil
 	}
 
-	l.client = signalr.NewClient("streaming", l.c2)
+	l.log.Info("Connected to live")
+	l.setState(Messages.Connected, 0, nil)
+
+	l.wg.Add(1)
+	go l.run(session)
+
+	return nil, l.dataFeed
+}
+
+// States reports each change to the connection. Changes are dropped if nobody is reading them.
+func (l *live) States() <-chan Messages.ConnectionState {
+	return l.states
+}
+
+func (l *live) run(session *liveSession) {
+	defer l.wg.Done()
+	defer l.closeArchive()
+
+	l.log.Info("Waiting for live data...")
+
+	for {
+		err := l.read(session)
+		session.cancel()
 
-	errg, ctx := errgroup.WithContext(l.ctx)
-	errg.Go(func() error { return l.client.Run(ctx) })
-	errg.Go(func() error {
-		l.wg.Add(1)
-		defer l.wg.Done()
+		if l.ctx.Err() != nil {
+			l.log.Info("Live shutdown")
+			l.setState(Messages.Disconnected, 0, nil)
+			return
+		}
+
+		l.log.Warnf("Live connection lost: %v", err)
+		liveReconnects.With().Inc()
 
-		stream, err1 := l.client.Callback(ctx, "feed")
-		if err1 != nil {
-			return err1
+		session, err = l.reconnect(err)
+		if err != nil {
+			l.log.Info("Live shutdown while reconnecting")
+			l.setState(Messages.Disconnected, 0, nil)
+			return
 		}
-		defer stream.Close()
+	}
+}
+
+// dial connects and subscribes to all of the feeds. The catchup with the current state of every feed is the first
+// message received.
+func (l *live) dial() (*liveSession, error) {
+	ctx, cancel := context.WithCancel(l.ctx)
+
+	conn, err := signalr.Dial(ctx, l.url, `[{"name":"streaming"}]`)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	session := &liveSession{
+		ctx:    ctx,
+		cancel: cancel,
+		client: signalr.NewClient("streaming", conn),
+	}
+
+	session.stream, err = session.client.Callback(ctx, "feed")
+	if err != nil {
+		cancel()
+		conn.Close()
+		return nil, err
+	}
+
+	go func() {
+		// Closes the connection when the context is cancelled
+		session.end(session.client.Run(ctx))
+	}()
+
+	if err = session.client.Invoke(ctx, "Subscribe", OrderedFiles).Exec(); err != nil {
+		session.end(fmt.Errorf("subscribe failed: %w", err))
+		return nil, err
+	}
+
+	return session, nil
+}
 
-		l.log.Info("Waiting for live data...")
+func (l *live) reconnect(cause error) (*liveSession, error) {
+	policy := backoff.WithContext(backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(0)), l.ctx)
 
-		for {
-			select {
-			case <-ctx.Done():
-				l.log.Info("Live shutdown")
-				return nil
+	attempt := 0
+	return backoff.RetryNotifyWithData(
+		func() (*liveSession, error) {
+			attempt++
+			l.setState(Messages.Reconnecting, attempt, cause)
 
-			default:
-				// do nothing
+			session, err := l.dial()
+			if err != nil {
+				cause = err
+				return nil, err
 			}
 
-			res := stream.ReadRaw()
-
-			if res.Args != nil {
-				if len(res.Args) == 3 {
-					data := Payload{}
-					abc, _ := res.Args[0].MarshalJSON()
-					data.Name = string(abc[1 : len(abc)-1])
-					abc, _ = res.Args[1].MarshalJSON()
-					if abc[0] == '"' {
-						data.Data = abc[1 : len(abc)-1]
-					} else {
-						data.Data = abc
-					}
-					abc, _ = res.Args[2].MarshalJSON()
-					data.Timestamp = string(abc[1 : len(abc)-1])
-
-					if l.archive != nil {
-						l.archive.WriteString(data.Name + "\r\n")
-						l.archive.Write(data.Data)
-						l.archive.WriteString("\r\n" + data.Timestamp + "\r\n")
-					}
-
-					l.dataFeed <- data
-				} else if len(res.Args) == 1 {
-					data := Payload{
-						Name:      CatchupFile,
-						Timestamp: "",
-					}
-					abc, _ := res.Args[0].MarshalJSON()
-					data.Data = abc
-
-					if l.archive != nil {
-						l.archive.WriteString(data.Name + "\r\n")
-						l.archive.Write(data.Data)
-						l.archive.WriteString("\r\n" + data.Timestamp + "\r\n")
-					}
-
-					l.dataFeed <- data
-				} else {
-					l.log.Errorf("There is an unhandled number of arguments for live data: %d, dropping data", len(res.Args))
-				}
+			l.log.Infof("Reconnected to live after %d attempts", attempt)
+			l.setState(Messages.Connected, 0, nil)
+			return session, nil
+		},
+		policy,
+		func(err error, wait time.Duration) {
+			l.log.Warnf("Live reconnect attempt %d failed, trying again in %v: %v", attempt, wait, err)
+		})
+}
+
+// read passes on the live data until the session ends
+func (l *live) read(session *liveSession) error {
+	stalled := time.AfterFunc(liveStallTimeout, func() { session.end(errLiveStalled) })
+	defer stalled.Stop()
+
+	for {
+		res := session.stream.ReadRaw()
+		if session.ctx.Err() != nil {
+			return session.error()
+		}
+		stalled.Reset(liveStallTimeout)
+
+		// The stream has been closed by the client
+		if len(res.Method) == 0 {
+			session.end(errors.New("live feed closed"))
+			return session.error()
+		}
+
+		if res.Args == nil {
+			continue
+		}
+
+		if len(res.Args) == 3 {
+			data := Payload{}
+			abc, _ := res.Args[0].MarshalJSON()
+			data.Name = string(abc[1 : len(abc)-1])
+			abc, _ = res.Args[1].MarshalJSON()
+			if abc[0] == '"' {
+				data.Data = abc[1 : len(abc)-1]
+			} else {
+				data.Data = abc
 			}
+			abc, _ = res.Args[2].MarshalJSON()
+			data.Timestamp = string(abc[1 : len(abc)-1])
+
+			l.send(data)
+		} else if len(res.Args) == 1 {
+			data := Payload{
+				Name:      CatchupFile,
+				Timestamp: "",
+			}
+			abc, _ := res.Args[0].MarshalJSON()
+			data.Data = abc
+
+			l.send(data)
+		} else {
+			l.log.Errorf("There is an unhandled number of arguments for live data: %d, dropping data", len(res.Args))
 		}
-	})
-	err = l.client.Invoke(ctx, "Subscribe", OrderedFiles).Exec()
+	}
+}
+
+func (l *live) send(data Payload) {
+	l.writeArchive(data)
+
+	select {
+	case <-l.ctx.Done():
+	case l.dataFeed <- data:
+	}
+}
+
+func (l *live) setState(status Messages.ConnectionStatus, attempt int, err error) {
+	state := Messages.ConnectionState{
+		Timestamp: time.Now(),
+		Status:    status,
+		Attempt:   attempt,
+	}
 	if err != nil {
-		l.log.Errorf("Live connection subscribe failed: %v", err)
-		return err, nil
+		state.Error = err.Error()
 	}
 
-	l.log.Info("Connected to live")
+	if status == Messages.Connected {
+		liveConnected.With().Set(1)
+	} else {
+		liveConnected.With().Set(0)
+	}
 
-	return nil, l.dataFeed
+	// Make room by throwing away the oldest change so the latest is always there to be read
+	for {
+		select {
+		case l.states <- state:
+			return
+		default:
+		}
+
+		select {
+		case <-l.states:
+		default:
+		}
+	}
+}
+
+func (l *live) writeArchive(data Payload) {
+	if l.archive == nil {
+		return
+	}
+
+	if err := l.archive.Write(data); err != nil {
+		l.log.Errorf("Writing live archive failed, no longer archiving: %v", err)
+		l.closeArchive()
+	}
+}
+
+func (l *live) closeArchive() {
+	if l.archive == nil {
+		return
+	}
+
+	if err := l.archive.Close(); err != nil {
+		l.log.Errorf("Closing live archive failed: %v", err)
+	}
+	l.archive = nil
 }
 
 // Can't do anything because this is live data
 func (l *live) IncrementTime(amount time.Duration) {}
 
 func (l *live) JumpToStart() time.Time { return time.Time{} }
+
+// end stops the session, keeping the first reason given
+func (s *liveSession) end(err error) {
+	s.errLock.Lock()
+	if s.err == nil {
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		s.err = err
+	}
+	s.errLock.Unlock()
+
+	s.cancel()
+}
+
+func (s *liveSession) error() error {
+	s.errLock.Lock()
+	defer s.errLock.Unlock()
+
+	if s.err == nil {
+		return s.ctx.Err()
+	}
+	return s.err
+}
@@ -0,0 +1,171 @@
+package livetest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+	"github.com/f1gopher/f1gopherlib/f1log"
+)
+
+var start = time.Date(2030, 3, 2, 15, 0, 0, 0, time.UTC)
+
+var testMessages = []Message{
+	{Name: connection.HeartbeatFile, Data: json.RawMessage(`{"Utc":"2030-03-02T15:00:00Z"}`), Timestamp: start},
+	{Name: connection.TimingDataFile, Data: json.RawMessage(`{"Lines":{"1":{"Position":"1"}}}`), Timestamp: start.Add(time.Second)},
+	{Name: connection.TimingDataFile, Data: json.RawMessage(`{"Lines":{"1":{"NumberOfLaps":2}}}`), Timestamp: start.Add(time.Second * 2)},
+	{Name: connection.CarDataFile, Data: json.RawMessage(`"7ZQ9DoIwFIDvcmcwpT9gWY2Lmzo"`), Timestamp: start.Add(time.Second * 3)},
+}
+
+func receive(t *testing.T, feed <-chan connection.Payload) connection.Payload {
+	t.Helper()
+
+	select {
+	case data := <-feed:
+		return data
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for live data")
+	}
+	return connection.Payload{}
+}
+
+func receiveState(t *testing.T, states <-chan Messages.ConnectionState, status Messages.ConnectionStatus) Messages.ConnectionState {
+	t.Helper()
+
+	timeout := time.After(time.Second * 10)
+	for {
+		select {
+		case state := <-states:
+			if state.Status == status {
+				return state
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", status)
+		}
+	}
+}
+
+func connect(t *testing.T, server *Server) (<-chan connection.Payload, <-chan Messages.ConnectionState, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	live := connection.CreateLiveFromUrl(ctx, &wg, f1log.CreateLog(), server.URL)
+	err, feed := live.Connect()
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+
+	return feed, live.States(), func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func TestLiveCatchupAndMessages(t *testing.T) {
+	server := Start(testMessages, Options{StartAt: time.Millisecond * 1500})
+	defer server.Close()
+
+	feed, _, stop := connect(t, server)
+	defer stop()
+
+	// Everything before the start is merged into the catchup
+	catchup := receive(t, feed)
+	if catchup.Name != connection.CatchupFile {
+		t.Fatalf("expected the catchup first, got %s", catchup.Name)
+	}
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(catchup.Data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if string(state[connection.TimingDataFile]) != `{"Lines":{"1":{"Position":"1"}}}` || state[connection.HeartbeatFile] == nil {
+		t.Errorf("unexpected catchup: %s", catchup.Data)
+	}
+
+	timing := receive(t, feed)
+	if timing.Name != connection.TimingDataFile || string(timing.Data) != `{"Lines":{"1":{"NumberOfLaps":2}}}` ||
+		timing.Timestamp != "2030-03-02T15:00:02.000Z" {
+		t.Errorf("unexpected message: %s %s %s", timing.Name, timing.Data, timing.Timestamp)
+	}
+
+	// Compressed data arrives without the quotes
+	car := receive(t, feed)
+	if car.Name != connection.CarDataFile || string(car.Data) != "7ZQ9DoIwFIDvcmcwpT9gWY2Lmzo" {
+		t.Errorf("unexpected message: %s %s", car.Name, car.Data)
+	}
+}
+
+func TestLiveReconnectsAfterDrop(t *testing.T) {
+	server := Start(testMessages, Options{})
+	defer server.Close()
+
+	feed, states, stop := connect(t, server)
+	defer stop()
+
+	receive(t, feed)
+	for range testMessages {
+		receive(t, feed)
+	}
+	<-server.Done()
+
+	server.Drop()
+
+	if state := receiveState(t, states, Messages.Reconnecting); state.Attempt != 1 || state.Error == "" {
+		t.Errorf("unexpected reconnecting state: %+v", state)
+	}
+	receiveState(t, states, Messages.Connected)
+
+	// Subscribing again gives everything so far
+	catchup := receive(t, feed)
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(catchup.Data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if catchup.Name != connection.CatchupFile ||
+		string(state[connection.TimingDataFile]) != `{"Lines":{"1":{"NumberOfLaps":2,"Position":"1"}}}` {
+		t.Errorf("unexpected catchup after reconnecting: %s", catchup.Data)
+	}
+}
+
+func TestLoadJsonStream(t *testing.T) {
+	folder := t.TempDir()
+
+	files := map[string]string{
+		connection.ExtrapolatedClockFile: "\ufeff00:00:05.000{\"Utc\":\"2030-03-02T15:00:05Z\"}\n",
+		connection.TimingDataFile:        "\ufeff00:00:01.000{\"Lines\":{}}\n00:00:06.250{\"Lines\":{\"1\":{}}}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(folder, name+".jsonStream"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err := LoadJsonStream(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name string
+		time time.Time
+	}{
+		{connection.TimingDataFile, start.Add(time.Second)},
+		{connection.ExtrapolatedClockFile, start.Add(time.Second * 5)},
+		{connection.TimingDataFile, start.Add(time.Millisecond * 6250)},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(messages))
+	}
+	for x := range expected {
+		if messages[x].Name != expected[x].name || !messages[x].Timestamp.Equal(expected[x].time) {
+			t.Errorf("message %d: expected %s at %v, got %s at %v",
+				x, expected[x].name, expected[x].time, messages[x].Name, messages[x].Timestamp)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package livetest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/connection"
+)
+
+// Message is one message from a feed as the server sends it
+type Message struct {
+	Name string
+	// A JSON object, or a quoted base64 string for the compressed feeds
+	Data      json.RawMessage
+	Timestamp time.Time
+}
+
+// LoadCapture reads the messages from a live capture. Catchups in the capture become the state sent to new
+// subscribers at that point.
+func LoadCapture(path string) ([]Message, error) {
+	reader, err := connection.OpenCapture(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := make([]Message, 0)
+	var last time.Time
+	for {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		msg := Message{
+			Name:      data.Name,
+			Data:      data.Data,
+			Timestamp: last,
+		}
+
+		if data.Name != connection.CatchupFile {
+			msg.Timestamp, err = time.Parse(time.RFC3339Nano, data.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp for %s: '%s'", data.Name, data.Timestamp)
+			}
+			last = msg.Timestamp
+
+			// Compressed data has the quotes removed by the live connection
+			if strings.HasSuffix(data.Name, ".z") {
+				msg.Data, _ = json.Marshal(string(data.Data))
+			}
+		}
+
+		result = append(result, msg)
+	}
+}
+
+// LoadJsonStream reads the <Feed>.jsonStream files in a cached session folder and puts the messages from all of
+// the feeds in time order
+func LoadJsonStream(folder string) ([]Message, error) {
+	feeds := make([][]Message, 0)
+	for _, name := range connection.OrderedFiles {
+		feed, err := loadFeed(filepath.Join(folder, name+".jsonStream"), name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+
+	// Times in the files are from the start of the data which the clock feed gives the real time of
+	dataStart := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, feed := range feeds {
+		if len(feed) > 0 && feed[0].Name == connection.ExtrapolatedClockFile {
+			var clock struct {
+				Utc string
+			}
+			if json.Unmarshal(feed[0].Data, &clock) == nil {
+				if utc, err := time.Parse(time.RFC3339Nano, clock.Utc); err == nil {
+					dataStart = utc.Add(-feed[0].Timestamp.Sub(time.Time{}))
+				}
+			}
+		}
+	}
+
+	result := make([]Message, 0)
+	for _, feed := range feeds {
+		for _, msg := range feed {
+			msg.Timestamp = dataStart.Add(msg.Timestamp.Sub(time.Time{}))
+			result = append(result, msg)
+		}
+	}
+
+	// Stable so messages at the same time stay in the feed order
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+
+	return result, nil
+}
+
+// loadFeed reads a feed with the timestamps as the time since the start of the data
+func loadFeed(path string, name string) ([]Message, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := make([]Message, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 10*1024*1024)
+	for scanner.Scan() {
+		// The files start with a byte order mark
+		line := strings.TrimPrefix(scanner.Text(), "\ufeff")
+		if len(line) <= 12 {
+			continue
+		}
+
+		offset, err := time.ParseDuration(fmt.Sprintf("%sh%sm%ss%sms", line[:2], line[3:5], line[6:8], line[9:12]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid time '%s': %w", path, line[:12], err)
+		}
+
+		result = append(result, Message{
+			Name:      name,
+			Data:      json.RawMessage(line[12:]),
+			Timestamp: time.Time{}.Add(offset),
+		})
+	}
+
+	return result, scanner.Err()
+}
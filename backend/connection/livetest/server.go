@@ -0,0 +1,393 @@
+// Package livetest serves recorded data from a local stand in for the live timing SignalR streaming hub so the live
+// connection, and everything after it, can be tested without a real session
+package livetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/connection"
+	"github.com/gorilla/websocket"
+)
+
+const connectionToken = "livetest-token"
+
+type Options struct {
+	// How many times faster than real time the messages are sent. Zero sends them as fast as possible.
+	Speed float64
+	// Messages from before this far into the data are only in the catchup sent to new subscribers
+	StartAt time.Duration
+}
+
+type Server struct {
+	// URL is the SignalR endpoint to connect to
+	URL string
+
+	http     *httptest.Server
+	upgrader websocket.Upgrader
+
+	messages []Message
+	options  Options
+
+	// Everything sent so far merged into the current state of each feed, used for the catchup
+	lock      sync.Mutex
+	state     map[string]any
+	clients   map[*client]bool
+	sent      int
+	messageId int
+
+	subscribed chan struct{}
+	done       chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type client struct {
+	ws        *websocket.Conn
+	writeLock sync.Mutex
+	// Nil until the client has subscribed
+	feeds map[string]bool
+}
+
+// Start serves the messages on localhost. Sending starts when the first client subscribes.
+func Start(messages []Message, options Options) *Server {
+	s := &Server{
+		messages:   messages,
+		options:    options,
+		state:      make(map[string]any),
+		clients:    make(map[*client]bool),
+		subscribed: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/signalr/negotiate", s.negotiate)
+	mux.HandleFunc("/signalr/connect", s.connect)
+	mux.HandleFunc("/signalr/start", s.start)
+	// Transport reconnects aren't supported so clients have to connect again from the start
+	mux.HandleFunc("/signalr/reconnect", http.NotFound)
+
+	s.http = httptest.NewServer(mux)
+	s.URL = s.http.URL + "/signalr"
+
+	s.skipToStart()
+
+	s.wg.Add(1)
+	go s.play()
+
+	return s
+}
+
+// Done is closed when every message has been sent
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Drop closes the connection to every client, like the real server going away, so they have to connect again
+func (s *Server) Drop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for c := range s.clients {
+		c.close(websocket.CloseInternalServerErr, "dropped")
+		delete(s.clients, c)
+	}
+}
+
+// Clients is how many clients are connected
+func (s *Server) Clients() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) Close() {
+	s.cancel()
+
+	s.lock.Lock()
+	for c := range s.clients {
+		c.close(websocket.CloseGoingAway, "closed")
+	}
+	s.clients = make(map[*client]bool)
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	s.http.CloseClientConnections()
+	s.http.Close()
+}
+
+func (s *Server) negotiate(w http.ResponseWriter, req *http.Request) {
+	writeJson(w, map[string]any{
+		"Url":                     "/signalr",
+		"ConnectionToken":         connectionToken,
+		"ConnectionId":            "livetest",
+		"KeepAliveTimeout":        20.0,
+		"DisconnectTimeout":       30.0,
+		"ConnectionTimeout":       110.0,
+		"TryWebSockets":           true,
+		"ProtocolVersion":         "1.5",
+		"TransportConnectTimeout": 5.0,
+		"LongPollDelay":           0.0,
+	})
+}
+
+func (s *Server) start(w http.ResponseWriter, req *http.Request) {
+	writeJson(w, map[string]any{"Response": "started"})
+}
+
+func (s *Server) connect(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("connectionToken") != connectionToken {
+		http.Error(w, "unknown connection token", http.StatusBadRequest)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+
+	c := &client{ws: ws}
+
+	s.lock.Lock()
+	s.clients[c] = true
+	s.lock.Unlock()
+
+	// The client waits for the transport to say it has started
+	if err = c.write(map[string]any{"C": "livetest-0", "S": 1, "M": []any{}}); err != nil {
+		s.remove(c)
+		return
+	}
+
+	s.wg.Add(1)
+	go s.readInvocations(c)
+}
+
+func (s *Server) readInvocations(c *client) {
+	defer s.wg.Done()
+	defer s.remove(c)
+
+	for {
+		var invocation struct {
+			H string
+			M string
+			A []json.RawMessage
+			I int
+		}
+		if err := c.ws.ReadJSON(&invocation); err != nil {
+			return
+		}
+
+		id := strconv.Itoa(invocation.I)
+
+		if !strings.EqualFold(invocation.M, "Subscribe") || len(invocation.A) != 1 {
+			c.write(map[string]any{"I": id, "E": fmt.Sprintf("unknown method '%s'", invocation.M)})
+			continue
+		}
+
+		var feeds []string
+		if err := json.Unmarshal(invocation.A[0], &feeds); err != nil {
+			c.write(map[string]any{"I": id, "E": err.Error()})
+			continue
+		}
+
+		s.subscribe(c, id, feeds)
+	}
+}
+
+// subscribe sends the catchup and from then on every message for the feeds. Nothing else is sent while the catchup
+// is taken so the client doesn't miss or repeat anything.
+func (s *Server) subscribe(c *client, id string, feeds []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	c.feeds = make(map[string]bool)
+	catchup := make(map[string]any)
+	for _, feed := range feeds {
+		c.feeds[feed] = true
+		if state, exists := s.state[feed]; exists {
+			catchup[feed] = state
+		}
+	}
+
+	if err := c.write(map[string]any{"I": id, "R": catchup}); err != nil {
+		return
+	}
+
+	select {
+	case <-s.subscribed:
+	default:
+		close(s.subscribed)
+	}
+}
+
+func (s *Server) remove(c *client) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.clients[c] {
+		c.close(websocket.CloseNormalClosure, "")
+		delete(s.clients, c)
+	}
+}
+
+// skipToStart puts the messages before the start in the state without sending them
+func (s *Server) skipToStart() {
+	if len(s.messages) == 0 {
+		return
+	}
+
+	start := s.messages[0].Timestamp.Add(s.options.StartAt)
+	for s.sent < len(s.messages) && s.messages[s.sent].Timestamp.Before(start) {
+		s.apply(s.messages[s.sent])
+		s.sent++
+	}
+}
+
+func (s *Server) play() {
+	defer s.wg.Done()
+	defer close(s.done)
+
+	select {
+	case <-s.ctx.Done():
+		return
+	case <-s.subscribed:
+	}
+
+	var previous time.Time
+	if s.sent > 0 {
+		previous = s.messages[s.sent-1].Timestamp
+	}
+
+	for ; s.sent < len(s.messages); s.sent++ {
+		msg := s.messages[s.sent]
+
+		if s.options.Speed > 0 && !previous.IsZero() && msg.Timestamp.After(previous) {
+			wait := time.Duration(float64(msg.Timestamp.Sub(previous)) / s.options.Speed)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+		previous = msg.Timestamp
+
+		s.send(msg)
+	}
+}
+
+func (s *Server) send(msg Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.apply(msg)
+
+	if msg.Name == connection.CatchupFile {
+		return
+	}
+
+	s.messageId++
+	data := map[string]any{
+		"C": fmt.Sprintf("livetest-%d", s.messageId),
+		"M": []any{map[string]any{
+			"H": "Streaming",
+			"M": "feed",
+			"A": []any{msg.Name, msg.Data, msg.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z")},
+		}},
+	}
+
+	for c := range s.clients {
+		if c.feeds[msg.Name] {
+			if err := c.write(data); err != nil {
+				c.close(websocket.CloseInternalServerErr, "write failed")
+				delete(s.clients, c)
+			}
+		}
+	}
+}
+
+// apply merges the message into the state. A catchup replaces the state of each feed in it.
+func (s *Server) apply(msg Message) {
+	var value any
+	if err := json.Unmarshal(msg.Data, &value); err != nil {
+		return
+	}
+
+	if msg.Name == connection.CatchupFile {
+		if feeds, ok := value.(map[string]any); ok {
+			for name, feed := range feeds {
+				s.state[name] = feed
+			}
+		}
+		return
+	}
+
+	s.state[msg.Name] = merge(s.state[msg.Name], value)
+}
+
+// merge applies an update to the state the same way the live timing does. Lists are updated by giving the index of
+// the item to change as the key.
+func merge(state any, update any) any {
+	changes, ok := update.(map[string]any)
+	if !ok {
+		return update
+	}
+
+	switch current := state.(type) {
+	case map[string]any:
+		for key, value := range changes {
+			current[key] = merge(current[key], value)
+		}
+		return current
+
+	case []any:
+		for key, value := range changes {
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 {
+				continue
+			}
+			for len(current) <= index {
+				current = append(current, nil)
+			}
+			current[index] = merge(current[index], value)
+		}
+		return current
+
+	default:
+		result := make(map[string]any)
+		for key, value := range changes {
+			result[key] = merge(nil, value)
+		}
+		return result
+	}
+}
+
+func (c *client) write(value any) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.ws.SetWriteDeadline(time.Now().Add(time.Second * 10))
+	return c.ws.WriteJSON(value)
+}
+
+func (c *client) close(code int, reason string) {
+	c.writeLock.Lock()
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.writeLock.Unlock()
+
+	c.ws.Close()
+}
+
+func writeJson(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
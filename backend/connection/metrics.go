@@ -0,0 +1,17 @@
+package connection
+
+import (
+	"github.com/f1gopher/f1gopherlib/metrics"
+)
+
+var liveReconnects = metrics.Default.CounterVec(
+	"f1gopher_live_reconnects_total",
+	"Times the live connection was lost and a reconnect started")
+
+var liveConnected = metrics.Default.GaugeVec(
+	"f1gopher_live_connected",
+	"1 while connected to the live data")
+
+var httpRetries = metrics.Default.CounterVec(
+	"f1gopher_http_retries_total",
+	"Requests for archived data or assets that failed and were tried again")
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,12 +20,14 @@ import (
 type fileInfo struct {
 	name         string
 	data         *bufio.Scanner
+	closer       io.Closer
 	nextLine     string
 	nextLineTime time.Time
 }
 
 type replay struct {
 	log      *f1log.F1GopherLibLog
+	client   *HttpClient
 	cache    string
 	dataFeed chan Payload
 
@@ -51,6 +52,7 @@ func CreateReplay(
 	ctx context.Context,
 	wg *sync.WaitGroup,
 	log *f1log.F1GopherLibLog,
+	client *HttpClient,
 	url string,
 	session Messages.SessionType,
 	eventYear int,
@@ -60,6 +62,7 @@ func CreateReplay(
 		ctx:       ctx,
 		wg:        wg,
 		log:       log,
+		client:    client,
 		dataFeed:  make(chan Payload, 1000),
 		eventUrl:  url,
 		session:   session,
@@ -84,12 +87,22 @@ func (r *replay) Connect() (error, <-chan Payload) {
 
 		r.dataFiles = append(r.dataFiles, fileInfo{
 			name:         name,
-			data:         r.get(r.eventUrl + name + ".jsonStream"),
 			nextLine:     "",
 			nextLineTime: time.Time{},
 		})
 	}
 
+	// Fetch the files at the same time, the http client limits how many download at once
+	var fetching sync.WaitGroup
+	for x := range r.dataFiles {
+		fetching.Add(1)
+		go func(file *fileInfo) {
+			defer fetching.Done()
+			file.data, file.closer = r.get(r.eventUrl + file.name + ".jsonStream")
+		}(&r.dataFiles[x])
+	}
+	fetching.Wait()
+
 	go r.readEntries()
 
 	return nil, r.dataFeed
@@ -119,6 +132,7 @@ func (r *replay) JumpToStart() time.Time {
 }
 
 func (r *replay) readEntries() {
+	defer r.close()
 
 	dataStartTime, raceStartTime, err := r.findSessionTimes()
 	if err != nil {
@@ -138,6 +152,10 @@ func (r *replay) readEntries() {
 	// Read drivers list and
 	for x := range r.dataFiles {
 		if r.dataFiles[x].name == DriverListFile {
+			// Not every source has a driver list
+			if r.dataFiles[x].data == nil {
+				break
+			}
 
 			r.dataFiles[x].data.Scan()
 			line := r.dataFiles[x].data.Text()
@@ -271,12 +289,13 @@ func (r *replay) sim(
 }
 
 func (r *replay) findSessionTimes() (dataStartTime time.Time, sessionStartTime time.Time, err error) {
-	dataBuffer := r.get(r.eventUrl + ExtrapolatedClockFile + ".jsonStream")
+	dataBuffer, closer := r.get(r.eventUrl + ExtrapolatedClockFile + ".jsonStream")
 
 	if dataBuffer == nil {
 		r.log.Errorf("Unable to find session start time because file doesn't exist")
 		return time.Time{}, time.Time{}, errors.New("No file for session start time")
 	}
+	defer closer.Close()
 
 	dataBuffer.Scan()
 	line := dataBuffer.Text()
@@ -366,71 +385,46 @@ func (r *replay) raceTime(value string, sessionStart time.Time) (time.Time, erro
 	return sessionStart.Add(timestamp), nil
 }
 
-func (r *replay) get(url string) *bufio.Scanner {
-
-	if len(r.cache) > 0 {
-		fileName := filepath.Base(url)
-
-		// If file matching url doesn't exist then retrieve
-		cachedFile := filepath.Join(r.cache, fileName)
-		cachedFile, _ = filepath.Abs(cachedFile)
-		f, err := os.Open(cachedFile)
-
-		if os.IsNotExist(err) {
-			f.Close()
-
-			var resp *http.Response
-			resp, err = http.Get(url)
-			if err != nil {
-				r.log.Errorf("Replay url error for '%s': %s", url, err)
-				return nil
-			}
-			defer resp.Body.Close()
-
-			if resp.ContentLength == int64(len(NotFoundResponse)) {
-				content, _ := io.ReadAll(resp.Body)
-				if string(content) == NotFoundResponse {
-					r.log.Errorf("Replay url not found '%s'", url)
-					return nil
-				}
-			}
-
-			scanner := bufio.NewScanner(resp.Body)
+// get opens the file at the url, downloading it to the cache first if there is one. Nil if the file can't be
+// found.
+func (r *replay) get(url string) (*bufio.Scanner, io.Closer) {
+	var file io.ReadCloser
+	var err error
 
-			err = os.MkdirAll(filepath.Dir(cachedFile), 0755)
+	switch {
+	case strings.HasPrefix(url, fileScheme):
+		file, err = os.Open(FilePath(url))
 
-			// Write body to file - using url as name
-			var newFile *os.File
-			newFile, err = os.Create(cachedFile)
-			defer newFile.Close()
-			for scanner.Scan() {
-				_, err = newFile.Write(scanner.Bytes())
+	case len(r.cache) > 0:
+		cachedFile, _ := filepath.Abs(filepath.Join(r.cache, filepath.Base(url)))
 
-				// need newline for scanner to split
-				newFile.WriteString("\n")
-			}
-			f, err = os.Open(cachedFile)
+		if _, err = os.Stat(cachedFile); errors.Is(err, os.ErrNotExist) {
+			err = r.client.Download(r.ctx, url, cachedFile)
+		}
+		if err == nil {
+			file, err = os.Open(cachedFile)
 		}
 
-		return bufio.NewScanner(f)
+	default:
+		file, err = r.client.Get(r.ctx, url)
 	}
 
-	var resp *http.Response
-	resp, err := http.Get(url)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		r.log.Errorf("Replay url not found '%s'", url)
+		return nil, nil
+	}
 	if err != nil {
-		r.log.Errorf("Replay get url '%s': %s", err)
-		return nil
+		r.log.Errorf("Replay url error for '%s': %s", url, err)
+		return nil, nil
 	}
-	// TODO - probably need to tidy this up but if we have no cache then we can't close it here or no data
-	//defer resp.Body.Close()
-
-	if resp.ContentLength == int64(len(NotFoundResponse)) {
-		content, _ := io.ReadAll(resp.Body)
-		if string(content) == NotFoundResponse {
-			r.log.Errorf("Replay url not found '%s'", url)
-			return nil
+
+	return bufio.NewScanner(file), file
+}
+
+func (r *replay) close() {
+	for x := range r.dataFiles {
+		if r.dataFiles[x].closer != nil {
+			r.dataFiles[x].closer.Close()
 		}
 	}
-
-	return bufio.NewScanner(resp.Body)
 }
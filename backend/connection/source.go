@@ -0,0 +1,77 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/f1log"
+)
+
+const fileScheme = "file://"
+
+// CreateFromUrl creates the connection for the data at the url, chosen by its scheme:
+//
+//	file://     a folder of <Feed>.jsonStream files or a live capture file
+//	http(s)://  a folder of <Feed>.jsonStream files, such as the official archive or a mirror of it
+//	ws(s)://    a SignalR streaming hub sending the live feeds, such as the live timing or the livetest server
+//
+// Every source has to send the same feed names as the live timing.
+func CreateFromUrl(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	client *HttpClient,
+	source string,
+	session Messages.SessionType,
+	eventYear int,
+	cache string) (Connection, error) {
+
+	sourceUrl, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source url '%s': %w", source, err)
+	}
+
+	switch sourceUrl.Scheme {
+	case "file":
+		path := FilePath(source)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			return CreateArchivedLive(ctx, wg, log, path), nil
+		}
+
+		if !strings.HasSuffix(source, "/") {
+			source += "/"
+		}
+		// Local files don't need caching
+		return CreateReplay(ctx, wg, log, client, source, session, eventYear, ""), nil
+
+	case "http", "https":
+		if !strings.HasSuffix(source, "/") {
+			source += "/"
+		}
+		return CreateReplay(ctx, wg, log, client, source, session, eventYear, cache), nil
+
+	case "ws", "wss":
+		// The SignalR client swaps back to a websocket after negotiating over http
+		sourceUrl.Scheme = strings.Replace(sourceUrl.Scheme, "ws", "http", 1)
+		return CreateLiveFromUrl(ctx, wg, log, sourceUrl.String()), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported source url '%s', expected file://, http(s):// or ws(s)://", source)
+	}
+}
+
+// FilePath is the local path for a file:// url. Relative paths are allowed, file://data/session/ is data/session/
+// from the working directory.
+func FilePath(source string) string {
+	return strings.TrimPrefix(source, fileScheme)
+}
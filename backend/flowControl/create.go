@@ -19,12 +19,19 @@ type Flow interface {
 	AddLocation(timing Messages.Location)
 	AddRadio(timing Messages.Radio)
 	AddDrivers(driver Messages.Drivers)
+	AddCarState(carState Messages.CarState)
 
 	IncrementLap()
 	IncrementTime(duration time.Duration)
 	SkipToSessionStart(start time.Time)
 	TogglePause()
 	IsPaused() bool
+
+	SetDeliveryPolicy(channel Channel, policy DeliveryPolicy)
+	DeliveryPolicy(channel Channel) DeliveryPolicy
+	SetBlockTimeout(channel Channel, timeout time.Duration)
+	BlockTimeout(channel Channel) time.Duration
+	Dropped() map[Channel]uint64
 }
 
 type FlowType int
@@ -38,20 +45,25 @@ func CreateFlowControl(
 	ctx context.Context,
 	wg *sync.WaitGroup,
 	flowType FlowType,
-	outputWeather chan<- Messages.Weather,
-	outputRaceControlMessages chan<- Messages.RaceControlMessage,
-	outputTimingMessages chan<- Messages.Timing,
-	outputEvent chan<- Messages.Event,
-	outputTelemetry chan<- Messages.Telemetry,
-	outputLocation chan<- Messages.Location,
-	outputEventTime chan<- Messages.EventTime,
-	outputRadio chan<- Messages.Radio,
-	outputDrivers chan<- Messages.Drivers) Flow {
+	outputWeather chan Messages.Weather,
+	outputRaceControlMessages chan Messages.RaceControlMessage,
+	outputTimingMessages chan Messages.Timing,
+	outputEvent chan Messages.Event,
+	outputTelemetry chan Messages.Telemetry,
+	outputLocation chan Messages.Location,
+	outputEventTime chan Messages.EventTime,
+	outputRadio chan Messages.Radio,
+	outputDrivers chan Messages.Drivers,
+	outputCarState chan Messages.CarState,
+	outputEnvelopes chan Messages.Envelope) Flow {
+
+	delivery := CreateDelivery(ctx, DefaultPolicies(flowType), dropped)
+	delivery.envelopes = outputEnvelopes
 
 	switch flowType {
 	case Realtime:
 		return &realtime{
-			ctx:                       ctx,
+			Delivery:                  delivery,
 			wg:                        wg,
 			outputWeather:             outputWeather,
 			outputRaceControlMessages: outputRaceControlMessages,
@@ -62,10 +74,12 @@ func CreateFlowControl(
 			outputEventTime:           outputEventTime,
 			outputRadio:               outputRadio,
 			outputDrivers:             outputDrivers,
+			outputCarState:            outputCarState,
 		}
 
 	case StraightThrough:
 		return &straightThrough{
+			Delivery:                  delivery,
 			outputWeather:             outputWeather,
 			outputRaceControlMessages: outputRaceControlMessages,
 			outputTimingMessages:      outputTimingMessages,
@@ -75,6 +89,7 @@ func CreateFlowControl(
 			outputEventTime:           outputEventTime,
 			outputRadio:               outputRadio,
 			outputDrivers:             outputDrivers,
+			outputCarState:            outputCarState,
 		}
 
 	default:
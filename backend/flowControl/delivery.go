@@ -0,0 +1,325 @@
+package flowControl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/metrics"
+)
+
+// DeliveryPolicy is what happens to a message when the consumer isn't keeping up and the output channel is full
+type DeliveryPolicy int
+
+const (
+	// Block waits until the consumer reads from the channel so nothing is lost. Only if a block timeout has been set
+	// for the channel and the consumer doesn't read in time is the message dropped, then the channel drops anything
+	// that doesn't fit until the consumer reads again so a consumer that has stopped reading only holds up the sender
+	// once.
+	Block DeliveryPolicy = iota
+	// DropNewest throws away the message that doesn't fit
+	DropNewest
+	// DropOldest throws away the oldest message waiting in the channel to make room
+	DropOldest
+	// CoalesceLatestPerDriver replaces the message waiting for the same driver so only the latest is kept. Channels
+	// that aren't per driver keep only the latest message.
+	CoalesceLatestPerDriver
+)
+
+func (d DeliveryPolicy) String() string {
+	return [...]string{"Block", "DropNewest", "DropOldest", "CoalesceLatestPerDriver"}[d]
+}
+
+type Channel int
+
+const (
+	WeatherChannel Channel = iota
+	RaceControlChannel
+	TimingChannel
+	EventChannel
+	TelemetryChannel
+	LocationChannel
+	EventTimeChannel
+	RadioChannel
+	DriversChannel
+	CarStateChannel
+	// Every message in the order it was sent
+	EnvelopeChannel
+	channelCount
+)
+
+func (c Channel) String() string {
+	return [...]string{"weather", "raceControl", "timing", "event", "telemetry", "location", "eventTime", "radio", "drivers", "carState", "envelope"}[c]
+}
+
+// Policies is the delivery policy for each channel
+type Policies [channelCount]DeliveryPolicy
+
+// DefaultPolicies block for timing, event, race control, radio and driver messages for the realtime flow so none are
+// lost. There is no block timeout unless one is set, so a consumer that stops reading one of these channels holds up
+// the replay. Telemetry, locations and car states are sent often enough that only the latest for each driver matters
+// and the weather and time only need the latest. Straight through flow blocks on every channel so the consumer sees
+// everything.
+func DefaultPolicies(flowType FlowType) Policies {
+	var policies Policies
+
+	if flowType == Realtime {
+		policies[WeatherChannel] = DropOldest
+		policies[TelemetryChannel] = CoalesceLatestPerDriver
+		policies[LocationChannel] = CoalesceLatestPerDriver
+		policies[CarStateChannel] = CoalesceLatestPerDriver
+		policies[EventTimeChannel] = DropOldest
+	}
+
+	return policies
+}
+
+// Delivery sends messages to output channels following the policy for each channel and counts what gets dropped
+type Delivery struct {
+	ctx    context.Context
+	metric *metrics.CounterVec
+
+	lock          sync.Mutex
+	policies      Policies
+	dropped       [channelCount]uint64
+	blockTimeouts [channelCount]time.Duration
+	// Channels whose consumer didn't read within the block timeout
+	stalled [channelCount]bool
+
+	// When set every message is also sent here wrapped in an envelope. Envelopes aren't filtered by what happens on
+	// the message's own channel so they include messages that were dropped or coalesced there.
+	envelopes chan Messages.Envelope
+	// Held while numbering and sending an envelope so the sequence is the order envelopes are sent
+	envelopeLock sync.Mutex
+	sequence     uint64
+}
+
+// CreateDelivery sends using the policies until the context is done. Drops are also counted in the metric which has a
+// channel label.
+func CreateDelivery(ctx context.Context, policies Policies, metric *metrics.CounterVec) *Delivery {
+	return &Delivery{
+		ctx:      ctx,
+		metric:   metric,
+		policies: policies,
+	}
+}
+
+// SetBlockTimeout is how long a Block channel waits for the consumer before dropping the message, zero waits forever
+// which is the default
+func (d *Delivery) SetBlockTimeout(channel Channel, timeout time.Duration) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.blockTimeouts[channel] = timeout
+	if timeout <= 0 {
+		d.stalled[channel] = false
+	}
+}
+
+func (d *Delivery) BlockTimeout(channel Channel) time.Duration {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.blockTimeouts[channel]
+}
+
+func (d *Delivery) SetDeliveryPolicy(channel Channel, policy DeliveryPolicy) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.policies[channel] = policy
+}
+
+func (d *Delivery) DeliveryPolicy(channel Channel) DeliveryPolicy {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.policies[channel]
+}
+
+// Dropped is the number of messages dropped or replaced for each channel
+func (d *Delivery) Dropped() map[Channel]uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	result := make(map[Channel]uint64)
+	for channel, count := range d.dropped {
+		result[Channel(channel)] = count
+	}
+	return result
+}
+
+func (d *Delivery) setStalled(channel Channel, stalled bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.stalled[channel] = stalled
+}
+
+func (d *Delivery) blocking(channel Channel) (timeout time.Duration, stalled bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.blockTimeouts[channel], d.stalled[channel]
+}
+
+// Drop counts a message for the channel that was dropped before it got to Send
+func (d *Delivery) Drop(channel Channel) {
+	d.drop(channel)
+}
+
+func (d *Delivery) drop(channel Channel) {
+	d.lock.Lock()
+	d.dropped[channel]++
+	d.lock.Unlock()
+
+	if d.metric != nil {
+		d.metric.With(channel.String()).Inc()
+	}
+}
+
+// sendEnvelope numbers the message and sends it to the envelopes. The flows send envelopes in the order the parser
+// gave them the messages rather than when each channel sends, and whatever happens to the message on its own channel,
+// so the envelope stream is every message the flow control handled in the order they happened. Recordings rely on
+// this so they don't depend on how quickly the typed channels are read.
+func (d *Delivery) sendEnvelope(payload Messages.Payload) {
+	if d.envelopes == nil {
+		return
+	}
+
+	d.envelopeLock.Lock()
+	defer d.envelopeLock.Unlock()
+	d.sequence++
+	Send(d, EnvelopeChannel, d.envelopes, Messages.CreateEnvelope(payload, d.sequence))
+}
+
+// Send delivers the value using the channel's policy and is false if the value was dropped. Only one goroutine
+// should send to each channel because dropping the oldest and coalescing take messages back out of the channel.
+func Send[T any](d *Delivery, channel Channel, out chan T, value T) bool {
+	policy := d.DeliveryPolicy(channel)
+
+	// There is nothing waiting in an unbuffered channel to drop or replace
+	if cap(out) == 0 && policy != Block {
+		policy = DropNewest
+	}
+
+	switch policy {
+	case DropNewest:
+		select {
+		case out <- value:
+			return true
+		default:
+			d.drop(channel)
+			return false
+		}
+
+	case DropOldest:
+		for {
+			select {
+			case out <- value:
+				return true
+			default:
+			}
+
+			select {
+			case <-out:
+				d.drop(channel)
+			default:
+			}
+		}
+
+	case CoalesceLatestPerDriver:
+		select {
+		case out <- value:
+			return true
+		default:
+		}
+
+		// Take everything waiting so the message for the same driver can be replaced and then put the rest back in
+		// the same order
+		waiting := make([]T, 0, cap(out))
+	drain:
+		for {
+			select {
+			case msg := <-out:
+				waiting = append(waiting, msg)
+			default:
+				break drain
+			}
+		}
+
+		replaced := false
+		for x := len(waiting) - 1; x >= 0; x-- {
+			if DriverOf(waiting[x]) == DriverOf(value) {
+				waiting = append(waiting[:x], waiting[x+1:]...)
+				replaced = true
+				break
+			}
+		}
+
+		// The consumer may have read some messages while we were draining so only drop one if there still isn't room
+		if !replaced && len(waiting) >= cap(out) {
+			waiting = waiting[1:]
+			replaced = true
+		}
+
+		if replaced {
+			d.drop(channel)
+		}
+
+		for _, msg := range append(waiting, value) {
+			select {
+			case out <- msg:
+			default:
+				d.drop(channel)
+			}
+		}
+		return true
+
+	default:
+		timeout, stalled := d.blocking(channel)
+		if stalled {
+			select {
+			case out <- value:
+				d.setStalled(channel, false)
+				return true
+			default:
+				d.drop(channel)
+				return false
+			}
+		}
+
+		if timeout <= 0 {
+			select {
+			case out <- value:
+				return true
+			case <-d.ctx.Done():
+				return false
+			}
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case out <- value:
+			return true
+		case <-d.ctx.Done():
+			return false
+		case <-timer.C:
+			d.setStalled(channel, true)
+			d.drop(channel)
+			return false
+		}
+	}
+}
+
+// DriverOf is the driver number for messages that are per driver and 0 for everything else
+func DriverOf(msg any) int {
+	switch value := msg.(type) {
+	case Messages.Timing:
+		return value.Number
+	case Messages.Telemetry:
+		return value.DriverNumber
+	case Messages.Location:
+		return value.DriverNumber
+	case Messages.CarState:
+		return value.DriverNumber
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,254 @@
+package flowControl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+func TestCoalesceLatestPerDriver(t *testing.T) {
+	d := CreateDelivery(context.Background(), Policies{}, nil)
+	d.SetDeliveryPolicy(TelemetryChannel, CoalesceLatestPerDriver)
+
+	out := make(chan Messages.Telemetry, 3)
+	Send(d, TelemetryChannel, out, Messages.Telemetry{DriverNumber: 1, RPM: 1})
+	Send(d, TelemetryChannel, out, Messages.Telemetry{DriverNumber: 44, RPM: 1})
+	Send(d, TelemetryChannel, out, Messages.Telemetry{DriverNumber: 16, RPM: 1})
+
+	// Replaces the waiting message for driver 44
+	Send(d, TelemetryChannel, out, Messages.Telemetry{DriverNumber: 44, RPM: 2})
+	// Nothing waiting for driver 4 so the oldest message goes
+	Send(d, TelemetryChannel, out, Messages.Telemetry{DriverNumber: 4, RPM: 1})
+
+	expected := []Messages.Telemetry{
+		{DriverNumber: 16, RPM: 1},
+		{DriverNumber: 44, RPM: 2},
+		{DriverNumber: 4, RPM: 1},
+	}
+	for _, value := range expected {
+		msg := <-out
+		if msg.DriverNumber != value.DriverNumber || msg.RPM != value.RPM {
+			t.Errorf("expected driver %d with rpm %d, got driver %d with rpm %d", value.DriverNumber, value.RPM, msg.DriverNumber, msg.RPM)
+		}
+	}
+
+	if dropped := d.Dropped()[TelemetryChannel]; dropped != 2 {
+		t.Errorf("expected 2 dropped, got %d", dropped)
+	}
+}
+
+func TestDropPolicies(t *testing.T) {
+	d := CreateDelivery(context.Background(), Policies{}, nil)
+	d.SetDeliveryPolicy(WeatherChannel, DropOldest)
+	d.SetDeliveryPolicy(RadioChannel, DropNewest)
+
+	weather := make(chan Messages.Weather, 1)
+	Send(d, WeatherChannel, weather, Messages.Weather{AirTemp: 1})
+	if !Send(d, WeatherChannel, weather, Messages.Weather{AirTemp: 2}) {
+		t.Error("expected the newest weather to be sent")
+	}
+	if msg := <-weather; msg.AirTemp != 2 {
+		t.Errorf("expected the newest weather, got %v", msg.AirTemp)
+	}
+
+	radio := make(chan Messages.Radio, 1)
+	Send(d, RadioChannel, radio, Messages.Radio{Driver: "HAM"})
+	if Send(d, RadioChannel, radio, Messages.Radio{Driver: "VER"}) {
+		t.Error("expected the newest radio message to be dropped")
+	}
+	if msg := <-radio; msg.Driver != "HAM" {
+		t.Errorf("expected the oldest radio message, got %s", msg.Driver)
+	}
+}
+
+func TestBlockStopsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := CreateDelivery(ctx, Policies{}, nil)
+
+	timing := make(chan Messages.Timing, 1)
+	Send(d, TimingChannel, timing, Messages.Timing{Number: 1})
+	cancel()
+
+	if Send(d, TimingChannel, timing, Messages.Timing{Number: 2}) {
+		t.Error("expected the blocked send to give up on shutdown")
+	}
+}
+
+func TestEnvelopesKeepOrder(t *testing.T) {
+	envelopes := make(chan Messages.Envelope, 10)
+	flow := CreateFlowControl(context.Background(), nil, StraightThrough, nil,
+		make(chan Messages.RaceControlMessage, 10), make(chan Messages.Timing, 10), nil, nil, nil, nil, nil, nil, nil, envelopes)
+
+	flow.AddRaceControlMessage(Messages.RaceControlMessage{Msg: "BLUE FLAG"})
+	flow.AddTiming(Messages.Timing{Number: 44})
+
+	first := <-envelopes
+	second := <-envelopes
+	if first.Kind != Messages.RaceControlMessageKind || first.Sequence != 1 {
+		t.Errorf("unexpected first envelope: %+v", first)
+	}
+	if second.Kind != Messages.TimingKind || second.Sequence != 2 || second.Payload.(Messages.Timing).Number != 44 {
+		t.Errorf("unexpected second envelope: %+v", second)
+	}
+}
+
+func TestRealtimeEnvelopesKeepOrderAcrossChannels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+
+	envelopes := make(chan Messages.Envelope, 100)
+	flow := CreateFlowControl(ctx, &wg, Realtime,
+		make(chan Messages.Weather, 10),
+		make(chan Messages.RaceControlMessage, 10),
+		make(chan Messages.Timing, 10),
+		make(chan Messages.Event, 10),
+		make(chan Messages.Telemetry, 10),
+		make(chan Messages.Location, 10),
+		make(chan Messages.EventTime, 10),
+		make(chan Messages.Radio, 10),
+		make(chan Messages.Drivers, 10),
+		make(chan Messages.CarState, 10),
+		envelopes)
+
+	// Locations and car states are sent every tick and the rest every third tick, the envelopes must still be in
+	// the order the messages happened
+	start := time.Date(2030, 3, 17, 4, 0, 0, 0, time.UTC)
+	flow.AddEvent(Messages.Event{Timestamp: start, Status: Messages.Started})
+	flow.AddLocation(Messages.Location{Timestamp: start.Add(100 * time.Millisecond), DriverNumber: 1})
+	flow.AddTiming(Messages.Timing{Timestamp: start.Add(200 * time.Millisecond), Number: 1})
+	flow.AddLocation(Messages.Location{Timestamp: start.Add(300 * time.Millisecond), DriverNumber: 1})
+	flow.AddRaceControlMessage(Messages.RaceControlMessage{Timestamp: start.Add(400 * time.Millisecond)})
+	flow.AddCarState(Messages.CarState{Timestamp: start.Add(600 * time.Millisecond), DriverNumber: 1})
+	flow.AddTiming(Messages.Timing{Timestamp: start.Add(700 * time.Millisecond), Number: 1})
+	expected := []Messages.Kind{
+		Messages.EventKind,
+		Messages.LocationKind,
+		Messages.TimingKind,
+		Messages.LocationKind,
+		Messages.RaceControlMessageKind,
+		Messages.CarStateKind,
+		Messages.TimingKind,
+	}
+
+	go flow.Run()
+
+	var sequence uint64
+	var previous time.Time
+	for len(expected) > 0 {
+		var envelope Messages.Envelope
+		select {
+		case envelope = <-envelopes:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", expected[0])
+		}
+
+		if envelope.Sequence != sequence+1 {
+			t.Errorf("expected sequence %d, got %d", sequence+1, envelope.Sequence)
+		}
+		sequence = envelope.Sequence
+		if envelope.Timestamp.Before(previous) {
+			t.Errorf("%s at %v is before %v", envelope.Kind, envelope.Timestamp, previous)
+		}
+		previous = envelope.Timestamp
+
+		// The replay time is in between the messages
+		if envelope.Kind == Messages.EventTimeKind {
+			continue
+		}
+
+		if envelope.Kind != expected[0] {
+			t.Errorf("expected %s, got %s at %v", expected[0], envelope.Kind, envelope.Timestamp)
+		}
+		expected = expected[1:]
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestBlockTimeout(t *testing.T) {
+	d := CreateDelivery(context.Background(), Policies{}, nil)
+	d.SetBlockTimeout(RadioChannel, 10*time.Millisecond)
+
+	radio := make(chan Messages.Radio, 1)
+	Send(d, RadioChannel, radio, Messages.Radio{Driver: "HAM"})
+
+	// Nobody is reading so the first message waits for the timeout and the next is dropped straight away
+	start := time.Now()
+	if Send(d, RadioChannel, radio, Messages.Radio{Driver: "VER"}) {
+		t.Error("expected the message to be dropped after the timeout")
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("expected to wait for the timeout")
+	}
+	if Send(d, RadioChannel, radio, Messages.Radio{Driver: "LEC"}) {
+		t.Error("expected the message to be dropped while the consumer isn't reading")
+	}
+
+	// Other channels aren't affected
+	timing := make(chan Messages.Timing, 1)
+	if !Send(d, TimingChannel, timing, Messages.Timing{Number: 1}) {
+		t.Error("expected the timing to be sent")
+	}
+	sent := make(chan bool)
+	go func() {
+		sent <- Send(d, TimingChannel, timing, Messages.Timing{Number: 2})
+	}()
+	select {
+	case <-sent:
+		t.Error("expected the timing to wait for the consumer without a timeout")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-timing
+	if !<-sent {
+		t.Error("expected the timing to be sent once the consumer read")
+	}
+
+	// Once the consumer reads again nothing is dropped
+	<-radio
+	if !Send(d, RadioChannel, radio, Messages.Radio{Driver: "NOR"}) {
+		t.Error("expected the message to be sent once the consumer is reading")
+	}
+	if msg := <-radio; msg.Driver != "NOR" {
+		t.Errorf("expected the newest message, got %s", msg.Driver)
+	}
+
+	if dropped := d.Dropped()[RadioChannel]; dropped != 2 {
+		t.Errorf("expected 2 dropped, got %d", dropped)
+	}
+}
+
+func TestEnvelopesIncludeDroppedMessages(t *testing.T) {
+	envelopes := make(chan Messages.Envelope, 10)
+	flow := CreateFlowControl(context.Background(), nil, StraightThrough, nil, nil, nil, nil, nil, nil, nil,
+		make(chan Messages.Radio, 1), nil, nil, envelopes)
+	flow.SetDeliveryPolicy(RadioChannel, DropNewest)
+
+	flow.AddRadio(Messages.Radio{Driver: "HAM"})
+	flow.AddRadio(Messages.Radio{Driver: "VER"})
+	if dropped := flow.Dropped()[RadioChannel]; dropped != 1 {
+		t.Fatalf("expected the radio message to be dropped, got %d", dropped)
+	}
+
+	for _, expected := range []string{"HAM", "VER"} {
+		if envelope := <-envelopes; envelope.Payload.(Messages.Radio).Driver != expected {
+			t.Errorf("expected an envelope for %s, got %+v", expected, envelope)
+		}
+	}
+}
+
+func TestRealtimeTimingNeverTimesOut(t *testing.T) {
+	for _, flowType := range []FlowType{Realtime, StraightThrough} {
+		flow := CreateFlowControl(context.Background(), nil, flowType, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if policy := flow.DeliveryPolicy(TimingChannel); policy != Block {
+			t.Errorf("expected timing to block, got %s", policy)
+		}
+		if timeout := flow.BlockTimeout(TimingChannel); timeout != 0 {
+			t.Errorf("expected timing to block without a timeout, got %v", timeout)
+		}
+	}
+}
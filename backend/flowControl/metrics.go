@@ -0,0 +1,75 @@
+package flowControl
+
+import (
+	"github.com/f1gopher/f1gopherlib/metrics"
+)
+
+var dropped = metrics.Default.CounterVec(
+	"f1gopher_flow_dropped_total",
+	"Messages dropped because the output channel was full",
+	"channel")
+
+var occupancy = metrics.Default.GaugeVec(
+	"f1gopher_flow_channel_occupancy",
+	"Messages waiting in each output channel for the consumer to read",
+	"channel")
+
+var pending = metrics.Default.GaugeVec(
+	"f1gopher_flow_pending",
+	"Messages held back until the replay time reaches them",
+	"channel")
+
+// recordOccupancy updates how full each output channel is and how many messages are still waiting to be sent
+func (f *realtime) recordOccupancy() {
+	occupancy.With("weather").Set(float64(len(f.outputWeather)))
+	occupancy.With("raceControl").Set(float64(len(f.outputRaceControlMessages)))
+	occupancy.With("timing").Set(float64(len(f.outputTimingMessages)))
+	occupancy.With("event").Set(float64(len(f.outputEvent)))
+	occupancy.With("telemetry").Set(float64(len(f.outputTelemetry)))
+	occupancy.With("location").Set(float64(len(f.outputLocation)))
+	occupancy.With("eventTime").Set(float64(len(f.outputEventTime)))
+	occupancy.With("radio").Set(float64(len(f.outputRadio)))
+	occupancy.With("drivers").Set(float64(len(f.outputDrivers)))
+	occupancy.With("carState").Set(float64(len(f.outputCarState)))
+	occupancy.With("envelope").Set(float64(len(f.envelopes)))
+
+	f.weatherLock.Lock()
+	pending.With("weather").Set(float64(len(f.weather)))
+	f.weatherLock.Unlock()
+
+	f.raceControlLock.Lock()
+	pending.With("raceControl").Set(float64(len(f.raceControl)))
+	f.raceControlLock.Unlock()
+
+	f.timingLock.Lock()
+	pending.With("timing").Set(float64(len(f.timing)))
+	f.timingLock.Unlock()
+
+	f.eventLock.Lock()
+	pending.With("event").Set(float64(len(f.event)))
+	f.eventLock.Unlock()
+
+	f.telemetryLock.Lock()
+	pending.With("telemetry").Set(float64(len(f.telemetry)))
+	f.telemetryLock.Unlock()
+
+	f.locationLock.Lock()
+	pending.With("location").Set(float64(len(f.location)))
+	f.locationLock.Unlock()
+
+	f.radioLock.Lock()
+	pending.With("radio").Set(float64(len(f.radio)))
+	f.radioLock.Unlock()
+
+	f.driversLock.Lock()
+	pending.With("drivers").Set(float64(len(f.drivers)))
+	f.driversLock.Unlock()
+
+	f.carStateLock.Lock()
+	pending.With("carState").Set(float64(len(f.carState)))
+	f.carStateLock.Unlock()
+
+	f.timelineLock.Lock()
+	pending.With("envelope").Set(float64(len(f.timeline)))
+	f.timelineLock.Unlock()
+}
@@ -1,7 +1,6 @@
 package flowControl
 
 import (
-	"context"
 	"sync"
 	"time"
 
@@ -9,15 +8,16 @@ import (
 )
 
 type realtime struct {
-	outputWeather             chan<- Messages.Weather
-	outputRaceControlMessages chan<- Messages.RaceControlMessage
-	outputTimingMessages      chan<- Messages.Timing
-	outputEvent               chan<- Messages.Event
-	outputTelemetry           chan<- Messages.Telemetry
-	outputLocation            chan<- Messages.Location
-	outputEventTime           chan<- Messages.EventTime
-	outputRadio               chan<- Messages.Radio
-	outputDrivers             chan<- Messages.Drivers
+	outputWeather             chan Messages.Weather
+	outputRaceControlMessages chan Messages.RaceControlMessage
+	outputTimingMessages      chan Messages.Timing
+	outputEvent               chan Messages.Event
+	outputTelemetry           chan Messages.Telemetry
+	outputLocation            chan Messages.Location
+	outputEventTime           chan Messages.EventTime
+	outputRadio               chan Messages.Radio
+	outputDrivers             chan Messages.Drivers
+	outputCarState            chan Messages.CarState
 
 	weatherLock     sync.Mutex
 	weather         []Messages.Weather
@@ -35,6 +35,11 @@ type realtime struct {
 	radio           []Messages.Radio
 	driversLock     sync.Mutex
 	drivers         []Messages.Drivers
+	carStateLock    sync.Mutex
+	carState        []Messages.CarState
+	// Every message in the order they were added, for the envelopes, if anything wants them
+	timelineLock sync.Mutex
+	timeline     []Messages.Payload
 
 	currentTime   time.Time
 	currentLap    int
@@ -51,8 +56,8 @@ type realtime struct {
 	sessionStart          time.Time
 	sessionLength         time.Duration
 
-	ctx context.Context
-	wg  *sync.WaitGroup
+	*Delivery
+	wg *sync.WaitGroup
 }
 
 func (f *realtime) Run() {
@@ -78,24 +83,23 @@ func (f *realtime) Run() {
 				f.ignoreRadioMsgsBefore = f.skipToTime
 				f.skipToTime = time.Time{}
 
-				f.radioLock.Lock()
-				for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-					f.radio = f.radio[1:]
-				}
-				f.radioLock.Unlock()
+				takeDue(&f.radioLock, &f.radio, f.currentTime)
 			}
 
 			if counter == 3 {
 				counter = 0
 
+				// Messages are taken off the queues before sending so the parser can keep adding to them while a
+				// consumer is slow to read
 				f.eventLock.Lock()
-				if len(f.event) > 0 {
-
-					if f.currentTime.IsZero() && !f.event[0].Timestamp.IsZero() {
-						f.currentTime = f.event[0].Timestamp
-						f.clockStopped = f.event[0].ClockStopped
-					}
+				hasEvents := len(f.event) > 0
+				if hasEvents && f.currentTime.IsZero() && !f.event[0].Timestamp.IsZero() {
+					f.currentTime = f.event[0].Timestamp
+					f.clockStopped = f.event[0].ClockStopped
+				}
+				f.eventLock.Unlock()
 
+				if hasEvents {
 					increment := f.incrementLapCount
 
 					if increment > 0 {
@@ -104,160 +108,83 @@ func (f *realtime) Run() {
 						targetLap := f.currentLap + increment
 						var incrementTime time.Time
 
-						for len(f.event) > 0 && f.currentLap < targetLap {
-							select {
-							case f.outputEvent <- f.event[0]:
-								f.currentLap = f.event[0].CurrentLap
-								f.currentStatus = f.event[0].Status
-								incrementTime = f.event[0].Timestamp
+						for f.currentLap < targetLap {
+							event, exists := takeFirst(&f.eventLock, &f.event)
+							if !exists {
+								break
+							}
 
-								f.sessionStart = f.event[0].SessionStartTime
-								f.sessionLength = f.event[0].RemainingTime
-								f.clockStopped = f.event[0].ClockStopped
+							if Send(f.Delivery, EventChannel, f.outputEvent, event) {
+								f.currentLap = event.CurrentLap
+								f.currentStatus = event.Status
+								incrementTime = event.Timestamp
 
-							default:
-								// Data loss
+								f.sessionStart = event.SessionStartTime
+								f.sessionLength = event.RemainingTime
+								f.clockStopped = event.ClockStopped
 							}
-
-							f.event = f.event[1:]
 						}
 
 						f.currentTime = incrementTime
 
 						// We want to skip any radio messages when we jump forward in time
-						f.radioLock.Lock()
-						for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-							f.radio = f.radio[1:]
-						}
-						f.radioLock.Unlock()
+						takeDue(&f.radioLock, &f.radio, f.currentTime)
 
 					} else {
-						for len(f.event) > 0 && (f.event[0].Timestamp.Before(f.currentTime) || f.event[0].Timestamp.Equal(f.currentTime)) {
-							select {
-							case f.outputEvent <- f.event[0]:
-								f.currentLap = f.event[0].CurrentLap
-								f.currentStatus = f.event[0].Status
-
-								f.sessionStart = f.event[0].SessionStartTime
-								f.sessionLength = f.event[0].RemainingTime
-								f.clockStopped = f.event[0].ClockStopped
-
-							default:
-								// Data loss
+						for _, event := range takeDue(&f.eventLock, &f.event, f.currentTime) {
+							if Send(f.Delivery, EventChannel, f.outputEvent, event) {
+								f.currentLap = event.CurrentLap
+								f.currentStatus = event.Status
+
+								f.sessionStart = event.SessionStartTime
+								f.sessionLength = event.RemainingTime
+								f.clockStopped = event.ClockStopped
 							}
-
-							f.event = f.event[1:]
 						}
 					}
 				}
-				f.eventLock.Unlock()
 
-				f.raceControlLock.Lock()
-				if len(f.raceControl) > 0 {
-					for len(f.raceControl) > 0 && (f.raceControl[0].Timestamp.Before(f.currentTime) || f.raceControl[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputRaceControlMessages <- f.raceControl[0]:
-						default:
-							// Data loss
-						}
-
-						f.raceControl = f.raceControl[1:]
-					}
+				for _, msg := range takeDue(&f.raceControlLock, &f.raceControl, f.currentTime) {
+					Send(f.Delivery, RaceControlChannel, f.outputRaceControlMessages, msg)
 				}
-				f.raceControlLock.Unlock()
-
-				f.weatherLock.Lock()
-				if len(f.weather) > 0 {
-					for len(f.weather) > 0 && (f.weather[0].Timestamp.Before(f.currentTime) || f.weather[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputWeather <- f.weather[0]:
-						default:
-							// Data loss
-						}
 
-						f.weather = f.weather[1:]
-					}
+				for _, msg := range takeDue(&f.weatherLock, &f.weather, f.currentTime) {
+					Send(f.Delivery, WeatherChannel, f.outputWeather, msg)
 				}
-				f.weatherLock.Unlock()
-
-				f.timingLock.Lock()
-				if len(f.timing) > 0 {
-					for len(f.timing) > 0 && (f.timing[0].Timestamp.Before(f.currentTime) || f.timing[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputTimingMessages <- f.timing[0]:
-						default:
-							// Data loss
-						}
 
-						f.timing = f.timing[1:]
-					}
+				for _, msg := range takeDue(&f.timingLock, &f.timing, f.currentTime) {
+					Send(f.Delivery, TimingChannel, f.outputTimingMessages, msg)
 				}
-				f.timingLock.Unlock()
-
-				f.telemetryLock.Lock()
-				if len(f.telemetry) > 0 {
-					for len(f.telemetry) > 0 && (f.telemetry[0].Timestamp.Before(f.currentTime) || f.telemetry[0].Timestamp.Equal(f.currentTime)) {
-						select {
-						case f.outputTelemetry <- f.telemetry[0]:
-						default:
-							// Data loss
-						}
 
-						f.telemetry = f.telemetry[1:]
-					}
+				for _, msg := range takeDue(&f.telemetryLock, &f.telemetry, f.currentTime) {
+					Send(f.Delivery, TelemetryChannel, f.outputTelemetry, msg)
 				}
-				f.telemetryLock.Unlock()
-
-				f.radioLock.Lock()
-				if len(f.radio) > 0 {
-					for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-						// If the radio message is before the skip to start of race time then ignore it
-						if !f.ignoreRadioMsgsBefore.IsZero() && f.radio[0].Timestamp.Before(f.ignoreRadioMsgsBefore) {
-							f.radio = f.radio[1:]
-							continue
-						}
-
-						select {
-						case f.outputRadio <- f.radio[0]:
-						default:
-							// Data loss
-						}
 
-						f.radio = f.radio[1:]
+				for _, msg := range takeDue(&f.radioLock, &f.radio, f.currentTime) {
+					// If the radio message is before the skip to start of race time then ignore it
+					if !f.ignoreRadioMsgsBefore.IsZero() && msg.Timestamp.Before(f.ignoreRadioMsgsBefore) {
+						continue
 					}
+
+					Send(f.Delivery, RadioChannel, f.outputRadio, msg)
 				}
-				f.radioLock.Unlock()
-
-				f.driversLock.Lock()
-				if len(f.drivers) > 0 {
-					// Send the driver list immediately so that users know who the drivers are before other data comes
-					// through.
-					select {
-					case f.outputDrivers <- f.drivers[0]:
-					default:
-						// Data loss
-					}
 
-					f.drivers = f.drivers[1:]
+				// Send the driver list immediately so that users know who the drivers are before other data comes
+				// through.
+				if drivers, exists := takeFirst(&f.driversLock, &f.drivers); exists {
+					Send(f.Delivery, DriversChannel, f.outputDrivers, drivers)
 				}
-				f.driversLock.Unlock()
 			} else {
 				counter++
 			}
 
-			f.locationLock.Lock()
-			if len(f.location) > 0 {
-				for len(f.location) > 0 && (f.location[0].Timestamp.Before(f.currentTime) || f.location[0].Timestamp.Equal(f.currentTime)) {
-					select {
-					case f.outputLocation <- f.location[0]:
-					default:
-						// Data loss
-					}
+			for _, msg := range takeDue(&f.locationLock, &f.location, f.currentTime) {
+				Send(f.Delivery, LocationChannel, f.outputLocation, msg)
+			}
 
-					f.location = f.location[1:]
-				}
+			for _, msg := range takeDue(&f.carStateLock, &f.carState, f.currentTime) {
+				Send(f.Delivery, CarStateChannel, f.outputCarState, msg)
 			}
-			f.locationLock.Unlock()
 
 			if !f.currentTime.IsZero() {
 				increment := f.incrementTime
@@ -268,11 +195,7 @@ func (f *realtime) Run() {
 					f.incrementTime = f.incrementTime - increment
 
 					// We want to skip any radio messages when we jump forward in time
-					f.radioLock.Lock()
-					for len(f.radio) > 0 && (f.radio[0].Timestamp.Before(f.currentTime) || f.radio[0].Timestamp.Equal(f.currentTime)) {
-						f.radio = f.radio[1:]
-					}
-					f.radioLock.Unlock()
+					takeDue(&f.radioLock, &f.radio, f.currentTime)
 				}
 
 				if !f.sessionStart.IsZero() && !f.clockStopped {
@@ -284,60 +207,128 @@ func (f *realtime) Run() {
 					}
 				}
 
-				f.outputEventTime <- Messages.EventTime{Timestamp: f.currentTime, Remaining: f.remainingTime}
+			}
+
+			// The channels are sent at different rates so the envelopes are sent from their own queue, in the order
+			// the messages were added, to keep the order they happened in
+			for _, payload := range takeDue(&f.timelineLock, &f.timeline, f.currentTime) {
+				f.sendEnvelope(payload)
+			}
+
+			if !f.currentTime.IsZero() {
+				eventTime := Messages.EventTime{Timestamp: f.currentTime, Remaining: f.remainingTime}
+				f.sendEnvelope(eventTime)
+				Send(f.Delivery, EventTimeChannel, f.outputEventTime, eventTime)
 
 				f.currentTime = f.currentTime.Add(time.Millisecond * 500)
 			}
+
+			f.recordOccupancy()
 		}
 	}
 }
 
+// takeDue removes the messages at or before the time from the front of the queue
+func takeDue[T Messages.Payload](lock *sync.Mutex, queue *[]T, now time.Time) []T {
+	lock.Lock()
+	defer lock.Unlock()
+
+	count := 0
+	for count < len(*queue) && !Messages.PayloadTimestamp((*queue)[count]).After(now) {
+		count++
+	}
+
+	result := (*queue)[:count:count]
+	*queue = (*queue)[count:]
+	return result
+}
+
+// takeFirst removes the first message from the queue
+func takeFirst[T any](lock *sync.Mutex, queue *[]T) (T, bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	var result T
+	if len(*queue) == 0 {
+		return result, false
+	}
+
+	result = (*queue)[0]
+	*queue = (*queue)[1:]
+	return result, true
+}
+
 func (f *realtime) AddWeather(weather Messages.Weather) {
 	f.weatherLock.Lock()
 	defer f.weatherLock.Unlock()
 	f.weather = append(f.weather, weather)
+	f.addToTimeline(weather)
 }
 
 func (f *realtime) AddRaceControlMessage(raceControlMessage Messages.RaceControlMessage) {
 	f.raceControlLock.Lock()
 	defer f.raceControlLock.Unlock()
 	f.raceControl = append(f.raceControl, raceControlMessage)
+	f.addToTimeline(raceControlMessage)
 }
 
 func (f *realtime) AddTiming(timing Messages.Timing) {
 	f.timingLock.Lock()
 	defer f.timingLock.Unlock()
 	f.timing = append(f.timing, timing)
+	f.addToTimeline(timing)
 }
 
 func (f *realtime) AddEvent(event Messages.Event) {
 	f.eventLock.Lock()
 	defer f.eventLock.Unlock()
 	f.event = append(f.event, event)
+	f.addToTimeline(event)
 }
 
 func (f *realtime) AddTelemetry(telemetry Messages.Telemetry) {
 	f.telemetryLock.Lock()
 	defer f.telemetryLock.Unlock()
 	f.telemetry = append(f.telemetry, telemetry)
+	f.addToTimeline(telemetry)
 }
 
 func (f *realtime) AddLocation(location Messages.Location) {
 	f.locationLock.Lock()
 	defer f.locationLock.Unlock()
 	f.location = append(f.location, location)
+	f.addToTimeline(location)
 }
 
 func (f *realtime) AddRadio(radio Messages.Radio) {
 	f.radioLock.Lock()
 	defer f.radioLock.Unlock()
 	f.radio = append(f.radio, radio)
+	f.addToTimeline(radio)
 }
 
 func (f *realtime) AddDrivers(drivers Messages.Drivers) {
 	f.driversLock.Lock()
 	defer f.driversLock.Unlock()
 	f.drivers = append(f.drivers, drivers)
+	f.addToTimeline(drivers)
+}
+
+func (f *realtime) AddCarState(carState Messages.CarState) {
+	f.carStateLock.Lock()
+	defer f.carStateLock.Unlock()
+	f.carState = append(f.carState, carState)
+	f.addToTimeline(carState)
+}
+
+func (f *realtime) addToTimeline(payload Messages.Payload) {
+	if f.envelopes == nil {
+		return
+	}
+
+	f.timelineLock.Lock()
+	defer f.timelineLock.Unlock()
+	f.timeline = append(f.timeline, payload)
 }
 
 func (f *realtime) IncrementLap() {
@@ -7,17 +7,20 @@ import (
 )
 
 type straightThrough struct {
-	outputWeather             chan<- Messages.Weather
-	outputRaceControlMessages chan<- Messages.RaceControlMessage
-	outputTimingMessages      chan<- Messages.Timing
-	outputEvent               chan<- Messages.Event
-	outputTelemetry           chan<- Messages.Telemetry
-	outputLocation            chan<- Messages.Location
-	outputEventTime           chan<- Messages.EventTime
-	outputRadio               chan<- Messages.Radio
-	outputDrivers             chan<- Messages.Drivers
+	outputWeather             chan Messages.Weather
+	outputRaceControlMessages chan Messages.RaceControlMessage
+	outputTimingMessages      chan Messages.Timing
+	outputEvent               chan Messages.Event
+	outputTelemetry           chan Messages.Telemetry
+	outputLocation            chan Messages.Location
+	outputEventTime           chan Messages.EventTime
+	outputRadio               chan Messages.Radio
+	outputDrivers             chan Messages.Drivers
+	outputCarState            chan Messages.CarState
 
 	isPaused bool
+
+	*Delivery
 }
 
 func (f *straightThrough) Run() {
@@ -25,37 +28,52 @@ func (f *straightThrough) Run() {
 }
 
 func (f *straightThrough) AddWeather(weather Messages.Weather) {
-	f.outputWeather <- weather
+	f.sendEnvelope(weather)
+	Send(f.Delivery, WeatherChannel, f.outputWeather, weather)
 }
 
 func (f *straightThrough) AddRaceControlMessage(raceControlMessage Messages.RaceControlMessage) {
-	f.outputRaceControlMessages <- raceControlMessage
+	f.sendEnvelope(raceControlMessage)
+	Send(f.Delivery, RaceControlChannel, f.outputRaceControlMessages, raceControlMessage)
 }
 
 func (f *straightThrough) AddTiming(timing Messages.Timing) {
-	f.outputTimingMessages <- timing
+	f.sendEnvelope(timing)
+	Send(f.Delivery, TimingChannel, f.outputTimingMessages, timing)
 }
 
 func (f *straightThrough) AddEvent(event Messages.Event) {
-	f.outputEvent <- event
+	f.sendEnvelope(event)
+	Send(f.Delivery, EventChannel, f.outputEvent, event)
 
-	f.outputEventTime <- Messages.EventTime{Timestamp: event.Timestamp}
+	eventTime := Messages.EventTime{Timestamp: event.Timestamp}
+	f.sendEnvelope(eventTime)
+	Send(f.Delivery, EventTimeChannel, f.outputEventTime, eventTime)
 }
 
 func (f *straightThrough) AddTelemetry(telemetry Messages.Telemetry) {
-	f.outputTelemetry <- telemetry
+	f.sendEnvelope(telemetry)
+	Send(f.Delivery, TelemetryChannel, f.outputTelemetry, telemetry)
 }
 
 func (f *straightThrough) AddLocation(location Messages.Location) {
-	f.outputLocation <- location
+	f.sendEnvelope(location)
+	Send(f.Delivery, LocationChannel, f.outputLocation, location)
 }
 
 func (f *straightThrough) AddRadio(radio Messages.Radio) {
-	f.outputRadio <- radio
+	f.sendEnvelope(radio)
+	Send(f.Delivery, RadioChannel, f.outputRadio, radio)
 }
 
 func (f *straightThrough) AddDrivers(drivers Messages.Drivers) {
-	f.outputDrivers <- drivers
+	f.sendEnvelope(drivers)
+	Send(f.Delivery, DriversChannel, f.outputDrivers, drivers)
+}
+
+func (f *straightThrough) AddCarState(carState Messages.CarState) {
+	f.sendEnvelope(carState)
+	Send(f.Delivery, CarStateChannel, f.outputCarState, carState)
 }
 
 func (f *straightThrough) IncrementLap() {}
@@ -3,16 +3,18 @@ module github.com/f1gopher/f1gopherlib
 go 1.23
 
 require (
+	github.com/cenkalti/backoff/v4 v4.3.0
 	github.com/f1gopher/signalr/v2 v2.0.0-20221210121059-1985aaf5fb97
+	github.com/gorilla/websocket v1.5.3
+	github.com/labstack/echo v3.3.10+incompatible
 	github.com/zsefvlol/timezonemapper v1.0.0
+	golang.org/x/net v0.33.0
 	golang.org/x/sync v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/labstack/echo v3.3.10+incompatible // indirect
 	github.com/labstack/echo/v4 v4.13.3 // indirect
 	github.com/labstack/gommon v0.4.2 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -20,7 +22,6 @@ require (
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	golang.org/x/crypto v0.31.0 // indirect
-	golang.org/x/net v0.33.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/time v0.8.0 // indirect
@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"sort"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// Samples further apart than this aren't interpolated between and a sample waits at most this long for the other
+// feed to catch up
+const maxCarStateGap = 3 * time.Second
+
+// carStates merges the telemetry and locations for each car onto one timeline. Every sample is sent as a car state
+// once the other feed has a sample at or after it, so the other feed can be interpolated to the time of the sample.
+type carStates struct {
+	drivers map[int]*carStateTimeline
+}
+
+type carStateTimeline struct {
+	// Received samples, oldest first, kept while they are needed to interpolate
+	telemetry []Messages.Telemetry
+	locations []Messages.Location
+
+	// Samples that haven't been sent yet, oldest first
+	pendingTelemetry []Messages.Telemetry
+	pendingLocations []Messages.Location
+
+	// Time of the newest sample from either feed
+	newest time.Time
+	// Time of the last car state sent, samples from before it arrived too late and are only used to interpolate
+	sent time.Time
+}
+
+func createCarStates() *carStates {
+	return &carStates{
+		drivers: make(map[int]*carStateTimeline),
+	}
+}
+
+func (c *carStates) timeline(driverNumber int) *carStateTimeline {
+	timeline, exists := c.drivers[driverNumber]
+	if !exists {
+		timeline = &carStateTimeline{}
+		c.drivers[driverNumber] = timeline
+	}
+	return timeline
+}
+
+func (c *carStates) addTelemetry(telemetry Messages.Telemetry) []Messages.CarState {
+	timeline := c.timeline(telemetry.DriverNumber)
+	timeline.telemetry = insertSample(timeline.telemetry, telemetry)
+	if !telemetry.Timestamp.Before(timeline.sent) {
+		timeline.pendingTelemetry = insertSample(timeline.pendingTelemetry, telemetry)
+	}
+	if telemetry.Timestamp.After(timeline.newest) {
+		timeline.newest = telemetry.Timestamp
+	}
+
+	return timeline.release()
+}
+
+func (c *carStates) addLocation(location Messages.Location) []Messages.CarState {
+	timeline := c.timeline(location.DriverNumber)
+	timeline.locations = insertSample(timeline.locations, location)
+	if !location.Timestamp.Before(timeline.sent) {
+		timeline.pendingLocations = insertSample(timeline.pendingLocations, location)
+	}
+	if location.Timestamp.After(timeline.newest) {
+		timeline.newest = location.Timestamp
+	}
+
+	return timeline.release()
+}
+
+// release sends the pending samples, oldest first, until one has to wait for the other feed
+func (t *carStateTimeline) release() []Messages.CarState {
+	var result []Messages.CarState
+
+	for len(t.pendingTelemetry) > 0 || len(t.pendingLocations) > 0 {
+		// Telemetry first when both are at the same time
+		useTelemetry := len(t.pendingLocations) == 0 ||
+			(len(t.pendingTelemetry) > 0 && !t.pendingTelemetry[0].Timestamp.After(t.pendingLocations[0].Timestamp))
+
+		var state Messages.CarState
+		if useTelemetry {
+			telemetry := t.pendingTelemetry[0]
+			if !t.ready(telemetry.Timestamp, len(t.locations) > 0 && !t.locations[len(t.locations)-1].Timestamp.Before(telemetry.Timestamp)) {
+				break
+			}
+
+			state = carStateFromTelemetry(telemetry)
+			setLocation(&state, t.locations)
+			t.pendingTelemetry = t.pendingTelemetry[1:]
+		} else {
+			location := t.pendingLocations[0]
+			if !t.ready(location.Timestamp, len(t.telemetry) > 0 && !t.telemetry[len(t.telemetry)-1].Timestamp.Before(location.Timestamp)) {
+				break
+			}
+
+			state = carStateFromLocation(location)
+			setTelemetry(&state, t.telemetry)
+			t.pendingLocations = t.pendingLocations[1:]
+		}
+
+		t.sent = state.Timestamp
+		result = append(result, state)
+	}
+
+	t.telemetry = trimSamples(t.telemetry, t.sent)
+	t.locations = trimSamples(t.locations, t.sent)
+
+	return result
+}
+
+// ready is true when the other feed has caught up with the time or has been missing for too long
+func (t *carStateTimeline) ready(timestamp time.Time, otherHasCaughtUp bool) bool {
+	return otherHasCaughtUp || t.newest.Sub(timestamp) > maxCarStateGap
+}
+
+func carStateFromTelemetry(telemetry Messages.Telemetry) Messages.CarState {
+	state := Messages.CarState{
+		Timestamp:    telemetry.Timestamp,
+		DriverNumber: telemetry.DriverNumber,
+		Source:       Messages.FromTelemetry,
+	}
+	copyTelemetry(&state, telemetry)
+	return state
+}
+
+func carStateFromLocation(location Messages.Location) Messages.CarState {
+	state := Messages.CarState{
+		Timestamp:    location.Timestamp,
+		DriverNumber: location.DriverNumber,
+		Source:       Messages.FromLocation,
+	}
+	copyLocation(&state, location)
+	state.FinishLineCrossing = location.FinishLineCrossing
+	return state
+}
+
+func copyTelemetry(state *Messages.CarState, telemetry Messages.Telemetry) {
+	state.TelemetryKnown = true
+	state.RPM = telemetry.RPM
+	state.Speed = telemetry.Speed
+	state.Gear = telemetry.Gear
+	state.Throttle = telemetry.Throttle
+	state.Brake = telemetry.Brake
+	state.BrakeOn = telemetry.BrakeOn
+	state.DRS = telemetry.DRS
+	state.DRSState = telemetry.DRSState
+}
+
+func copyLocation(state *Messages.CarState, location Messages.Location) {
+	state.LocationKnown = true
+	state.X = location.X
+	state.Y = location.Y
+	state.Z = location.Z
+	state.LapDistance = location.LapDistance
+	state.LapFraction = location.LapFraction
+	state.LapDistanceKnown = location.LapDistanceKnown
+}
+
+// setTelemetry interpolates the values that change smoothly and uses the sample before for the gear, brake and DRS
+func setTelemetry(state *Messages.CarState, telemetry []Messages.Telemetry) {
+	before, after, fraction, found := bracket(telemetry, state.Timestamp)
+	if !found {
+		return
+	}
+
+	copyTelemetry(state, before)
+	if after != before {
+		state.RPM = int16(lerp(float64(before.RPM), float64(after.RPM), fraction))
+		state.Speed = float32(lerp(float64(before.Speed), float64(after.Speed), fraction))
+		state.Throttle = float32(lerp(float64(before.Throttle), float64(after.Throttle), fraction))
+	}
+}
+
+func setLocation(state *Messages.CarState, locations []Messages.Location) {
+	before, after, fraction, found := bracket(locations, state.Timestamp)
+	if !found {
+		return
+	}
+
+	copyLocation(state, before)
+	if after != before {
+		state.X = lerp(before.X, after.X, fraction)
+		state.Y = lerp(before.Y, after.Y, fraction)
+		state.Z = lerp(before.Z, after.Z, fraction)
+
+		// Don't interpolate across the finish line, the lap distance goes back to 0 there
+		if before.LapDistanceKnown && after.LapDistanceKnown && after.LapDistance >= before.LapDistance {
+			state.LapDistance = lerp(before.LapDistance, after.LapDistance, fraction)
+			state.LapFraction = lerp(before.LapFraction, after.LapFraction, fraction)
+		}
+	}
+}
+
+// bracket finds the samples either side of the time and how far between them it is. When the time isn't between two
+// samples close enough together the nearest sample within the maximum gap is used for both.
+func bracket[T Messages.Telemetry | Messages.Location](samples []T, timestamp time.Time) (before T, after T, fraction float64, found bool) {
+	index := sort.Search(len(samples), func(x int) bool {
+		return !sampleTime(samples[x]).Before(timestamp)
+	})
+
+	hasBefore := index > 0
+	hasAfter := index < len(samples)
+
+	if hasAfter && sampleTime(samples[index]).Equal(timestamp) {
+		return samples[index], samples[index], 0, true
+	}
+
+	if hasBefore && hasAfter {
+		start, end := sampleTime(samples[index-1]), sampleTime(samples[index])
+		if end.Sub(start) <= maxCarStateGap {
+			return samples[index-1], samples[index], float64(timestamp.Sub(start)) / float64(end.Sub(start)), true
+		}
+	}
+
+	if hasBefore && timestamp.Sub(sampleTime(samples[index-1])) <= maxCarStateGap &&
+		(!hasAfter || timestamp.Sub(sampleTime(samples[index-1])) <= sampleTime(samples[index]).Sub(timestamp)) {
+		return samples[index-1], samples[index-1], 0, true
+	}
+
+	if hasAfter && sampleTime(samples[index]).Sub(timestamp) <= maxCarStateGap {
+		return samples[index], samples[index], 0, true
+	}
+
+	return before, after, 0, false
+}
+
+func sampleTime[T Messages.Telemetry | Messages.Location](sample T) time.Time {
+	switch value := any(sample).(type) {
+	case Messages.Telemetry:
+		return value.Timestamp
+	case Messages.Location:
+		return value.Timestamp
+	}
+	return time.Time{}
+}
+
+// insertSample keeps the samples in time order, they usually arrive in order so this is normally an append
+func insertSample[T Messages.Telemetry | Messages.Location](samples []T, sample T) []T {
+	index := len(samples)
+	for index > 0 && sampleTime(samples[index-1]).After(sampleTime(sample)) {
+		index--
+	}
+	return append(samples[:index], append([]T{sample}, samples[index:]...)...)
+}
+
+// trimSamples drops the samples that can't be used to interpolate anything not sent yet, which is everything apart
+// from the last one before the time
+func trimSamples[T Messages.Telemetry | Messages.Location](samples []T, sent time.Time) []T {
+	keep := 0
+	for keep+1 < len(samples) && !sampleTime(samples[keep+1]).After(sent) {
+		keep++
+	}
+	return samples[keep:]
+}
+
+func lerp(a, b, fraction float64) float64 {
+	return a + fraction*(b-a)
+}
@@ -8,22 +8,25 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseCarData(dat map[string]interface{}, timestamp time.Time) ([]Messages.Telemetry, []Messages.Timing, error) {
+func (p *Parser) parseCarData(dat []byte, timestamp time.Time) ([]Messages.Telemetry, []Messages.Timing, error) {
 
 	result := make([]Messages.Telemetry, 0)
 	timingResult := make([]Messages.Timing, 0)
 
-	entries := dat["Entries"].([]interface{})
-	for _, record := range entries {
+	var data carData
+	if err := p.decode(connection.CarDataFile, timestamp, dat, &data); err != nil {
+		return nil, nil, err
+	}
+
+	for _, record := range data.Entries {
 
-		timestampStr := record.(map[string]interface{})["Utc"].(string)
-		utcTimestamp, err := parseTime(timestampStr)
+		utcTimestamp, err := parseTime(record.Utc)
 		if err != nil {
 			p.ParseTimeError(connection.CarDataFile, timestamp, "Utc", err)
 		}
 		localTimestamp := utcTimestamp.In(p.timezone)
 
-		for driverId, car := range record.(map[string]interface{})["Cars"].(map[string]interface{}) {
+		record.Cars.each(func(driverId string, car carChannels) {
 			driverNum, _ := strconv.Atoi(driverId)
 
 			t := Messages.Telemetry{
@@ -31,57 +34,73 @@ func (p *Parser) parseCarData(dat map[string]interface{}, timestamp time.Time) (
 				DriverNumber: driverNum,
 			}
 
-			for id, channel := range car.(map[string]interface{})["Channels"].(map[string]interface{}) {
+			for id, channel := range car.Channels {
 				switch id {
 				case "0": // RPM
-					t.RPM = int16(channel.(float64))
-				case "2": // Speed
-					t.Speed = float32(channel.(float64))
+					t.RPM = int16(channel)
+				case "2": // Speed - km/h
+					t.Speed = float32(channel)
 				case "3": // Gear
-					t.Gear = byte(channel.(float64))
-				case "4": // Throttle
-					t.Throttle = float32(channel.(float64))
-				case "5": // Brake
-					t.Brake = float32(channel.(float64))
+					t.Gear = byte(channel)
+				case "4": // Throttle - percent
+					t.Throttle = float32(channel)
+				case "5": // Brake - 0 or 100, older data 0 or 1
+					t.Brake = float32(channel)
+					t.BrakeOn = channel > 0
 				case "45": // DRS
 					driverInfo, _ := p.driverTimes[driverId]
 
-					drsOpen := false
-					drsValue := int(channel.(float64))
-					if drsValue == 10 || drsValue == 12 || drsValue == 14 {
-						t.DRS = true
-						drsOpen = true
-					} else {
-						t.DRS = false
-						drsOpen = false
-					}
+					t.DRSValue = byte(channel)
+					t.DRSState = drsState(t.DRSValue)
+					t.DRS = t.DRSState == Messages.CarDRSOpen
 
-					if drsOpen != driverInfo.DRSOpen {
-						driverInfo.DRSOpen = drsOpen
+					if t.DRS != driverInfo.DRSOpen {
+						driverInfo.DRSOpen = t.DRS
 						p.driverTimes[driverId] = driverInfo
 						timingResult = append(timingResult, driverInfo)
 					}
 
 				default:
-					p.ParseErrorf(connection.CarDataFile, timestamp, "Unhandled channel id '%s'", id)
+					// Only report it once rather than for every sample
+					if !p.unknownChannels[id] {
+						p.unknownChannels[id] = true
+						p.ParseErrorf(connection.CarDataFile, timestamp, "Unhandled channel id '%s'", id)
+					}
 				}
 			}
 
-			// Don't send telemetry data is the car is turned off to improve performance
-			if t.RPM == 0 {
-				continue
-			}
-
-			// Only send the telemetry info if has been requested for this driver
-			p.sendTelemetryLock.Lock()
-			_, sendTelemetry := p.sendTelemetryFor[driverNum]
-			p.sendTelemetryLock.Unlock()
-			if sendTelemetry {
-				result = append(result, t)
-			}
-			// result = append(result, t)
-		}
+			result = append(result, t)
+		})
 	}
 
 	return result, timingResult, nil
 }
+
+// sendTelemetry is true if the telemetry should be sent on the telemetry channel. Car states use every sample.
+func (p *Parser) sendTelemetry(t Messages.Telemetry) bool {
+	p.sendTelemetryLock.Lock()
+	defer p.sendTelemetryLock.Unlock()
+
+	// Don't send telemetry data is the car is turned off to improve performance
+	if t.RPM == 0 && !p.sendStoppedTelemetry {
+		return false
+	}
+
+	// Only send the telemetry info if has been requested for this driver
+	_, requested := p.sendTelemetryFor[t.DriverNumber]
+	return requested
+}
+
+// drsState is the DRS state for the value in the feed. 0 and 1 are off, 8 is eligible and 10, 12 and 14 are open.
+func drsState(value byte) Messages.CarDRS {
+	switch value {
+	case 0, 1:
+		return Messages.CarDRSOff
+	case 8:
+		return Messages.CarDRSEligible
+	case 10, 12, 14:
+		return Messages.CarDRSOpen
+	default:
+		return Messages.CarDRSUnknown
+	}
+}
@@ -4,18 +4,22 @@ import (
 	"time"
 
 	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseCurrentLapData(dat map[string]interface{}, timestamp time.Time) (Messages.Event, error) {
+func (p *Parser) parseCurrentLapData(dat []byte, timestamp time.Time) (Messages.Event, error) {
 
-	value, exists := dat["CurrentLap"]
-	if exists {
-		p.eventState.CurrentLap = int(value.(float64))
+	var data lapCount
+	if err := p.decode(connection.LapCountFile, timestamp, dat, &data); err != nil {
+		return Messages.Event{}, err
 	}
 
-	value, exists = dat["TotalLaps"]
-	if exists {
-		p.eventState.TotalLaps = int(value.(float64))
+	if data.CurrentLap != nil {
+		p.eventState.CurrentLap = *data.CurrentLap
+	}
+
+	if data.TotalLaps != nil {
+		p.eventState.TotalLaps = *data.TotalLaps
 	}
 
 	p.eventState.Timestamp = timestamp
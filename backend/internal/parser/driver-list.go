@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"image/color"
+	"sort"
 	"strconv"
 	"time"
 
@@ -10,74 +11,111 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseDriverList(dat map[string]interface{}, timestamp time.Time) []Messages.Drivers {
-	var driver []Messages.Drivers = nil
+// parseDriverList applies the update to the drivers so far. The drivers message has everyone with who was added or
+// whose name, team or colour changed and there is a timing message for each driver that changed. Updates that only
+// move drivers in the list are kept without sending anything because the timing already has the positions.
+func (p *Parser) parseDriverList(dat []byte, timestamp time.Time) ([]Messages.Drivers, []Messages.Timing, error) {
+	var data keyed[driverListEntry]
+	if err := p.decode(connection.DriverListFile, timestamp, dat, &data); err != nil {
+		return nil, nil, err
+	}
 
-	for driverNum, info := range dat {
-		if driverNum == "_kf" {
-			continue
-		}
+	var added []int
+	var changed []int
+	timingResult := make([]Messages.Timing, 0)
 
-		record := info.(map[string]interface{})
+	data.each(func(driverNum string, record driverListEntry) {
+		number, err := strconv.Atoi(driverNum)
+		if err != nil {
+			// Not a driver, like the _kf flag
+			return
+		}
 
-		current, exists := p.driverTimes[driverNum]
+		info, exists := p.driverInfo[driverNum]
+		previous := info
 
 		if !exists {
-			number, _ := strconv.Atoi(driverNum)
-
-			line := 0
-			rawLine, exists := record["Line"]
-			if exists {
-				line = int(rawLine.(float64))
+			info = Messages.DriverInfo{
+				Number:   number,
+				HexColor: "#",
+				// Default colors
+				Color: color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
 			}
+		}
 
-			fullName, _ := record["FullName"].(string)
-			shortName, _ := record["Tla"].(string)
-			// TeamName and TeamColor do not always exist
-			teamName, _ := record["TeamName"].(string)
-			teamHexColour, colorExists := record["TeamColour"].(string)
-
-			// Default colors
+		if record.Line != nil {
+			info.Line = *record.Line
+		}
+		if record.FullName != nil {
+			info.Name = *record.FullName
+		}
+		if record.Tla != nil {
+			info.ShortName = *record.Tla
+		}
+		// TeamName and TeamColor do not always exist
+		if record.TeamName != nil {
+			info.Team = *record.TeamName
+		}
+		if record.TeamColour != nil {
 			teamColor := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
-			if colorExists {
-				_, err := fmt.Sscanf(teamHexColour, "%02x%02x%02x", &teamColor.R, &teamColor.G, &teamColor.B)
-				if err != nil {
-					p.ParseErrorf(connection.DriverListFile, timestamp, "Unable to parse team color: '%s', %v", teamColor, err)
-				}
+			_, err := fmt.Sscanf(*record.TeamColour, "%02x%02x%02x", &teamColor.R, &teamColor.G, &teamColor.B)
+			if err != nil {
+				p.ParseErrorf(connection.DriverListFile, timestamp, "Unable to parse team color: '%s', %v", *record.TeamColour, err)
 			}
+			info.HexColor = "#" + *record.TeamColour
+			info.Color = teamColor
+		}
 
-			current = Messages.Timing{
-				Number:    number,
-				Position:  line,
-				Name:      fullName,
-				ShortName: shortName,
-				Team:      teamName,
-				HexColor:  "#" + teamHexColour,
-				Color:     teamColor,
-			}
+		detailsChanged := exists && (info.Name != previous.Name || info.ShortName != previous.ShortName ||
+			info.Team != previous.Team || info.HexColor != previous.HexColor)
+
+		if !exists {
+			info.StartPosition = info.Line
+			added = append(added, number)
+		} else if detailsChanged {
+			changed = append(changed, number)
+		}
+		p.driverInfo[driverNum] = info
+		if exists && !detailsChanged {
+			return
+		}
 
-			if driver == nil {
-				driver = []Messages.Drivers{
-					{
-						Timestamp: timestamp,
-						Drivers:   nil,
-					},
-				}
+		current, hasTiming := p.driverTimes[driverNum]
+		if !hasTiming {
+			current = Messages.Timing{
+				Number:   number,
+				Position: info.Line,
 			}
+		}
+		current.Name = info.Name
+		current.ShortName = info.ShortName
+		current.Team = info.Team
+		current.HexColor = info.HexColor
+		current.Color = info.Color
+		p.driverTimes[driverNum] = current
 
-			driver[0].Drivers = append(driver[0].Drivers, Messages.DriverInfo{
-				StartPosition: current.Position,
-				Name:          current.Name,
-				ShortName:     current.ShortName,
-				Number:        current.Number,
-				Team:          current.Team,
-				HexColor:      current.HexColor,
-				Color:         current.Color,
-			})
+		// Drivers being added don't have any timing yet
+		if detailsChanged {
+			timingResult = append(timingResult, current)
 		}
+	})
 
-		p.driverTimes[driverNum] = current
+	if len(added) == 0 && len(changed) == 0 {
+		return nil, timingResult, nil
+	}
+
+	drivers := Messages.Drivers{
+		Timestamp: timestamp,
+		Drivers:   make([]Messages.DriverInfo, 0, len(p.driverInfo)),
+		Added:     added,
+		Changed:   changed,
+	}
+	for _, info := range p.driverInfo {
+		drivers.Drivers = append(drivers.Drivers, info)
 	}
+	sort.Slice(drivers.Drivers, func(i, j int) bool {
+		return drivers.Drivers[i].Number < drivers.Drivers[j].Number
+	})
 
-	return driver
+	return []Messages.Drivers{drivers}, timingResult, nil
 }
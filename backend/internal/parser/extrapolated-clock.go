@@ -8,22 +8,29 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseExtrapolatedClockData(dat map[string]interface{}, timestamp time.Time) (Messages.Event, error) {
+func (p *Parser) parseExtrapolatedClockData(dat []byte, timestamp time.Time) (Messages.Event, error) {
 
-	var err error
-	remaining := dat["Remaining"].(string)
+	var data extrapolatedClock
+	if err := p.decode(connection.ExtrapolatedClockFile, timestamp, dat, &data); err != nil {
+		return Messages.Event{}, err
+	}
+
+	if data.Remaining != nil {
+		remaining := strings.Replace(*data.Remaining, ":", "h", 1)
+		remaining = strings.Replace(remaining, ":", "m", 1)
+		remaining = remaining + "s"
 
-	remaining = strings.Replace(remaining, ":", "h", 1)
-	remaining = strings.Replace(remaining, ":", "m", 1)
-	remaining = remaining + "s"
-	p.eventState.RemainingTime, err = time.ParseDuration(remaining)
-	if err != nil {
-		p.ParseTimeError(connection.ExtrapolatedClockFile, timestamp, "Remaining", err)
+		var err error
+		p.eventState.RemainingTime, err = time.ParseDuration(remaining)
+		if err != nil {
+			p.ParseTimeError(connection.ExtrapolatedClockFile, timestamp, "Remaining", err)
+		}
 	}
 
-	extrapolating, exists := dat["Extrapolating"].(bool)
-	if exists && extrapolating {
-		abc, err := parseTime(dat["Utc"].(string))
+	exists := data.Extrapolating != nil
+	extrapolating := exists && *data.Extrapolating
+	if extrapolating {
+		abc, err := parseTime(data.Utc)
 		if err != nil {
 			p.ParseTimeError(connection.ExtrapolatedClockFile, timestamp, "Utc", err)
 		} else {
@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The feeds send everything the first time and after that only what has changed, so a message is a patch on what
+// came before. Fields are pointers so a field that wasn't sent can be told apart from one sent as empty, and only
+// fields that were sent are applied.
+//
+// Lists are sent as an array the first time and after that as an object keyed by the index of the items that
+// changed, see indexed. Objects keyed by driver number also have extra keys such as _kf, see keyed.
+
+// indexed is a list sent either as an array or as an object keyed by index
+type indexed[T any] struct {
+	items map[int]T
+	// Sent as an array so it has every item rather than only the ones that changed
+	whole bool
+}
+
+func (i *indexed[T]) UnmarshalJSON(data []byte) error {
+	i.items = make(map[int]T)
+	i.whole = false
+
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+
+	case len(data) > 0 && data[0] == '[':
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for x, item := range items {
+			i.items[x] = item
+		}
+		i.whole = true
+		return nil
+
+	case len(data) > 0 && data[0] == '{':
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for key, raw := range items {
+			// Skips _kf and _deleted
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			if index < 0 {
+				return fmt.Errorf("negative index %d", index)
+			}
+
+			var item T
+			if err = json.Unmarshal(raw, &item); err != nil {
+				return err
+			}
+			i.items[index] = item
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("expected an array or object but got: %.20s", data)
+	}
+}
+
+func (i indexed[T]) get(index int) (T, bool) {
+	item, exists := i.items[index]
+	return item, exists
+}
+
+func (i indexed[T]) len() int {
+	return len(i.items)
+}
+
+// each calls fn for the items in index order
+func (i indexed[T]) each(fn func(index int, item T)) {
+	indexes := make([]int, 0, len(i.items))
+	for index := range i.items {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		fn(index, i.items[index])
+	}
+}
+
+// keyed is an object keyed by driver number. Keys starting with an underscore, like _kf, aren't drivers and are
+// skipped.
+type keyed[T any] map[string]T
+
+func (k *keyed[T]) UnmarshalJSON(data []byte) error {
+	var items map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	*k = make(keyed[T], len(items))
+	for key, raw := range items {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+		(*k)[key] = item
+	}
+	return nil
+}
+
+// each calls fn for the items in driver number order
+func (k keyed[T]) each(fn func(key string, item T)) {
+	keys := make([]string, 0, len(k))
+	for key := range k {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		fn(key, k[key])
+	}
+}
+
+// TimingData
+
+type timingData struct {
+	Lines keyed[timingLine]
+	// Number of cars in each part of qualifying
+	NoEntries indexed[int]
+}
+
+type timingLine struct {
+	NumberOfPitStops        *int
+	Position                *string
+	TimeDiffToFastest       *string
+	TimeDiffToPositionAhead *string
+	GapToLeader             *string
+	IntervalToPositionAhead *timingInterval
+	Stats                   indexed[timingStats]
+	NumberOfLaps            *int
+	Sectors                 indexed[timingSector]
+	Stopped                 *bool
+	Retired                 *bool
+	BestLapTime             *timingValue
+	BestLapTimes            indexed[timingValue]
+	LastLapTime             *timingValue
+	Speeds                  *timingSpeeds
+	KnockedOut              *bool
+}
+
+type timingInterval struct {
+	Value    *string
+	Catching *bool
+}
+
+// timingStats has the gaps for each part of qualifying
+type timingStats struct {
+	TimeDiffToFastest *string
+}
+
+type timingSector struct {
+	Segments        indexed[timingSegment]
+	Value           *string
+	OverallFastest  *bool
+	PersonalFastest *bool
+}
+
+type timingSegment struct {
+	Status *int
+}
+
+// timingValue is a lap time or speed
+type timingValue struct {
+	Value           *string
+	OverallFastest  *bool
+	PersonalFastest *bool
+}
+
+type timingSpeeds struct {
+	// TODO - handle 'I1', 'I2', 'FL'
+	ST *timingValue
+}
+
+// TimingAppData
+
+type timingAppData struct {
+	Lines keyed[timingAppLine]
+}
+
+type timingAppLine struct {
+	Stints indexed[timingAppStint]
+}
+
+type timingAppStint struct {
+	Compound  *string
+	TotalLaps *int
+}
+
+// CarData.z
+
+type carData struct {
+	Entries []carEntry
+}
+
+type carEntry struct {
+	Utc  string
+	Cars keyed[carChannels]
+}
+
+type carChannels struct {
+	Channels map[string]float64
+}
+
+// Position.z
+
+type positionData struct {
+	Position []positionEntry
+}
+
+type positionEntry struct {
+	Timestamp string
+	Entries   keyed[position]
+}
+
+type position struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+// RaceControlMessages
+
+type raceControlData struct {
+	Messages indexed[raceControlMessage]
+}
+
+type raceControlMessage struct {
+	Utc     string
+	Message string
+	Flag    *string
+	Scope   string
+	Sector  int
+}
+
+// TeamRadio
+
+type teamRadioData struct {
+	Captures indexed[teamRadioCapture]
+}
+
+type teamRadioCapture struct {
+	Utc          string
+	RacingNumber string
+	Path         string
+}
+
+// DriverList
+
+// Updates only have the fields that changed
+type driverListEntry struct {
+	Line       *int
+	FullName   *string
+	Tla        *string
+	TeamName   *string
+	TeamColour *string
+}
+
+// SessionData
+
+type sessionData struct {
+	Series       indexed[sessionSeries]
+	StatusSeries indexed[sessionStatusSeries]
+}
+
+type sessionSeries struct {
+	Utc            string
+	SessionStatus  *string
+	QualifyingPart *int
+}
+
+type sessionStatusSeries struct {
+	Utc string
+}
+
+// SessionInfo
+
+type sessionInfo struct {
+	Meeting map[string]interface{}
+	Name    *string
+}
+
+// SessionStatus
+
+type sessionStatus struct {
+	Status *string
+}
+
+// ExtrapolatedClock
+
+type extrapolatedClock struct {
+	Utc           string
+	Remaining     *string
+	Extrapolating *bool
+}
+
+// Heartbeat
+
+type heartbeat struct {
+	Utc *string
+}
+
+// LapCount
+
+type lapCount struct {
+	CurrentLap *int
+	TotalLaps  *int
+}
+
+// WeatherData
+
+type weatherData struct {
+	AirTemp       *string
+	Humidity      *string
+	Pressure      *string
+	Rainfall      *string
+	TrackTemp     *string
+	WindDirection *string
+	WindSpeed     *string
+}
@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+)
+
+// The fuzz targets start from every message for the feed in the golden sessions. Run one with:
+//
+//	go test ./internal/parser -run NONE -fuzz FuzzTimingData
+
+// goldenMessages is every message for the feed in the golden sessions, decompressed for the .z feeds
+func goldenMessages(f *testing.F, feed string) [][]byte {
+	folders, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	p := createTestParser(&recordingFlow{}, Messages.RaceSession)
+
+	var messages [][]byte
+	for _, folder := range folders {
+		file := filepath.Join(folder, feed+".jsonStream")
+		if _, err = os.Stat(file); err != nil {
+			continue
+		}
+
+		for _, line := range readJsonStream(f, file) {
+			data := []byte(line.data)
+			if strings.HasSuffix(feed, ".z") {
+				if data, err = p.decompressData(data); err != nil {
+					f.Fatal(err)
+				}
+			}
+			messages = append(messages, data)
+		}
+	}
+	return messages
+}
+
+// fuzzParser has the drivers and segments from the start of the sprint so the messages for them aren't ignored
+func fuzzParser(f *testing.F) func() *Parser {
+	folder := filepath.Join("testdata", "golden", "2023-azerbaijan-sprint")
+	drivers := readJsonStream(f, filepath.Join(folder, connection.DriverListFile+".jsonStream"))[0].data
+	timing := readJsonStream(f, filepath.Join(folder, connection.TimingDataFile+".jsonStream"))[0].data
+
+	return func() *Parser {
+		p := createTestParser(&recordingFlow{}, Messages.RaceSession)
+		p.SelectTelemetrySources(telemetryForEveryone())
+		p.parseDriverList([]byte(drivers), time.Time{})
+		p.parseTimingData([]byte(timing), time.Time{})
+		return p
+	}
+}
+
+// fuzzFeed checks parsing any message for the feed never panics
+func fuzzFeed(f *testing.F, feed string, parse func(p *Parser, data []byte)) {
+	for _, msg := range goldenMessages(f, feed) {
+		f.Add(msg)
+	}
+	f.Add([]byte(`{}`))
+
+	create := fuzzParser(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parse(create(), data)
+	})
+}
+
+// FuzzHandleMessage checks any message for any feed never panics, including what is made from the parsed messages
+// such as the car states
+func FuzzHandleMessage(f *testing.F) {
+	feeds := []string{
+		connection.TimingDataFile,
+		connection.TimingAppDataFile,
+		connection.CarDataFile,
+		connection.PositionFile,
+		connection.RaceControlMessagesFile,
+		connection.TeamRadioFile,
+		connection.DriverListFile,
+		connection.SessionDataFile,
+		connection.SessionInfoFile,
+		connection.SessionStatusFile,
+		connection.ExtrapolatedClockFile,
+		connection.HeartbeatFile,
+		connection.LapCountFile,
+		connection.WeatherDataFile,
+	}
+	for x, feed := range feeds {
+		for _, msg := range goldenMessages(f, feed) {
+			f.Add(uint8(x), msg)
+		}
+	}
+
+	create := fuzzParser(f)
+	f.Fuzz(func(t *testing.T, feed uint8, data []byte) {
+		create().handleMessage(feeds[int(feed)%len(feeds)], data, time.Time{})
+	})
+}
+
+func FuzzTimingData(f *testing.F) {
+	fuzzFeed(f, connection.TimingDataFile, func(p *Parser, data []byte) {
+		p.parseTimingData(data, time.Time{})
+	})
+}
+
+func FuzzTimingAppData(f *testing.F) {
+	fuzzFeed(f, connection.TimingAppDataFile, func(p *Parser, data []byte) {
+		p.parseTimingAppData(data, time.Time{})
+	})
+}
+
+func FuzzCarData(f *testing.F) {
+	fuzzFeed(f, connection.CarDataFile, func(p *Parser, data []byte) {
+		p.parseCarData(data, time.Time{})
+	})
+}
+
+func FuzzPositionData(f *testing.F) {
+	fuzzFeed(f, connection.PositionFile, func(p *Parser, data []byte) {
+		p.parsePositionData(data, time.Time{})
+	})
+}
+
+func FuzzRaceControlMessages(f *testing.F) {
+	fuzzFeed(f, connection.RaceControlMessagesFile, func(p *Parser, data []byte) {
+		p.parseRaceControlMessagesData(data, time.Time{})
+	})
+}
+
+func FuzzTeamRadio(f *testing.F) {
+	fuzzFeed(f, connection.TeamRadioFile, func(p *Parser, data []byte) {
+		p.parseTeamRadioData(data, time.Time{})
+	})
+}
+
+func FuzzDriverList(f *testing.F) {
+	fuzzFeed(f, connection.DriverListFile, func(p *Parser, data []byte) {
+		p.parseDriverList(data, time.Time{})
+	})
+}
+
+func FuzzSessionData(f *testing.F) {
+	fuzzFeed(f, connection.SessionDataFile, func(p *Parser, data []byte) {
+		p.parseSessionDataData(data, time.Time{})
+	})
+}
+
+func FuzzSessionInfo(f *testing.F) {
+	fuzzFeed(f, connection.SessionInfoFile, func(p *Parser, data []byte) {
+		p.parseSessionInfoData(data, time.Time{})
+	})
+}
+
+func FuzzSessionStatus(f *testing.F) {
+	fuzzFeed(f, connection.SessionStatusFile, func(p *Parser, data []byte) {
+		p.parseSessionStatusData(data, time.Time{})
+	})
+}
+
+func FuzzExtrapolatedClock(f *testing.F) {
+	fuzzFeed(f, connection.ExtrapolatedClockFile, func(p *Parser, data []byte) {
+		p.parseExtrapolatedClockData(data, time.Time{})
+	})
+}
+
+func FuzzHeartbeat(f *testing.F) {
+	fuzzFeed(f, connection.HeartbeatFile, func(p *Parser, data []byte) {
+		p.parseHeartbeatData(data, time.Time{})
+	})
+}
+
+func FuzzLapCount(f *testing.F) {
+	fuzzFeed(f, connection.LapCountFile, func(p *Parser, data []byte) {
+		p.parseCurrentLapData(data, time.Time{})
+	})
+}
+
+func FuzzWeatherData(f *testing.F) {
+	fuzzFeed(f, connection.WeatherDataFile, func(p *Parser, data []byte) {
+		p.parseWeatherData(data, time.Time{})
+	})
+}
+
+func FuzzDecompressData(f *testing.F) {
+	folder := filepath.Join("testdata", "golden", "2023-azerbaijan-sprint")
+	for _, feed := range []string{connection.CarDataFile, connection.PositionFile} {
+		for _, line := range readJsonStream(f, filepath.Join(folder, feed+".jsonStream")) {
+			f.Add([]byte(line.data))
+		}
+	}
+	f.Add([]byte("not base64!"))
+
+	p := createTestParser(&recordingFlow{}, Messages.RaceSession)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p.decompressData(data)
+	})
+}
+
+func FuzzParseTime(f *testing.F) {
+	for _, value := range []string{"2023-04-29T13:30:25.1234567Z", "2018-03-25T05:08:06", "2023-04-29T13:20:01.512Z", ""} {
+		f.Add(value)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		parseTime(value)
+	})
+}
+
+func FuzzParseDuration(f *testing.F) {
+	for _, value := range []string{"1:46.542", "+0.812", "36.412", "+1:02.003", "1L", ""} {
+		f.Add(value)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		parseDuration(value)
+	})
+}
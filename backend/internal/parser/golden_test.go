@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected output of the golden sessions")
+
+// The golden sessions are short excerpts in the archive's jsonStream layout, cut down to a few drivers and a few
+// lines per feed. Each one is played through the parser and every message sent is compared with expected.jsonl.
+var goldenSessions = []struct {
+	folder  string
+	session Messages.SessionType
+}{
+	// No position or car data and no segments
+	{"2018-australia-race", Messages.RaceSession},
+	// Red flag after a crash on the first lap
+	{"2021-great-britain-race", Messages.RaceSession},
+	{"2023-azerbaijan-sprint", Messages.SprintSession},
+	{"2023-azerbaijan-sprint-shootout", Messages.QualifyingSession},
+}
+
+func TestGoldenSessions(t *testing.T) {
+	for _, golden := range goldenSessions {
+		t.Run(golden.folder, func(t *testing.T) {
+			folder := filepath.Join("testdata", "golden", golden.folder)
+			sent := playGoldenSession(t, golden.folder, golden.session)
+
+			var actual bytes.Buffer
+			for _, msg := range sent {
+				line, err := json.Marshal(struct {
+					Kind    string
+					Message Messages.Payload
+				}{
+					Kind:    Messages.CreateEnvelope(msg, 0).Kind.String(),
+					Message: msg,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual.Write(line)
+				actual.WriteByte('\n')
+			}
+
+			expectedFile := filepath.Join(folder, "expected.jsonl")
+			if *update {
+				if err := os.WriteFile(expectedFile, actual.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(expectedFile)
+			if err != nil {
+				t.Fatalf("%v, run with -update to create it", err)
+			}
+
+			expectedLines := strings.Split(string(expected), "\n")
+			actualLines := strings.Split(actual.String(), "\n")
+			for x := 0; x < max(len(expectedLines), len(actualLines)); x++ {
+				var want, got string
+				if x < len(expectedLines) {
+					want = expectedLines[x]
+				}
+				if x < len(actualLines) {
+					got = actualLines[x]
+				}
+
+				if want != got {
+					t.Fatalf("message %d is different\nexpected: %s\nactual:   %s", x+1, want, got)
+				}
+			}
+		})
+	}
+}
+
+// playGoldenSession gives every message the parser sends for the golden session
+func playGoldenSession(t testing.TB, name string, session Messages.SessionType) []Messages.Payload {
+	t.Helper()
+
+	payloads := readGoldenSession(t, filepath.Join("testdata", "golden", name))
+
+	output := &recordingFlow{}
+	p := createTestParser(output, session)
+	p.SelectTelemetrySources(telemetryForEveryone())
+
+	incoming := make(chan connection.Payload, len(payloads)+1)
+	for _, payload := range payloads {
+		incoming <- payload
+	}
+	incoming <- connection.Payload{Name: connection.EndOfDataFile}
+	p.incoming = incoming
+	p.Process()
+
+	return output.sent
+}
+
+// The expected output is rewritten whenever the messages change so these check the things each session is there for
+func TestGoldenSessionDetails(t *testing.T) {
+	t.Run("2018 without position data", func(t *testing.T) {
+		timing := 0
+		for _, msg := range playGoldenSession(t, "2018-australia-race", Messages.RaceSession) {
+			switch msg := msg.(type) {
+			case Messages.Location, Messages.Telemetry, Messages.CarState:
+				t.Errorf("expected no car data or positions, got %T", msg)
+			case Messages.Timing:
+				timing++
+				if msg.Position <= 0 {
+					t.Errorf("expected timing to still have the race position, got %+v", msg)
+				}
+			}
+		}
+		if timing == 0 {
+			t.Error("expected timing without position data")
+		}
+	})
+
+	t.Run("red flag", func(t *testing.T) {
+		stopped, restarted := false, false
+		for _, msg := range playGoldenSession(t, "2021-great-britain-race", Messages.RaceSession) {
+			if event, ok := msg.(Messages.Event); ok {
+				switch {
+				case event.Status == Messages.Aborted && event.TrackStatus == Messages.RedFlag:
+					stopped = true
+				case stopped && event.Status == Messages.Started:
+					restarted = true
+				}
+			}
+		}
+		if !stopped || !restarted {
+			t.Errorf("expected the race to be stopped for the red flag and restarted, stopped %v restarted %v", stopped, restarted)
+		}
+	})
+
+	t.Run("sprint fastest lap", func(t *testing.T) {
+		for _, msg := range playGoldenSession(t, "2023-azerbaijan-sprint", Messages.SprintSession) {
+			if timing, ok := msg.(Messages.Timing); ok && timing.OverallFastestLap && timing.FastestLap == 0 {
+				t.Errorf("car %d has the fastest lap without a lap time", timing.Number)
+			}
+		}
+	})
+}
+
+func telemetryForEveryone() []int {
+	drivers := make([]int, 100)
+	for x := range drivers {
+		drivers[x] = x
+	}
+	return drivers
+}
+
+// readGoldenSession gives the messages in the feeds in the order they were received, with the timestamps the replay
+// would give them
+func readGoldenSession(t testing.TB, folder string) []connection.Payload {
+	files, err := filepath.Glob(filepath.Join(folder, "*.jsonStream"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no jsonStream files in %s: %v", folder, err)
+	}
+
+	type line struct {
+		offset time.Duration
+		order  int
+		name   string
+		data   string
+	}
+	var lines []line
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".jsonStream")
+		order := slices.Index(connection.OrderedFiles[:], name)
+		if order == -1 {
+			t.Fatalf("unknown feed %s", name)
+		}
+
+		for _, entry := range readJsonStream(t, file) {
+			lines = append(lines, line{offset: entry.offset, order: order, name: name, data: entry.data})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].offset != lines[j].offset {
+			return lines[i].offset < lines[j].offset
+		}
+		return lines[i].order < lines[j].order
+	})
+
+	// Like the replay the first clock update says when the data starts
+	clock := readJsonStream(t, filepath.Join(folder, connection.ExtrapolatedClockFile+".jsonStream"))[0]
+	var start struct{ Utc string }
+	if err = json.Unmarshal([]byte(clock.data), &start); err != nil {
+		t.Fatal(err)
+	}
+	clockTime, err := parseTime(start.Utc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataStart := clockTime.Add(-clock.offset)
+
+	payloads := make([]connection.Payload, 0, len(lines))
+	for _, line := range lines {
+		payloads = append(payloads, connection.Payload{
+			Name:      line.name,
+			Data:      []byte(line.data),
+			Timestamp: dataStart.Add(line.offset).Format("2006-01-02T15:04:05.999Z"),
+		})
+	}
+	return payloads
+}
+
+type jsonStreamLine struct {
+	offset time.Duration
+	data   string
+}
+
+// readJsonStream splits the file into the time from the start of the data and the message. Compressed messages are
+// left base64 encoded without the quotes.
+func readJsonStream(t testing.TB, file string) []jsonStreamLine {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []jsonStreamLine
+	for _, text := range strings.Split(strings.TrimPrefix(string(content), "\ufeff"), "\n") {
+		text = strings.TrimRight(text, "\r")
+		if len(text) == 0 {
+			continue
+		}
+		if len(text) < 13 {
+			t.Fatalf("%s: line too short '%s'", file, text)
+		}
+
+		offset, err := time.ParseDuration(fmt.Sprintf("%sh%sm%ss%sms", text[:2], text[3:5], text[6:8], text[9:12]))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		result = append(result, jsonStreamLine{offset: offset, data: strings.Trim(text[12:], `"`)})
+	}
+	return result
+}
@@ -7,14 +7,20 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseHeartbeatData(dat map[string]interface{}, timestamp time.Time) (Messages.Event, error) {
-
-	time := dat["Utc"].(string)
-	value, err := parseTime(time)
-	if err != nil {
-		p.ParseTimeError(connection.HeartbeatFile, timestamp, "Utc", err)
-	} else {
-		p.eventState.Timestamp = value
+func (p *Parser) parseHeartbeatData(dat []byte, timestamp time.Time) (Messages.Event, error) {
+
+	var data heartbeat
+	if err := p.decode(connection.HeartbeatFile, timestamp, dat, &data); err != nil {
+		return Messages.Event{}, err
+	}
+
+	if data.Utc != nil {
+		value, err := parseTime(*data.Utc)
+		if err != nil {
+			p.ParseTimeError(connection.HeartbeatFile, timestamp, "Utc", err)
+		} else {
+			p.eventState.Timestamp = value
+		}
 	}
 
 	// TODO - ignore _kf or flag when no heartbeat recieved?
@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"math"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// Positions are in tenths of a metre
+const positionUnitsPerMetre = 10.0
+
+// A lap needs at least this many locations to be used as the reference
+const minReferencePoints = 20
+
+// Further than this from the part of the lap the car was last near means it isn't there anymore, in metres
+const maxDistanceFromReference = 50.0
+
+// A lap taking longer than this isn't a normal lap, for example the car stopped on track, so it isn't recorded
+const maxReferenceLapTime = 5 * time.Minute
+
+// lapReference is a lap recorded from one car which is used to measure how far round the lap every car is. The
+// first car to complete a lap without going into the pitlane is recorded, starting and ending at the times the timing
+// says it crossed the finish line.
+type lapReference struct {
+	// A closed loop starting at the finish line
+	points []referencePoint
+	// Metres
+	length float64
+
+	recordingDriver int
+	recordingStart  time.Time
+	recording       []Messages.Location
+
+	lastLocation map[int]Messages.Location
+	progress     map[int]*lapProgress
+}
+
+type referencePoint struct {
+	x, y, z float64
+	// Metres from the finish line
+	distance float64
+}
+
+// lapProgress is the last location for a driver that was measured against the reference
+type lapProgress struct {
+	location Messages.Location
+	segment  int
+}
+
+func createLapReference() *lapReference {
+	return &lapReference{
+		lastLocation: make(map[int]Messages.Location),
+		progress:     make(map[int]*lapProgress),
+	}
+}
+
+// lapCompleted is called when the timing says the driver has crossed the finish line. The lap count in a catchup has
+// no timestamp and isn't a crossing that was seen, so the lap is only recorded from the next one.
+func (l *lapReference) lapCompleted(driverNumber int, timestamp time.Time, location Messages.CarLocation) {
+	if l.points != nil || timestamp.IsZero() {
+		return
+	}
+
+	if l.recordingDriver == driverNumber && onTrack(location) {
+		l.createReference(timestamp)
+		if l.points != nil {
+			return
+		}
+	}
+
+	// Didn't work so try again with the first driver to start a lap
+	if (l.recordingDriver == 0 || l.recordingDriver == driverNumber) && onTrack(location) {
+		l.recordingDriver = driverNumber
+		l.recordingStart = timestamp
+		l.recording = nil
+	}
+}
+
+// locationChanged stops recording the lap if the driver isn't on track anymore, like going into the pitlane
+func (l *lapReference) locationChanged(driverNumber int, location Messages.CarLocation) {
+	if l.recordingDriver == driverNumber && !onTrack(location) {
+		l.recordingDriver = 0
+		l.recording = nil
+	}
+}
+
+// onTrack is true unless the car is in the pitlane or has stopped. Data without segments never knows where the car is.
+func onTrack(location Messages.CarLocation) bool {
+	return location == Messages.OnTrack || location == Messages.NoLocation
+}
+
+func (l *lapReference) createReference(end time.Time) {
+	var lap []Messages.Location
+	for _, location := range l.recording {
+		if !location.Timestamp.After(end) {
+			lap = append(lap, location)
+		}
+	}
+
+	l.recordingDriver = 0
+	l.recording = nil
+
+	if len(lap) < minReferencePoints {
+		return
+	}
+
+	points := make([]referencePoint, len(lap))
+	for x, location := range lap {
+		points[x] = referencePoint{
+			x: location.X / positionUnitsPerMetre,
+			y: location.Y / positionUnitsPerMetre,
+			z: location.Z / positionUnitsPerMetre,
+		}
+		if x > 0 {
+			points[x].distance = points[x-1].distance + pointDistance(points[x-1], points[x])
+		}
+	}
+
+	l.points = points
+	// Back to the start
+	l.length = points[len(points)-1].distance + pointDistance(points[len(points)-1], points[0])
+}
+
+// measure adds how far round the lap the location is and when the car crossed the finish line if it just has
+func (l *lapReference) measure(location *Messages.Location) {
+	previous, hasPrevious := l.lastLocation[location.DriverNumber]
+	l.lastLocation[location.DriverNumber] = *location
+
+	if l.points == nil {
+		l.record(*location, previous, hasPrevious)
+		return
+	}
+
+	progress := l.progress[location.DriverNumber]
+	near := -1
+	if progress != nil {
+		near = progress.segment
+	}
+
+	distance, segment, found := l.locate(location.X, location.Y, location.Z, near)
+	if !found {
+		delete(l.progress, location.DriverNumber)
+		return
+	}
+
+	location.LapDistance = distance
+	location.LapFraction = distance / l.length
+	location.LapDistanceKnown = true
+
+	// Going from the end of the lap to the start is crossing the finish line
+	if progress != nil && progress.location.LapDistance-distance > l.length/2 {
+		remaining := l.length - progress.location.LapDistance
+		taken := location.Timestamp.Sub(progress.location.Timestamp)
+		location.FinishLineCrossing = progress.location.Timestamp.Add(
+			time.Duration(float64(taken) * remaining / (remaining + distance)))
+	}
+
+	l.progress[location.DriverNumber] = &lapProgress{location: *location, segment: segment}
+}
+
+func (l *lapReference) record(location Messages.Location, previous Messages.Location, hasPrevious bool) {
+	if l.recordingDriver != location.DriverNumber || location.Timestamp.Before(l.recordingStart) {
+		return
+	}
+
+	// Never finished the lap so start again with the next driver to cross the line
+	if location.Timestamp.Sub(l.recordingStart) > maxReferenceLapTime {
+		l.recordingDriver = 0
+		l.recording = nil
+		return
+	}
+
+	// Start exactly where the car crossed the finish line
+	if len(l.recording) == 0 && hasPrevious && previous.Timestamp.Before(l.recordingStart) {
+		l.recording = append(l.recording, interpolateLocation(previous, location, l.recordingStart))
+	}
+
+	l.recording = append(l.recording, location)
+}
+
+// locate finds the closest part of the reference to the position. Looks near the segment the car was last on first,
+// so parts of a track that are close together aren't mixed up, or everywhere when near is -1.
+func (l *lapReference) locate(x, y, z float64, near int) (distance float64, segment int, found bool) {
+	position := referencePoint{x: x / positionUnitsPerMetre, y: y / positionUnitsPerMetre, z: z / positionUnitsPerMetre}
+
+	search := func(from int, count int) (float64, int, float64) {
+		bestOffset := math.MaxFloat64
+		var bestDistance float64
+		bestSegment := -1
+
+		for x := 0; x < count; x++ {
+			index := ((from+x)%len(l.points) + len(l.points)) % len(l.points)
+			start := l.points[index]
+			end := l.points[(index+1)%len(l.points)]
+			endDistance := end.distance
+			if index == len(l.points)-1 {
+				endDistance = l.length
+			}
+
+			fraction, offset := projectOntoSegment(position, start, end)
+			if offset < bestOffset {
+				bestOffset = offset
+				bestSegment = index
+				bestDistance = start.distance + fraction*(endDistance-start.distance)
+			}
+		}
+
+		return bestDistance, bestSegment, bestOffset
+	}
+
+	if near >= 0 {
+		// Cars only go forwards so look a little behind and further ahead
+		distance, segment, offset := search(near-5, 35)
+		if offset <= maxDistanceFromReference {
+			return distance, segment, true
+		}
+	}
+
+	distance, segment, offset := search(0, len(l.points))
+	return distance, segment, offset <= maxDistanceFromReference
+}
+
+// projectOntoSegment is how far along the segment the closest point to the position is (0 to 1) and how far away
+// it is
+func projectOntoSegment(position, start, end referencePoint) (float64, float64) {
+	dx, dy, dz := end.x-start.x, end.y-start.y, end.z-start.z
+	lengthSquared := dx*dx + dy*dy + dz*dz
+
+	fraction := 0.0
+	if lengthSquared > 0 {
+		fraction = ((position.x-start.x)*dx + (position.y-start.y)*dy + (position.z-start.z)*dz) / lengthSquared
+		fraction = math.Max(0, math.Min(1, fraction))
+	}
+
+	closest := referencePoint{x: start.x + fraction*dx, y: start.y + fraction*dy, z: start.z + fraction*dz}
+	return fraction, pointDistance(closest, position)
+}
+
+func pointDistance(a, b referencePoint) float64 {
+	return math.Sqrt(math.Pow(b.x-a.x, 2) + math.Pow(b.y-a.y, 2) + math.Pow(b.z-a.z, 2))
+}
+
+func interpolateLocation(a, b Messages.Location, at time.Time) Messages.Location {
+	fraction := 0.0
+	if total := b.Timestamp.Sub(a.Timestamp); total > 0 {
+		fraction = float64(at.Sub(a.Timestamp)) / float64(total)
+	}
+
+	return Messages.Location{
+		Timestamp:    at,
+		DriverNumber: a.DriverNumber,
+		X:            a.X + fraction*(b.X-a.X),
+		Y:            a.Y + fraction*(b.Y-a.Y),
+		Z:            a.Z + fraction*(b.Z-a.Z),
+	}
+}
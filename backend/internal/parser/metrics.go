@@ -0,0 +1,15 @@
+package parser
+
+import (
+	"github.com/f1gopher/f1gopherlib/metrics"
+)
+
+var messagesParsed = metrics.Default.CounterVec(
+	"f1gopher_parser_messages_total",
+	"Messages received for each feed",
+	"feed")
+
+var parseErrors = metrics.Default.CounterVec(
+	"f1gopher_parser_errors_total",
+	"Messages or fields that couldn't be parsed for each feed file",
+	"file")
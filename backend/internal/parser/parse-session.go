@@ -1,72 +1,61 @@
 package parser
 
 import (
-	"fmt"
-	"reflect"
 	"time"
 
 	"github.com/f1gopher/f1gopherlib/Messages"
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseSessionDataData(dat map[string]interface{}, timestamp time.Time) ([]Messages.Event, error) {
+func (p *Parser) parseSessionDataData(dat []byte, timestamp time.Time) ([]Messages.Event, []Messages.Timing, error) {
 
 	var result []Messages.Event
+	var timingResult []Messages.Timing
 
-	if dat["Series"] != nil && reflect.TypeOf(dat["Series"]).Kind() == reflect.Map {
-		for _, series := range dat["Series"].(map[string]interface{}) {
-
-			var text string
-			msg, exists := series.(map[string]interface{})["SessionStatus"]
-			if exists {
-				text = msg.(string)
-
-			} else {
-				msg, exists = series.(map[string]interface{})["QualifyingPart"]
-				if exists {
-					text = fmt.Sprintf("%g", msg.(float64))
+	var data sessionData
+	if err := p.decode(connection.SessionDataFile, timestamp, dat, &data); err != nil {
+		return nil, nil, err
+	}
 
-					switch text {
-					case "0":
-						p.eventState.Type = Messages.Qualifying0
-					case "1":
-						p.eventState.Type = Messages.Qualifying1
-					case "2":
-						p.eventState.Type = Messages.Qualifying2
-					case "3":
-						p.eventState.Type = Messages.Qualifying3
-					default:
-						p.ParseErrorf(connection.SessionDataFile, timestamp, "SessionData: Unhandled value for QualifyingPart '%s'", text)
-					}
+	// Only the changes sent as an object are new, the whole list is the history of the session so far
+	if data.Series.len() > 0 && !data.Series.whole {
+		data.Series.each(func(_ int, series sessionSeries) {
+			if series.SessionStatus == nil && series.QualifyingPart != nil {
+				switch *series.QualifyingPart {
+				case 0:
+					p.eventState.Type = Messages.Qualifying0
+				case 1:
+					p.eventState.Type = Messages.Qualifying1
+				case 2:
+					p.eventState.Type = Messages.Qualifying2
+				case 3:
+					p.eventState.Type = Messages.Qualifying3
+				default:
+					p.ParseErrorf(connection.SessionDataFile, timestamp, "SessionData: Unhandled value for QualifyingPart '%d'", *series.QualifyingPart)
 				}
-			}
 
-			time := series.(map[string]interface{})["Utc"].(string)
-			value, err := parseTime(time)
-			if err != nil {
-				p.ParseTimeError(connection.SessionDataFile, timestamp, "Utc", err)
-			} else {
-				p.eventState.Timestamp = value
+				// A new part so a new drop zone
+				timingResult = p.updateKnockoutZone(timingResult)
 			}
 
+			p.setSessionDataTime(series.Utc, timestamp)
 			result = append(result, p.eventState)
-		}
-	} else {
-		if reflect.TypeOf(dat["StatusSeries"]).Kind() == reflect.Map {
-			for _, series := range dat["StatusSeries"].(map[string]interface{}) {
-				time := series.(map[string]interface{})["Utc"].(string)
+		})
+	} else if !data.StatusSeries.whole {
+		data.StatusSeries.each(func(_ int, series sessionStatusSeries) {
+			p.setSessionDataTime(series.Utc, timestamp)
+			result = append(result, p.eventState)
+		})
+	}
 
-				value, err := parseTime(time)
-				if err != nil {
-					p.ParseTimeError(connection.SessionDataFile, timestamp, "Utc", err)
-				} else {
-					p.eventState.Timestamp = value
-				}
+	return result, timingResult, nil
+}
 
-				result = append(result, p.eventState)
-			}
-		}
+func (p *Parser) setSessionDataTime(utc string, timestamp time.Time) {
+	value, err := parseTime(utc)
+	if err != nil {
+		p.ParseTimeError(connection.SessionDataFile, timestamp, "Utc", err)
+	} else {
+		p.eventState.Timestamp = value
 	}
-
-	return result, nil
 }
@@ -6,8 +6,10 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +32,8 @@ const (
 	Location
 	TeamRadio
 	Drivers
+	// Telemetry and locations merged onto one timeline
+	CarState
 )
 
 type Parser struct {
@@ -39,7 +43,9 @@ type Parser struct {
 	output   flowControl.Flow
 
 	driverTimes map[string]Messages.Timing
+	driverInfo  map[string]Messages.DriverInfo
 	eventState  Messages.Event
+	weather     Messages.Weather
 
 	assets connection.AssetStore
 
@@ -51,8 +57,22 @@ type Parser struct {
 	ctx context.Context
 	wg  *sync.WaitGroup
 
-	sendTelemetryFor  map[int]bool
-	sendTelemetryLock sync.Mutex
+	sendTelemetryFor     map[int]bool
+	sendStoppedTelemetry bool
+	sendTelemetryLock    sync.Mutex
+	unknownChannels      map[string]bool
+
+	// The catchup after reconnecting has every race control message and team radio so far. Remember which have
+	// been sent so they aren't sent again.
+	catchups        int
+	seenRaceControl map[string]bool
+	seenRadio       map[string]bool
+
+	// Number of cars in each part of qualifying when the feed sends it
+	qualifyingEntries map[int]int
+
+	lapReference *lapReference
+	carStates    *carStates
 }
 
 // Hardcoded shortcut for:
@@ -81,24 +101,50 @@ func Create(
 		incoming:         incoming,
 		output:           output,
 		driverTimes:      make(map[string]Messages.Timing),
+		driverInfo:       make(map[string]Messages.DriverInfo),
 		assets:           assets,
 		session:          session,
 		timezone:         timezone,
 		log:              log,
 		sendTelemetryFor: nil,
+		seenRaceControl:  make(map[string]bool),
+		seenRadio:        make(map[string]bool),
+
+		qualifyingEntries: make(map[int]int),
+		unknownChannels:   make(map[string]bool),
+		lapReference:      createLapReference(),
+		carStates:         createCarStates(),
 	}
 
 	return &abc
 }
 
 func (p *Parser) ParseErrorf(file string, timestamp time.Time, msg string, a ...any) {
-	p.log.Errorf("%s - %v: %s", file, timestamp, fmt.Sprintf(msg, a))
+	parseErrors.With(file).Inc()
+	p.log.Errorf("%s - %v: %s", file, timestamp, fmt.Sprintf(msg, a...))
 }
 
 func (p *Parser) ParseTimeError(file string, timestamp time.Time, field string, err error) {
+	parseErrors.With(file).Inc()
 	p.log.Errorf("%s - %v: Unable to parse time for '%s': %v", file, timestamp, field, err)
 }
 
+// driverNumbers is every driver in number order so messages for all the drivers are always sent in the same order
+func (p *Parser) driverNumbers() []string {
+	numbers := make([]string, 0, len(p.driverTimes))
+	for number := range p.driverTimes {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		a, b := p.driverTimes[numbers[i]].Number, p.driverTimes[numbers[j]].Number
+		if a != b {
+			return a < b
+		}
+		return numbers[i] < numbers[j]
+	})
+	return numbers
+}
+
 func (p *Parser) SelectTelemetrySources(drivers []int) {
 	if drivers == nil {
 		p.sendTelemetryLock.Lock()
@@ -117,6 +163,13 @@ func (p *Parser) SelectTelemetrySources(drivers []int) {
 	p.sendTelemetryFor = tmp
 }
 
+// SendStoppedCarTelemetry sends telemetry when the RPM is 0, which is skipped by default as the car is turned off
+func (p *Parser) SendStoppedCarTelemetry(send bool) {
+	p.sendTelemetryLock.Lock()
+	defer p.sendTelemetryLock.Unlock()
+	p.sendStoppedTelemetry = send
+}
+
 func (p *Parser) Process() {
 	p.wg.Add(1)
 	defer p.wg.Done()
@@ -132,52 +185,63 @@ func (p *Parser) Process() {
 				return
 
 			case connection.CatchupFile:
-				var dat map[string]interface{}
-				if err := json.Unmarshal([]byte(msg.Data), &dat); err != nil {
+				var dat map[string]json.RawMessage
+				if err := json.Unmarshal(msg.Data, &dat); err != nil {
+					parseErrors.With(msg.Name).Inc()
 					p.log.Errorf("Catchup data parse error: '%v' for data: %s", err, msg.Data)
 					continue
 				}
 
 				zeroTimestamp := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+				p.catchups++
 
 				for _, fileName := range connection.OrderedFiles {
-					if fileName == connection.TeamRadioFile ||
-						fileName == connection.ContentStreamsFile ||
+					if fileName == connection.ContentStreamsFile ||
 						fileName == connection.AudioStreamsFile {
 						continue
 					}
 
 					fileData, exists := dat[fileName]
-					if exists {
-						if strings.HasSuffix(fileName, ".z") {
-							abc, err := p.decompressData([]byte(fileData.(string)))
-							if err != nil {
-								p.log.Errorf("Decompressing data for file '%s': %v with data: %s", msg.Name, err, msg.Data)
-								continue
-							}
-
-							p.handleMessage(fileName, abc, zeroTimestamp)
-						} else {
-							p.handleMessage(fileName, fileData.(map[string]interface{}), zeroTimestamp)
+					if !exists {
+						continue
+					}
+
+					// Radio from before we connected is old news but anything missed while reconnecting is wanted
+					if fileName == connection.TeamRadioFile && p.catchups == 1 {
+						p.skipTeamRadio(fileData, zeroTimestamp)
+						continue
+					}
+
+					if strings.HasSuffix(fileName, ".z") {
+						var compressed string
+						if err := json.Unmarshal(fileData, &compressed); err != nil {
+							p.ParseErrorf(fileName, zeroTimestamp, "Expected compressed data: %v", err)
+							continue
 						}
+
+						abc, err := p.decompressData([]byte(compressed))
+						if err != nil {
+							parseErrors.With(fileName).Inc()
+							p.log.Errorf("Decompressing data for file '%s': %v with data: %s", msg.Name, err, msg.Data)
+							continue
+						}
+
+						p.handleMessage(fileName, abc, zeroTimestamp)
+					} else {
+						p.handleMessage(fileName, fileData, zeroTimestamp)
 					}
 				}
 
 			default:
-				var dat map[string]interface{}
-				var err error
+				dat := msg.Data
 				if strings.HasSuffix(msg.Name, ".z") {
+					var err error
 					dat, err = p.decompressData(msg.Data)
 					if err != nil {
+						parseErrors.With(msg.Name).Inc()
 						p.log.Errorf("Decompressing data for file '%s': %v with data: %s", msg.Name, err, msg.Data)
 						continue
 					}
-
-				} else {
-					if err := json.Unmarshal([]byte(msg.Data), &dat); err != nil {
-						p.log.Errorf("Data parse error for file '%s': '%v' for data: %s", msg.Name, err, msg.Data)
-						continue
-					}
 				}
 
 				dataTime, err := parseTime(msg.Timestamp)
@@ -191,33 +255,66 @@ func (p *Parser) Process() {
 	}
 }
 
-func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestamp time.Time) {
+// decode reads a feed message into its typed form. A field with the wrong type is reported and left unset and what
+// could be read is still used. Anything else wrong with the message is reported and returned.
+func (p *Parser) decode(file string, timestamp time.Time, data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+
+	p.ParseErrorf(file, timestamp, "Decoding message: %v", err)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil
+	}
+	return err
+}
+
+func (p *Parser) handleMessage(name string, dat []byte, timestamp time.Time) {
+	messagesParsed.With(name).Inc()
+
 	switch name {
 	case connection.WeatherDataFile:
 		if p.requestedData&Weather == Weather {
-			outgoing, err := parseWeatherData(dat, timestamp)
+			outgoing, err := p.parseWeatherData(dat, timestamp)
 			if err == nil {
 				p.output.AddWeather(outgoing)
 			}
 		}
 
 	case connection.SessionDataFile:
-		if p.requestedData&Event == Event {
-			outgoing, err := p.parseSessionDataData(dat, timestamp)
+		if p.requestedData&Event == Event || p.requestedData&Timing == Timing {
+			outgoing, timingOutgoing, err := p.parseSessionDataData(dat, timestamp)
 			if err == nil {
-				for _, rcMsg := range outgoing {
-					p.output.AddEvent(rcMsg)
+				if p.requestedData&Event == Event {
+					for _, rcMsg := range outgoing {
+						p.output.AddEvent(rcMsg)
+					}
+				}
+
+				if p.requestedData&Timing == Timing {
+					for _, timingMsg := range timingOutgoing {
+						p.output.AddTiming(timingMsg)
+					}
 				}
 			}
 		}
 
 	case connection.TimingDataFile:
 		if p.requestedData&Timing == Timing {
-			outgoing, err := p.parseTimingData(dat, timestamp)
+			outgoing, outgoingEvent, err := p.parseTimingData(dat, timestamp)
 			if err == nil {
 				for _, rcMsg := range outgoing {
 					p.output.AddTiming(rcMsg)
 				}
+
+				if p.requestedData&Event == Event {
+					for _, eventMsg := range outgoingEvent {
+						p.output.AddEvent(eventMsg)
+					}
+				}
 			}
 		}
 
@@ -240,12 +337,22 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 		}
 
 	case connection.CarDataFile:
-		if p.requestedData&Telemetry == Telemetry || p.requestedData&Timing == Timing {
+		if p.requestedData&Telemetry == Telemetry || p.requestedData&Timing == Timing || p.requestedData&CarState == CarState {
 			outgoing, timingOutgoing, err := p.parseCarData(dat, timestamp)
 			if err == nil {
 				if p.requestedData&Telemetry == Telemetry {
 					for _, rcMsg := range outgoing {
-						p.output.AddTelemetry(rcMsg)
+						if p.sendTelemetry(rcMsg) {
+							p.output.AddTelemetry(rcMsg)
+						}
+					}
+				}
+
+				if p.requestedData&CarState == CarState {
+					for _, rcMsg := range outgoing {
+						for _, carState := range p.carStates.addTelemetry(rcMsg) {
+							p.output.AddCarState(carState)
+						}
 					}
 				}
 
@@ -258,11 +365,21 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 		}
 
 	case connection.PositionFile:
-		if p.requestedData&Location == Location {
+		if p.requestedData&Location == Location || p.requestedData&CarState == CarState {
 			outgoing, err := p.parsePositionData(dat, timestamp)
 			if err == nil {
-				for _, rcMsg := range outgoing {
-					p.output.AddLocation(rcMsg)
+				if p.requestedData&Location == Location {
+					for _, rcMsg := range outgoing {
+						p.output.AddLocation(rcMsg)
+					}
+				}
+
+				if p.requestedData&CarState == CarState {
+					for _, rcMsg := range outgoing {
+						for _, carState := range p.carStates.addLocation(rcMsg) {
+							p.output.AddCarState(carState)
+						}
+					}
 				}
 			}
 		}
@@ -308,7 +425,6 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 
 				if p.requestedData&Timing == Timing {
 					for _, timingMsg := range outgoingTiming {
-						fmt.Println("timingMsg", timingMsg)
 						p.output.AddTiming(timingMsg)
 					}
 				}
@@ -332,10 +448,19 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 		}
 
 	case connection.DriverListFile:
-		outgoing := p.parseDriverList(dat, timestamp)
-		if p.requestedData&Drivers == Drivers && outgoing != nil {
-			// Is always only one record
-			p.output.AddDrivers(outgoing[0])
+		outgoing, timingOutgoing, err := p.parseDriverList(dat, timestamp)
+		if err == nil {
+			if p.requestedData&Drivers == Drivers {
+				for _, driversMsg := range outgoing {
+					p.output.AddDrivers(driversMsg)
+				}
+			}
+
+			if p.requestedData&Timing == Timing {
+				for _, timingMsg := range timingOutgoing {
+					p.output.AddTiming(timingMsg)
+				}
+			}
 		}
 
 	case connection.ExtrapolatedClockFile:
@@ -355,11 +480,15 @@ func (p *Parser) handleMessage(name string, dat map[string]interface{}, timestam
 	}
 }
 
-func (p *Parser) decompressData(data []byte) (map[string]interface{}, error) {
-	b64 := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data))
+// decompressData gives the json for the base64 encoded deflate data used by the .z feeds
+func (p *Parser) decompressData(data []byte) ([]byte, error) {
+	compressed, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
 
-	stuff, err := io.ReadAll(b64)
-	stuff = append(decompressHelper[:10], stuff...) //buf.Bytes()[:10], stuff...)
+	// Copy the header so the shared helper isn't written to
+	stuff := append(decompressHelper[:10:10], compressed...)
 
 	zr, err := gzip.NewReader(bytes.NewBuffer(stuff))
 	if err != nil {
@@ -368,12 +497,10 @@ func (p *Parser) decompressData(data []byte) (map[string]interface{}, error) {
 	defer zr.Close()
 
 	uncompressed, err := io.ReadAll(zr)
+	// There is no gzip footer so running out of data is how it ends
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
 
-	return p.deserializeData(uncompressed)
-}
-
-func (p *Parser) deserializeData(data []byte) (map[string]interface{}, error) {
-	var dat map[string]interface{}
-	err := json.Unmarshal(data, &dat)
-	return dat, err
+	return uncompressed, nil
 }
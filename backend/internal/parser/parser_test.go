@@ -0,0 +1,709 @@
+package parser
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+	"github.com/f1gopher/f1gopherlib/f1log"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+)
+
+// recordingFlow keeps the messages sent by the parser in order
+type recordingFlow struct {
+	flowControl.Flow
+	sent []Messages.Payload
+}
+
+func (r *recordingFlow) add(msg Messages.Payload) { r.sent = append(r.sent, msg) }
+
+func (r *recordingFlow) AddWeather(msg Messages.Weather)                       { r.add(msg) }
+func (r *recordingFlow) AddRaceControlMessage(msg Messages.RaceControlMessage) { r.add(msg) }
+func (r *recordingFlow) AddTiming(msg Messages.Timing)                         { r.add(msg) }
+func (r *recordingFlow) AddEvent(msg Messages.Event)                           { r.add(msg) }
+func (r *recordingFlow) AddTelemetry(msg Messages.Telemetry)                   { r.add(msg) }
+func (r *recordingFlow) AddLocation(msg Messages.Location)                     { r.add(msg) }
+func (r *recordingFlow) AddRadio(msg Messages.Radio)                           { r.add(msg) }
+func (r *recordingFlow) AddDrivers(msg Messages.Drivers)                       { r.add(msg) }
+func (r *recordingFlow) AddCarState(msg Messages.CarState)                     { r.add(msg) }
+
+func (r *recordingFlow) lastTiming() Messages.Timing {
+	for x := len(r.sent) - 1; x >= 0; x-- {
+		if timing, ok := r.sent[x].(Messages.Timing); ok {
+			return timing
+		}
+	}
+	return Messages.Timing{}
+}
+
+type noAssets struct{}
+
+func (noAssets) TeamRadio(string) ([]byte, error) { return nil, nil }
+
+func createTestParser(output flowControl.Flow, session Messages.SessionType) *Parser {
+	const everything = EventTime | Event | RaceControl | Weather | Timing | Telemetry | Location | TeamRadio | Drivers | CarState
+
+	return Create(
+		context.Background(),
+		&sync.WaitGroup{},
+		everything,
+		nil,
+		output,
+		noAssets{},
+		session,
+		f1log.CreateLog(),
+		time.UTC)
+}
+
+func compress(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+func TestMalformedMessagesDontPanic(t *testing.T) {
+	files := []string{
+		connection.WeatherDataFile,
+		connection.SessionDataFile,
+		connection.TimingDataFile,
+		connection.TimingAppDataFile,
+		connection.HeartbeatFile,
+		connection.CarDataFile,
+		connection.PositionFile,
+		connection.SessionInfoFile,
+		connection.LapCountFile,
+		connection.RaceControlMessagesFile,
+		connection.SessionStatusFile,
+		connection.TeamRadioFile,
+		connection.DriverListFile,
+		connection.ExtrapolatedClockFile,
+	}
+	messages := []string{
+		``,
+		`null`,
+		`[]`,
+		`"text"`,
+		`{}`,
+		`{"Lines":[1,2],"Entries":{},"Position":"x","Messages":7,"Captures":true,"Series":"0"}`,
+		`{"Lines":{"1":{"Sectors":{"0":{"Segments":{"99":{"Status":2049}}}}}}}`,
+		`{"Lines":{"1":{"Position":1,"Sectors":"0","Stats":[{"TimeDiffToFastest":1}]}}}`,
+		`{"Entries":[{"Utc":1,"Cars":{"1":{"Channels":{"0":"fast"}}}}]}`,
+		`{"Position":[{"Timestamp":null,"Entries":{"1":{"X":"1"}}}]}`,
+		`{"Messages":[{"Utc":"2023-01-01T00:00:00","Flag":"RED","Scope":"Sector","Sector":0}]}`,
+		`{"Messages":{"0":{"Utc":"2023-01-01T00:00:00","Flag":"YELLOW","Scope":"Sector","Sector":100}}}`,
+		`{"Meeting":"name","Name":5}`,
+		`{"1":{"Line":"one","TeamColour":7},"_kf":true}`,
+		`{"Remaining":null,"Extrapolating":"yes"}`,
+	}
+
+	p := createTestParser(&recordingFlow{}, Messages.RaceSession)
+	p.driverTimes["1"] = Messages.Timing{Number: 1}
+	var log bytes.Buffer
+	p.log.SetLogOutput(&log)
+
+	for _, file := range files {
+		for _, msg := range messages {
+			p.handleMessage(file, []byte(msg), time.Time{})
+		}
+	}
+
+	// The recover is only a safety net, the parsing itself shouldn't panic
+	if strings.Contains(log.String(), "Parsing message failed") {
+		t.Errorf("expected malformed data to be reported without panicking:\n%s", log.String())
+	}
+}
+
+func TestTimingPatchesMerge(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.RaceSession)
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1,"Tla":"VER"},"_kf":true}`), time.Time{})
+	p.handleMessage(connection.TimingDataFile, []byte(`{"Lines":{"1":{
+		"Position":"1",
+		"NumberOfLaps":3,
+		"Sectors":[
+			{"Value":"30.000","Segments":[{"Status":2049},{"Status":2049}]},
+			{"Value":"","Segments":[{"Status":0},{"Status":0}]},
+			{"Value":"","Segments":[{"Status":0}]}],
+		"BestLapTime":{"Value":"1:30.000"}}}}`), time.Time{})
+
+	if p.eventState.Sector1Segments != 2 || p.eventState.Sector2Segments != 2 || p.eventState.Sector3Segments != 1 {
+		t.Fatalf("expected the segment counts from the full message, got %d %d %d",
+			p.eventState.Sector1Segments, p.eventState.Sector2Segments, p.eventState.Sector3Segments)
+	}
+
+	// A patch only has what changed and lists are objects keyed by index
+	p.handleMessage(connection.TimingDataFile, []byte(`{"Lines":{"1":{"Sectors":{"1":{"Value":"31.500","Segments":{"0":{"Status":2051}}}}},"_kf":true}}`), time.Time{})
+
+	driver := output.lastTiming()
+	if driver.Position != 1 || driver.Lap != 3 || driver.FastestLap != 90000 || driver.Sector1 != 30000 {
+		t.Errorf("expected the earlier values to be kept, got %+v", driver)
+	}
+	if driver.Sector2 != 31500 {
+		t.Errorf("expected sector 2 from the patch, got %d", driver.Sector2)
+	}
+	if driver.Segment[2] != Messages.PurpleSegment || driver.Segment[0] != Messages.GreenSegment {
+		t.Errorf("unexpected segments %v", driver.Segment[:5])
+	}
+}
+
+func TestDecompressData(t *testing.T) {
+	p := createTestParser(&recordingFlow{}, Messages.RaceSession)
+
+	data, err := p.decompressData(compress(t, `{"Entries":[]}`))
+	if err != nil || string(data) != `{"Entries":[]}` {
+		t.Errorf("expected the json back, got '%s' %v", data, err)
+	}
+
+	for _, bad := range []string{"not base64!", "bm90IGRlZmxhdGU="} {
+		if _, err := p.decompressData([]byte(bad)); err == nil {
+			t.Errorf("expected an error for '%s'", bad)
+		}
+	}
+}
+
+func TestSectorHistoryAndBests(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.RaceSession)
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+	timing := func(after time.Duration, msg string) {
+		p.handleMessage(connection.TimingDataFile, []byte(msg), start.Add(after))
+	}
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1,"Tla":"VER"},"44":{"Line":2,"Tla":"HAM"}}`), start)
+	timing(0, `{"Lines":{"1":{"NumberOfLaps":0,"Sectors":[
+		{"Value":"","Segments":[{"Status":0},{"Status":0}]},
+		{"Value":"","Segments":[{"Status":0},{"Status":0}]},
+		{"Value":"","Segments":[{"Status":0},{"Status":0}]}]}}}`)
+
+	// First lap, the first segment can't be timed as the lap before wasn't seen
+	timing(10*time.Second, `{"Lines":{"1":{"Sectors":{"0":{"Segments":{"0":{"Status":2049}}}}}}}`)
+	timing(20*time.Second, `{"Lines":{"1":{"Sectors":{"0":{"Value":"20.000","Segments":{"1":{"Status":2049}}}}}}}`)
+	timing(30*time.Second, `{"Lines":{"1":{"Sectors":{"1":{"Segments":{"0":{"Status":2051}}}}}}}`)
+	timing(45*time.Second, `{"Lines":{"1":{"Sectors":{"1":{"Value":"25.000","Segments":{"1":{"Status":2049}}}}}}}`)
+	timing(55*time.Second, `{"Lines":{"1":{"Sectors":{"2":{"Segments":{"0":{"Status":2049}}}}}}}`)
+	timing(60*time.Second, `{"Lines":{"1":{"NumberOfLaps":1,"Sectors":{"2":{"Value":"15.000","Segments":{"1":{"Status":2049}}}}}}}`)
+
+	// Second lap
+	timing(68*time.Second, `{"Lines":{"1":{"Sectors":{"0":{"Value":"18.000","Segments":{"0":{"Status":2051}}}}}}}`)
+
+	// The lap is only in the update that finished it
+	var completed []Messages.LapSectors
+	for _, msg := range output.sent {
+		if timing, ok := msg.(Messages.Timing); ok && timing.CompletedLap != nil {
+			completed = append(completed, *timing.CompletedLap)
+		}
+	}
+	if len(completed) != 1 {
+		t.Fatalf("expected one completed lap, got %d", len(completed))
+	}
+
+	driver := output.lastTiming()
+	lap := completed[0]
+	if lap.Lap != 1 || lap.Sector1 != 20000 || lap.Sector2 != 25000 || lap.Sector3 != 15000 {
+		t.Errorf("unexpected lap %+v", lap)
+	}
+	expectedTimes := []int64{0, 10000, 10000, 15000, 10000, 5000}
+	for x, expected := range expectedTimes {
+		if lap.MiniSectors[x].Time != expected {
+			t.Errorf("expected mini-sector %d to take %d, got %d", x, expected, lap.MiniSectors[x].Time)
+		}
+	}
+	if lap.MiniSectors[2].Status != Messages.PurpleSegment {
+		t.Errorf("expected mini-sector 2 to be purple, got %v", lap.MiniSectors[2].Status)
+	}
+
+	if driver.CurrentLapSectors.MiniSectors[0].Time != 8000 || driver.CurrentLapSectors.Sector1 != 18000 {
+		t.Errorf("unexpected lap in progress %+v", driver.CurrentLapSectors)
+	}
+	if driver.PersonalBest.MiniSectors[0] != 8000 || driver.PersonalBest.Sector1 != 18000 || driver.PersonalBest.IdealLap != 58000 {
+		t.Errorf("unexpected personal best %+v", driver.PersonalBest)
+	}
+
+	// A faster second sector by someone else improves the session's ideal lap but not the driver's
+	timing(70*time.Second, `{"Lines":{"44":{"Sectors":{"1":{"Value":"24.000"}}}}}`)
+
+	event, ok := output.sent[len(output.sent)-1].(Messages.Event)
+	if !ok {
+		t.Fatalf("expected an event when the session best changed, got %T", output.sent[len(output.sent)-1])
+	}
+	if event.SessionBest.IdealLap != 57000 || event.SessionBest.MiniSectors[0] != 8000 {
+		t.Errorf("unexpected session best %+v", event.SessionBest)
+	}
+	if p.driverTimes["1"].PersonalBest.IdealLap != 58000 {
+		t.Errorf("expected the driver's ideal lap to be unchanged, got %d", p.driverTimes["1"].PersonalBest.IdealLap)
+	}
+}
+
+func TestLappedCarGaps(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.RaceSession)
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1,"Tla":"VER"},"44":{"Line":2,"Tla":"HAM"},"63":{"Line":3,"Tla":"RUS"}}`), time.Time{})
+	p.handleMessage(connection.TimingDataFile, []byte(`{"Lines":{
+		"1":{"GapToLeader":"LAP 5","IntervalToPositionAhead":{"Value":"LAP 5"}},
+		"44":{"GapToLeader":"+1:02.003","IntervalToPositionAhead":{"Value":"+1:02.003"}},
+		"63":{"GapToLeader":"2L","IntervalToPositionAhead":{"Value":"1L"}}}}`), time.Time{})
+
+	leader := p.driverTimes["1"]
+	if leader.LeaderLap != 5 || leader.GapToLeader != 0 || leader.GapToLeaderText() != "" {
+		t.Errorf("expected the leader's lap to be kept, got %+v", leader)
+	}
+
+	second := p.driverTimes["44"]
+	if second.GapToLeader != 62003 || second.GapToLeaderLaps != 0 || second.GapToLeaderText() != "+1:02.003" {
+		t.Errorf("unexpected gap for a car on the lead lap %d %d '%s'", second.GapToLeader, second.GapToLeaderLaps, second.GapToLeaderText())
+	}
+
+	lapped := p.driverTimes["63"]
+	if lapped.GapToLeaderLaps != 2 || lapped.IntervalLaps != 1 || lapped.GapToLeaderText() != "+2 L" || lapped.IntervalText() != "+1 L" {
+		t.Errorf("unexpected gaps for a lapped car %d %d '%s' '%s'",
+			lapped.GapToLeaderLaps, lapped.IntervalLaps, lapped.GapToLeaderText(), lapped.IntervalText())
+	}
+
+	// Unlapping goes back to a time
+	p.handleMessage(connection.TimingDataFile, []byte(`{"Lines":{"63":{"GapToLeader":"+58.120","IntervalToPositionAhead":{"Value":"+3.400"}}}}`), time.Time{})
+	lapped = p.driverTimes["63"]
+	if lapped.GapToLeaderLaps != 0 || lapped.IntervalLaps != 0 || lapped.GapToLeaderText() != "+58.120" || lapped.IntervalText() != "+3.400" {
+		t.Errorf("expected the laps to be cleared, got '%s' '%s'", lapped.GapToLeaderText(), lapped.IntervalText())
+	}
+}
+
+func TestQualifyingKnockoutZone(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.QualifyingSession)
+
+	p.handleMessage(connection.SessionInfoFile, []byte(`{"Name":"Qualifying"}`), time.Time{})
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1},"11":{"Line":2},"16":{"Line":3},"44":{"Line":4}}`), time.Time{})
+	p.handleMessage(connection.TimingDataFile, []byte(`{"NoEntries":[4,2,1],"Lines":{
+		"1":{"BestLapTimes":[{"Value":"1:30.000"},{"Value":""}]},
+		"11":{"BestLapTimes":[{"Value":"1:31.500"},{"Value":""}]},
+		"16":{"BestLapTime":{"Value":"1:30.800"}}}}`), time.Time{})
+
+	event, ok := output.sent[len(output.sent)-1].(Messages.Event)
+	if !ok || event.ProgressingCars != 2 || event.KnockoutCutoff != 90800 {
+		t.Fatalf("expected an event with the cutoff from the second car, got %+v", output.sent[len(output.sent)-1])
+	}
+
+	for driver, expected := range map[string]struct {
+		inDropZone     bool
+		timeToProgress int64
+	}{
+		"1":  {false, 0},
+		"16": {false, 0},
+		"11": {true, 700},
+		// Without a time
+		"44": {true, 0},
+	} {
+		timing := p.driverTimes[driver]
+		if timing.InDropZone != expected.inDropZone || timing.TimeToProgress != expected.timeToProgress {
+			t.Errorf("driver %s expected %v %d, got %v %d",
+				driver, expected.inDropZone, expected.timeToProgress, timing.InDropZone, timing.TimeToProgress)
+		}
+	}
+
+	// Q2 starts without times and the knocked out cars aren't in the drop zone
+	p.handleMessage(connection.TimingDataFile, []byte(`{"Lines":{"11":{"KnockedOut":true},"44":{"KnockedOut":true}}}`), time.Time{})
+	p.handleMessage(connection.SessionDataFile, []byte(`{"Series":{"1":{"Utc":"2023-04-29T08:30:00Z","QualifyingPart":2}}}`), time.Time{})
+	p.handleMessage(connection.TimingDataFile, []byte(`{"Lines":{"16":{"BestLapTimes":{"1":{"Value":"1:29.900"}}}}}`), time.Time{})
+
+	if p.eventState.ProgressingCars != 1 || p.eventState.KnockoutCutoff != 89900 {
+		t.Errorf("unexpected Q2 cutoff %d %d", p.eventState.ProgressingCars, p.eventState.KnockoutCutoff)
+	}
+	if !p.driverTimes["1"].InDropZone || p.driverTimes["16"].InDropZone || p.driverTimes["11"].InDropZone {
+		t.Errorf("unexpected Q2 drop zone")
+	}
+	if p.driverTimes["16"].QualifyingBestLaps != [3]int64{90800, 89900, 0} {
+		t.Errorf("unexpected best laps %v", p.driverTimes["16"].QualifyingBestLaps)
+	}
+}
+
+func TestCarData(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.RaceSession)
+	p.SelectTelemetrySources([]int{1})
+	var log bytes.Buffer
+	p.log.SetLogOutput(&log)
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1,"Tla":"VER"}}`), time.Time{})
+
+	carData := func(rpm int, brake int, drs int) {
+		msg := fmt.Sprintf(`{"Entries":[{"Utc":"2023-04-29T13:30:25.123Z","Cars":{"1":{"Channels":{"0":%d,"2":280,"3":7,"4":100,"5":%d,"45":%d,"99":1}}}}]}`, rpm, brake, drs)
+		p.handleMessage(connection.CarDataFile, []byte(msg), time.Time{})
+	}
+	var telemetry []Messages.Telemetry
+	lastTelemetry := func() Messages.Telemetry {
+		telemetry = nil
+		for _, msg := range output.sent {
+			if sample, ok := msg.(Messages.Telemetry); ok {
+				telemetry = append(telemetry, sample)
+			}
+		}
+		return telemetry[len(telemetry)-1]
+	}
+
+	carData(11000, 100, 8)
+	if telemetry := lastTelemetry(); telemetry.Brake != 100 || !telemetry.BrakeOn || telemetry.DRS || telemetry.DRSState != Messages.CarDRSEligible || telemetry.DRSValue != 8 {
+		t.Errorf("unexpected telemetry %+v", telemetry)
+	}
+
+	carData(11000, 0, 12)
+	if p.driverTimes["1"].DRSOpen != true {
+		t.Errorf("expected the timing to have DRS open")
+	}
+	if telemetry := lastTelemetry(); telemetry.BrakeOn || !telemetry.DRS || telemetry.DRSState != Messages.CarDRSOpen {
+		t.Errorf("unexpected telemetry %+v", telemetry)
+	}
+
+	if strings.Count(log.String(), "Unhandled channel id '99'") != 1 {
+		t.Errorf("expected the unknown channel to be reported once:\n%s", log.String())
+	}
+
+	// The car is turned off
+	carData(0, 0, 0)
+	if lastTelemetry(); len(telemetry) != 2 {
+		t.Errorf("expected telemetry with no RPM to be skipped")
+	}
+
+	p.SendStoppedCarTelemetry(true)
+	carData(0, 0, 0)
+	if last := lastTelemetry(); len(telemetry) != 3 || last.RPM != 0 {
+		t.Errorf("expected telemetry with no RPM to be sent")
+	}
+}
+
+func TestTelemetryChannels(t *testing.T) {
+	sample := Messages.Telemetry{RPM: 11000, Speed: 280, Gear: 7, Throttle: 99, Brake: 100, BrakeOn: true, DRSState: Messages.CarDRSOpen}
+
+	var fields map[string]any
+	encoded, _ := json.Marshal(sample)
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	// Web clients read the descriptor as JSON and find the value by the field name
+	var channels []Messages.TelemetryChannelInfo
+	data, err := json.Marshal(Messages.TelemetryChannels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, &channels); err != nil {
+		t.Fatal(err)
+	}
+
+	for x, channel := range channels {
+		value, exists := fields[channel.Field]
+		if !exists {
+			t.Errorf("%s has no telemetry field '%s'", channel.Name, channel.Field)
+			continue
+		}
+		if value.(float64) != Messages.TelemetryChannels[x].Value(sample) {
+			t.Errorf("%s field is %v but the value is %v", channel.Name, value, Messages.TelemetryChannels[x].Value(sample))
+		}
+	}
+}
+
+func TestLapDistance(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.RaceSession)
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+
+	// A round track 500m across that takes 100 seconds, positions are in tenths of a metre
+	const radius = 5000.0
+	const lapTime = 100.0
+	position := func(driver int, seconds float64, offset float64) {
+		angle := 2 * math.Pi * (seconds/lapTime + offset)
+		msg := fmt.Sprintf(`{"Position":[{"Timestamp":"%s","Entries":{"%d":{"X":%f,"Y":%f,"Z":0}}}]}`,
+			start.Add(time.Duration(seconds*float64(time.Second))).Format("2006-01-02T15:04:05.999Z"),
+			driver, radius*math.Cos(angle), radius*math.Sin(angle))
+		p.handleMessage(connection.PositionFile, []byte(msg), start)
+	}
+	lapCompleted := func(seconds float64, lap int) {
+		p.handleMessage(connection.TimingDataFile, []byte(fmt.Sprintf(`{"Lines":{"1":{"NumberOfLaps":%d}}}`, lap)),
+			start.Add(time.Duration(seconds*float64(time.Second))))
+	}
+	lastLocation := func() Messages.Location {
+		for x := len(output.sent) - 1; x >= 0; x-- {
+			if location, ok := output.sent[x].(Messages.Location); ok {
+				return location
+			}
+		}
+		return Messages.Location{}
+	}
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1},"44":{"Line":2}}`), start)
+
+	// Record the reference lap
+	position(1, -0.5, 0)
+	lapCompleted(0, 1)
+	for seconds := 0.5; seconds < lapTime; seconds++ {
+		position(1, seconds, 0)
+		if lastLocation().LapDistanceKnown {
+			t.Fatalf("expected no distance before the reference lap is complete")
+		}
+	}
+	lapCompleted(lapTime, 2)
+
+	position(1, 125, 0)
+	location := lastLocation()
+	circumference := 2 * math.Pi * radius / 10
+	if !location.LapDistanceKnown ||
+		math.Abs(location.LapFraction-0.25) > 0.005 ||
+		math.Abs(location.LapDistance-circumference/4) > 5 {
+		t.Errorf("expected a quarter of the lap, got %f %fm", location.LapFraction, location.LapDistance)
+	}
+
+	// Someone else half a lap ahead
+	position(44, 125, 0.5)
+	if location = lastLocation(); math.Abs(location.LapFraction-0.75) > 0.005 {
+		t.Errorf("expected three quarters of the lap, got %f", location.LapFraction)
+	}
+
+	// Crossing the finish line
+	position(1, 199.5, 0)
+	if location = lastLocation(); !location.FinishLineCrossing.IsZero() {
+		t.Errorf("didn't expect a crossing before the line")
+	}
+	position(1, 200.5, 0)
+	location = lastLocation()
+	if crossing := location.FinishLineCrossing.Sub(start.Add(200 * time.Second)); crossing < -10*time.Millisecond || crossing > 10*time.Millisecond {
+		t.Errorf("expected to cross the line at 200 seconds, got %v", location.FinishLineCrossing.Sub(start))
+	}
+	if location.LapFraction > 0.01 {
+		t.Errorf("expected to be at the start of the lap, got %f", location.LapFraction)
+	}
+}
+
+func TestLapDistanceAfterCatchup(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.RaceSession)
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+
+	const radius = 5000.0
+	const lapTime = 100.0
+	position := func(seconds float64) {
+		angle := 2 * math.Pi * seconds / lapTime
+		msg := fmt.Sprintf(`{"Position":[{"Timestamp":"%s","Entries":{"1":{"X":%f,"Y":%f,"Z":0}}}]}`,
+			start.Add(time.Duration(seconds*float64(time.Second))).Format("2006-01-02T15:04:05.999Z"),
+			radius*math.Cos(angle), radius*math.Sin(angle))
+		p.handleMessage(connection.PositionFile, []byte(msg), start)
+	}
+	lapCompleted := func(timestamp time.Time, lap int) {
+		p.handleMessage(connection.TimingDataFile, []byte(fmt.Sprintf(`{"Lines":{"1":{"NumberOfLaps":%d}}}`, lap)), timestamp)
+	}
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1}}`), time.Time{})
+
+	// Joining live part way through lap 6, the catchup has the laps so far without a time
+	lapCompleted(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC), 5)
+	for seconds := 50.5; seconds < lapTime; seconds++ {
+		position(seconds)
+	}
+
+	// Half a lap isn't a reference lap so the whole of the next one is used
+	lapCompleted(start.Add(lapTime*time.Second), 6)
+	for seconds := lapTime + 0.5; seconds < 2*lapTime; seconds++ {
+		position(seconds)
+	}
+	lapCompleted(start.Add(2*lapTime*time.Second), 7)
+
+	position(225)
+	var location Messages.Location
+	for _, msg := range output.sent {
+		if sent, ok := msg.(Messages.Location); ok {
+			location = sent
+		}
+	}
+	circumference := 2 * math.Pi * radius / 10
+	if !location.LapDistanceKnown || math.Abs(location.LapDistance-circumference/4) > 5 {
+		t.Errorf("expected a quarter of a %fm lap, got %fm", circumference, location.LapDistance)
+	}
+}
+
+func TestLapDistanceRecordingStopsForUnfinishedLap(t *testing.T) {
+	p := createTestParser(&recordingFlow{}, Messages.RaceSession)
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+
+	position := func(driver int, seconds float64) {
+		msg := fmt.Sprintf(`{"Position":[{"Timestamp":"%s","Entries":{"%d":{"X":%f,"Y":0,"Z":0}}}]}`,
+			start.Add(time.Duration(seconds*float64(time.Second))).Format("2006-01-02T15:04:05.999Z"), driver, seconds)
+		p.handleMessage(connection.PositionFile, []byte(msg), start)
+	}
+	lapCompleted := func(driver int, seconds float64, lap int) {
+		p.handleMessage(connection.TimingDataFile, []byte(fmt.Sprintf(`{"Lines":{"%d":{"NumberOfLaps":%d}}}`, driver, lap)),
+			start.Add(time.Duration(seconds*float64(time.Second))))
+	}
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1},"44":{"Line":2}}`), start)
+
+	// Starts the reference lap and then stops on track without going into the pitlane
+	lapCompleted(1, 0, 1)
+	for seconds := 0.5; seconds < maxReferenceLapTime.Seconds()+60; seconds++ {
+		position(1, seconds)
+	}
+
+	if p.lapReference.recordingDriver != 0 || p.lapReference.recording != nil {
+		t.Fatalf("expected the unfinished lap to be dropped, still recording %d locations for %d",
+			len(p.lapReference.recording), p.lapReference.recordingDriver)
+	}
+
+	// The next car to cross the line is recorded instead
+	lapCompleted(44, maxReferenceLapTime.Seconds()+60, 1)
+	if p.lapReference.recordingDriver != 44 {
+		t.Errorf("expected to record driver 44, got %d", p.lapReference.recordingDriver)
+	}
+}
+
+func TestCarStateSynchronisation(t *testing.T) {
+	output := &recordingFlow{}
+	// Car states use the telemetry for every driver, not just the ones selected for the telemetry channel
+	p := createTestParser(output, Messages.RaceSession)
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+	at := func(milliseconds int) string {
+		return start.Add(time.Duration(milliseconds) * time.Millisecond).Format("2006-01-02T15:04:05.999Z")
+	}
+	carData := func(milliseconds int, speed int, gear int) {
+		p.handleMessage(connection.CarDataFile, []byte(fmt.Sprintf(
+			`{"Entries":[{"Utc":"%s","Cars":{"1":{"Channels":{"0":10000,"2":%d,"3":%d,"4":100,"5":0,"45":0}}}}]}`,
+			at(milliseconds), speed, gear)), start)
+	}
+	position := func(milliseconds int, x float64) {
+		p.handleMessage(connection.PositionFile, []byte(fmt.Sprintf(
+			`{"Position":[{"Timestamp":"%s","Entries":{"1":{"X":%f,"Y":100,"Z":0}}}]}`, at(milliseconds), x)), start)
+	}
+	carStates := func() []Messages.CarState {
+		var result []Messages.CarState
+		for _, msg := range output.sent {
+			if state, ok := msg.(Messages.CarState); ok {
+				result = append(result, state)
+			}
+		}
+		return result
+	}
+
+	p.handleMessage(connection.DriverListFile, []byte(`{"1":{"Line":1,"Tla":"VER"}}`), start)
+
+	position(0, 1000)
+	carData(200, 200, 5)
+	if len(carStates()) != 1 {
+		t.Fatalf("expected the first location to be sent once there is telemetry after it, got %d", len(carStates()))
+	}
+
+	// Telemetry waits for a location after it
+	carData(400, 300, 6)
+	if len(carStates()) != 1 {
+		t.Fatalf("expected telemetry to wait for the next location, got %d", len(carStates()))
+	}
+
+	position(500, 2000)
+	states := carStates()
+	if len(states) != 3 {
+		t.Fatalf("expected both telemetry samples to be sent, got %d", len(states))
+	}
+	if states[0].Source != Messages.FromLocation || states[0].Speed != 200 || states[0].Gear != 5 {
+		t.Errorf("expected the nearest telemetry for the first location, got %+v", states[0])
+	}
+	if states[1].Source != Messages.FromTelemetry || math.Abs(states[1].X-1400) > 0.001 {
+		t.Errorf("expected the location to be interpolated for the telemetry, got %+v", states[1])
+	}
+	if states[2].Speed != 300 || math.Abs(states[2].X-1800) > 0.001 || !states[2].LocationKnown || !states[2].TelemetryKnown {
+		t.Errorf("unexpected car state %+v", states[2])
+	}
+
+	carData(600, 400, 7)
+	states = carStates()
+	if len(states) != 4 || states[3].Source != Messages.FromLocation || math.Abs(float64(states[3].Speed)-350) > 0.001 ||
+		states[3].Gear != 6 {
+		t.Errorf("expected the speed to be interpolated and the gear from before for the location, got %+v", states[3])
+	}
+
+	for x := 1; x < len(states); x++ {
+		if states[x].Timestamp.Before(states[x-1].Timestamp) {
+			t.Errorf("expected the car states in time order")
+		}
+	}
+
+	// No locations for a while so the telemetry is sent without one
+	carData(4000, 100, 3)
+	carData(8000, 100, 3)
+	states = carStates()
+	if len(states) != 6 || states[5].LocationKnown || !states[5].Timestamp.Equal(start.Add(4*time.Second)) {
+		t.Errorf("expected the telemetry to be sent without a location, got %+v", states[len(states)-1])
+	}
+
+	for _, msg := range output.sent {
+		if _, ok := msg.(Messages.Telemetry); ok {
+			t.Fatal("expected no telemetry for a driver that wasn't selected")
+		}
+	}
+}
+
+func TestDriverListUpdates(t *testing.T) {
+	output := &recordingFlow{}
+	p := createTestParser(output, Messages.Practice1Session)
+	driverList := func(msg string) []Messages.Payload {
+		sent := len(output.sent)
+		p.handleMessage(connection.DriverListFile, []byte(msg), time.Time{})
+		return output.sent[sent:]
+	}
+
+	sent := driverList(`{"1":{"Line":1,"FullName":"Max VERSTAPPEN","Tla":"VER","TeamName":"Red Bull Racing","TeamColour":"3671C6"},
+		"44":{"Line":2,"FullName":"Lewis HAMILTON","Tla":"HAM","TeamName":"Mercedes","TeamColour":"6CD3BF"}}`)
+	if len(sent) != 1 {
+		t.Fatalf("expected only the drivers, got %d messages", len(sent))
+	}
+	drivers := sent[0].(Messages.Drivers)
+	if len(drivers.Drivers) != 2 || !slices.Equal(drivers.Added, []int{1, 44}) || len(drivers.Changed) != 0 {
+		t.Errorf("expected both drivers added, got %+v", drivers)
+	}
+
+	// Only the line changes, which happens on every overtake
+	if sent = driverList(`{"44":{"Line":1},"1":{"Line":2},"_kf":true}`); len(sent) != 0 {
+		t.Errorf("didn't expect anything to be sent for a new line, got %+v", sent)
+	}
+	if hamilton := p.driverInfo["44"]; hamilton.Line != 1 || hamilton.StartPosition != 2 || hamilton.ShortName != "HAM" {
+		t.Errorf("expected the line to change and everything else to be kept, got %+v", hamilton)
+	}
+
+	// Nothing changed
+	if sent = driverList(`{"1":{"Line":2}}`); len(sent) != 0 {
+		t.Errorf("didn't expect anything to be sent, got %+v", sent)
+	}
+
+	// New team colour
+	sent = driverList(`{"44":{"TeamColour":"00A19C"}}`)
+	if len(sent) != 2 {
+		t.Fatalf("expected the drivers and the timing, got %d messages", len(sent))
+	}
+	if drivers = sent[0].(Messages.Drivers); !slices.Equal(drivers.Changed, []int{44}) || len(drivers.Added) != 0 || drivers.Drivers[1].Line != 1 {
+		t.Errorf("expected the driver to be changed with the line kept, got %+v", drivers)
+	}
+	if timing := sent[1].(Messages.Timing); timing.Number != 44 || timing.HexColor != "#00A19C" || timing.Color.G != 0xA1 || timing.Name != "Lewis HAMILTON" {
+		t.Errorf("expected the timing with the new colour, got %+v", timing)
+	}
+
+	// A reserve driver
+	sent = driverList(`{"40":{"Line":3,"FullName":"Liam LAWSON","Tla":"LAW","TeamName":"AlphaTauri","TeamColour":"5E8FAA"}}`)
+	drivers = sent[0].(Messages.Drivers)
+	if len(sent) != 1 || !slices.Equal(drivers.Added, []int{40}) || len(drivers.Drivers) != 3 || drivers.Drivers[1].Number != 40 {
+		t.Errorf("expected the reserve driver to be added to everyone, got %+v", drivers)
+	}
+	if p.driverTimes["40"].ShortName != "LAW" || p.driverTimes["40"].Position != 3 {
+		t.Errorf("expected timing for the reserve driver, got %+v", p.driverTimes["40"])
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// The number of cars that progress from each part of qualifying when the feed doesn't say
+var defaultProgressingCars = [...]int{15, 10, 0}
+
+// qualifyingPart is the index of the part of qualifying running or -1 when not in qualifying
+func (p *Parser) qualifyingPart() int {
+	switch p.eventState.Type {
+	case Messages.Qualifying1:
+		return 0
+	case Messages.Qualifying2:
+		return 1
+	case Messages.Qualifying3:
+		return 2
+	default:
+		return -1
+	}
+}
+
+func (p *Parser) progressingCars(part int) int {
+	// The cars that progress are the ones in the next part
+	if entries, exists := p.qualifyingEntries[part+1]; exists {
+		return entries
+	}
+
+	return defaultProgressingCars[part]
+}
+
+// updateKnockoutZone works out the cutoff for the part of qualifying and who is in the drop zone. Returns the
+// timing with the latest values for the drivers already in it and any other drivers that changed.
+func (p *Parser) updateKnockoutZone(timing []Messages.Timing) []Messages.Timing {
+	part := p.qualifyingPart()
+	progressing := 0
+	if part >= 0 {
+		progressing = p.progressingCars(part)
+	}
+
+	// Rank the cars still in qualifying by their best lap in this part with the cars without a time last
+	var running []string
+	for _, driverNumber := range p.driverNumbers() {
+		if !p.driverTimes[driverNumber].KnockedOutOfQualifying {
+			running = append(running, driverNumber)
+		}
+	}
+
+	bestLap := func(driverNumber string) int64 {
+		if part < 0 {
+			return 0
+		}
+		return p.driverTimes[driverNumber].QualifyingBestLaps[part]
+	}
+
+	sort.SliceStable(running, func(i, j int) bool {
+		a, b := bestLap(running[i]), bestLap(running[j])
+		if a == 0 || b == 0 {
+			return a != 0
+		}
+		return a < b
+	})
+
+	var cutoff int64
+	if progressing > 0 && progressing <= len(running) {
+		cutoff = bestLap(running[progressing-1])
+	}
+	p.eventState.ProgressingCars = progressing
+	p.eventState.KnockoutCutoff = cutoff
+
+	rank := make(map[string]int, len(running))
+	for x, driverNumber := range running {
+		rank[driverNumber] = x
+	}
+
+	changed := make(map[string]bool)
+	for _, driverNumber := range p.driverNumbers() {
+		driver := p.driverTimes[driverNumber]
+
+		position, isRunning := rank[driverNumber]
+		inDropZone := isRunning && progressing > 0 && position >= progressing
+
+		var timeToProgress int64
+		if inDropZone && cutoff > 0 && bestLap(driverNumber) > 0 {
+			timeToProgress = bestLap(driverNumber) - cutoff
+		}
+
+		if driver.InDropZone != inDropZone || driver.TimeToProgress != timeToProgress {
+			driver.InDropZone = inDropZone
+			driver.TimeToProgress = timeToProgress
+			p.driverTimes[driverNumber] = driver
+			changed[driverNumber] = true
+		}
+	}
+
+	for x := range timing {
+		driverNumber := strconv.Itoa(timing[x].Number)
+		if changed[driverNumber] {
+			timing[x] = p.driverTimes[driverNumber]
+			delete(changed, driverNumber)
+		}
+	}
+
+	for _, driverNumber := range p.driverNumbers() {
+		if changed[driverNumber] {
+			timing = append(timing, p.driverTimes[driverNumber])
+		}
+	}
+
+	return timing
+}
@@ -1,42 +1,38 @@
 package parser
 
 import (
-	"reflect"
 	"time"
 
 	"github.com/f1gopher/f1gopherlib/Messages"
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseRaceControlMessagesData(dat map[string]interface{}, timestamp time.Time) ([]Messages.RaceControlMessage, []Messages.Event, []Messages.Timing, error) {
+func (p *Parser) parseRaceControlMessagesData(dat []byte, timestamp time.Time) ([]Messages.RaceControlMessage, []Messages.Event, []Messages.Timing, error) {
 
 	result := make([]Messages.RaceControlMessage, 0)
 	eventResult := make([]Messages.Event, 0)
 	timingResult := make([]Messages.Timing, 0)
 
-	if reflect.TypeOf(dat["Messages"]).Kind() == reflect.Slice {
-		for _, msg := range dat["Messages"].([]interface{}) {
-			p.readRaceControlMessage(msg, timestamp, &result, &eventResult, &timingResult)
-		}
-	} else if reflect.TypeOf(dat["Messages"]).Kind() == reflect.Map {
-		for _, msg := range dat["Messages"].(map[string]interface{}) {
-			p.readRaceControlMessage(msg, timestamp, &result, &eventResult, &timingResult)
-		}
-	} else {
-		p.ParseErrorf(connection.RaceControlMessagesFile, timestamp, "Unhandled data format: %v", dat)
+	var data raceControlData
+	if err := p.decode(connection.RaceControlMessagesFile, timestamp, dat, &data); err != nil {
+		return nil, nil, nil, err
 	}
 
+	data.Messages.each(func(_ int, msg raceControlMessage) {
+		p.readRaceControlMessage(msg, timestamp, &result, &eventResult, &timingResult)
+	})
+
 	return result, eventResult, timingResult, nil
 }
 
 func (p *Parser) readRaceControlMessage(
-	msg interface{},
+	msg raceControlMessage,
 	timestamp time.Time,
 	result *[]Messages.RaceControlMessage,
 	eventResult *[]Messages.Event,
 	timingResult *[]Messages.Timing) {
 
-	time, err := parseTime(msg.(map[string]interface{})["Utc"].(string))
+	time, err := parseTime(msg.Utc)
 	if err != nil {
 		p.ParseTimeError(connection.RaceControlMessagesFile, timestamp, "Utc", err)
 		return
@@ -44,13 +40,20 @@ func (p *Parser) readRaceControlMessage(
 
 	// Lap
 	// Category
-	status := msg.(map[string]interface{})["Message"].(string)
+	status := msg.Message
 
-	//status := msg.(map[string]interface{})["Message"].(string)
+	key := msg.Utc + "|" + status
+	if p.seenRaceControl[key] {
+		return
+	}
+	p.seenRaceControl[key] = true
 
-	flagTxt, exists := msg.(map[string]interface{})["Flag"].(string)
+	exists := msg.Flag != nil
+	flagTxt := ""
 	flag := Messages.NoFlag
 	if exists {
+		flagTxt = *msg.Flag
+
 		switch flagTxt {
 		case "BLUE":
 			flag = Messages.BlueFlag
@@ -123,12 +126,17 @@ func (p *Parser) readRaceControlMessage(
 	}
 
 	if exists {
-		scope, _ := msg.(map[string]interface{})["Scope"].(string)
+		scope := msg.Scope
 		sectorNum := 0
 		if scope == "Sector" {
-			sectorNum = int(msg.(map[string]interface{})["Sector"].(float64))
+			sectorNum = msg.Sector
 			sectorNum -= 1 // 0 indexing
 			// TODO - 2021 - Saudi Arabia Qualifying uses sector 0 so 1 isn't the first sector?
+
+			if sectorNum >= len(p.eventState.SegmentFlags) {
+				p.ParseErrorf(connection.RaceControlMessagesFile, timestamp, "Flag for unknown sector %d", msg.Sector)
+				sectorNum = -1
+			}
 		}
 
 		switch flagTxt {
@@ -136,7 +144,7 @@ func (p *Parser) readRaceControlMessage(
 			if scope == "Track" {
 				p.eventState.TrackStatus = Messages.RedFlag
 			}
-			if scope == "Sector" {
+			if scope == "Sector" && sectorNum >= 0 {
 				p.eventState.SegmentFlags[sectorNum] = Messages.RedFlag
 			}
 			p.eventState.Timestamp = time
@@ -189,7 +197,8 @@ func (p *Parser) readRaceControlMessage(
 				p.eventState.Type == Messages.Qualifying2 ||
 				p.eventState.Type == Messages.Qualifying3 {
 
-				for x, driver := range p.driverTimes {
+				for _, x := range p.driverNumbers() {
+					driver := p.driverTimes[x]
 					if driver.Location == Messages.Pitlane || driver.Location == Messages.PitOut {
 						driver.ChequeredFlag = true
 						p.driverTimes[x] = driver
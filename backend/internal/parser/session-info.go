@@ -7,13 +7,19 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseSessionInfoData(dat map[string]interface{}, timestamp time.Time) (Messages.Event, []Messages.Timing, error) {
+func (p *Parser) parseSessionInfoData(dat []byte, timestamp time.Time) (Messages.Event, []Messages.Timing, error) {
 
-	info, _ := dat["Meeting"].(map[string]interface{})
 	timingResult := make([]Messages.Timing, 0)
 
-	p.eventState.Meeting = info
-	p.eventState.Name = info["Name"].(string)
+	var data sessionInfo
+	if err := p.decode(connection.SessionInfoFile, timestamp, dat, &data); err != nil {
+		return Messages.Event{}, nil, err
+	}
+
+	if data.Meeting != nil {
+		p.eventState.Meeting = data.Meeting
+		p.eventState.Name, _ = data.Meeting["Name"].(string)
+	}
 	// Key
 	// OfficialName
 	// Location
@@ -24,7 +30,14 @@ func (p *Parser) parseSessionInfoData(dat map[string]interface{}, timestamp time
 	p.eventState.Heartbeat = true
 	previousType := p.eventState.Type
 
-	switch dat["Name"].(string) {
+	name := ""
+	if data.Name != nil {
+		name = *data.Name
+	}
+
+	switch name {
+	case "":
+		// Not in this update
 	case "Race":
 		p.eventState.Type = Messages.Race
 	case "Qualifying", "Sprint Qualifying", "Sprint Shootout":
@@ -38,12 +51,18 @@ func (p *Parser) parseSessionInfoData(dat map[string]interface{}, timestamp time
 	case "Practice 3":
 		p.eventState.Type = Messages.Practice3
 	default:
-		p.ParseErrorf(connection.SessionInfoFile, timestamp, "Unknown type: %s", dat["Name"].(string))
+		p.ParseErrorf(connection.SessionInfoFile, timestamp, "Unknown type: %s", name)
 	}
 
 	if previousType != p.eventState.Type {
+		p.eventState.SessionBest = Messages.BestSectors{}
+		p.eventState.ProgressingCars = 0
+		p.eventState.KnockoutCutoff = 0
+		p.qualifyingEntries = make(map[int]int)
+
 		// Clear the chequered flag state for all cars
-		for driverNum, driverInfo := range p.driverTimes {
+		for _, driverNum := range p.driverNumbers() {
+			driverInfo := p.driverTimes[driverNum]
 			driverInfo.ChequeredFlag = false
 			driverInfo.Sector1 = 0
 			driverInfo.Sector2 = 0
@@ -67,6 +86,13 @@ func (p *Parser) parseSessionInfoData(dat map[string]interface{}, timestamp time
 				driverInfo.Segment[x] = Messages.None
 			}
 			driverInfo.Location = Messages.NoLocation
+			driverInfo.PreviousSegmentTime = time.Time{}
+			driverInfo.CurrentLapSectors = Messages.LapSectors{}
+			driverInfo.CompletedLap = nil
+			driverInfo.PersonalBest = Messages.BestSectors{}
+			driverInfo.QualifyingBestLaps = [3]int64{}
+			driverInfo.InDropZone = false
+			driverInfo.TimeToProgress = 0
 
 			p.driverTimes[driverNum] = driverInfo
 
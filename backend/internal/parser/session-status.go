@@ -7,11 +7,21 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseSessionStatusData(dat map[string]interface{}, timestamp time.Time) (Messages.Event, error) {
+func (p *Parser) parseSessionStatusData(dat []byte, timestamp time.Time) (Messages.Event, error) {
 
-	status := dat["Status"].(string)
+	var data sessionStatus
+	if err := p.decode(connection.SessionStatusFile, timestamp, dat, &data); err != nil {
+		return Messages.Event{}, err
+	}
+
+	status := ""
+	if data.Status != nil {
+		status = *data.Status
+	}
 
 	switch status {
+	case "":
+		// Not in this update
 	case "Inactive":
 		p.eventState.Status = Messages.Inactive
 	case "Started":
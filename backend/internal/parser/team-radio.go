@@ -1,45 +1,56 @@
 package parser
 
 import (
-	"reflect"
 	"time"
 
 	"github.com/f1gopher/f1gopherlib/Messages"
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseTeamRadioData(dat map[string]interface{}, timestamp time.Time) ([]Messages.Radio, error) {
+func (p *Parser) parseTeamRadioData(dat []byte, timestamp time.Time) ([]Messages.Radio, error) {
 
 	result := make([]Messages.Radio, 0)
 
-	if reflect.TypeOf(dat["Captures"]).Kind() == reflect.Map {
-		for _, data := range dat["Captures"].(map[string]interface{}) {
-			p.readTeamRadio(data, timestamp, &result)
-		}
-
-	} else if reflect.TypeOf(dat["Captures"]).Kind() == reflect.Slice {
-		for _, data := range dat["Captures"].([]interface{}) {
-			p.readTeamRadio(data, timestamp, &result)
-		}
-	} else {
-		p.ParseErrorf(connection.TeamRadioFile, timestamp, "Unhandled data format: %v", dat)
+	var data teamRadioData
+	if err := p.decode(connection.TeamRadioFile, timestamp, dat, &data); err != nil {
+		return nil, err
 	}
 
+	data.Captures.each(func(_ int, capture teamRadioCapture) {
+		p.readTeamRadio(capture, timestamp, &result)
+	})
+
 	return result, nil
 }
 
-func (p *Parser) readTeamRadio(data interface{}, timestamp time.Time, result *[]Messages.Radio) {
-	record := data.(map[string]interface{})
+// skipTeamRadio remembers the radio messages without sending them
+func (p *Parser) skipTeamRadio(dat []byte, timestamp time.Time) {
+	var data teamRadioData
+	if err := p.decode(connection.TeamRadioFile, timestamp, dat, &data); err != nil {
+		return
+	}
+
+	data.Captures.each(func(_ int, capture teamRadioCapture) {
+		p.seenRadio[capture.Path] = true
+	})
+}
+
+func (p *Parser) readTeamRadio(record teamRadioCapture, timestamp time.Time, result *[]Messages.Radio) {
+	if len(record.Path) == 0 {
+		p.ParseErrorf(connection.TeamRadioFile, timestamp, "Team radio without a path")
+		return
+	}
 
-	time := record["Utc"].(string)
-	driverNumber := record["RacingNumber"].(string)
-	path := record["Path"].(string)
+	if p.seenRadio[record.Path] {
+		return
+	}
+	p.seenRadio[record.Path] = true
 
-	radio, err := p.assets.TeamRadio(path)
+	radio, err := p.assets.TeamRadio(record.Path)
 
 	if err == nil {
 
-		msgTime, err := parseTime(time)
+		msgTime, err := parseTime(record.Utc)
 		if err != nil {
 			p.ParseTimeError(connection.TeamRadioFile, timestamp, "Utc", err)
 			return
@@ -47,7 +58,7 @@ func (p *Parser) readTeamRadio(data interface{}, timestamp time.Time, result *[]
 
 		msg := Messages.Radio{
 			Timestamp: msgTime,
-			Driver:    p.driverTimes[driverNumber].Name,
+			Driver:    p.driverTimes[record.RacingNumber].Name,
 			Msg:       radio,
 		}
 
@@ -7,15 +7,20 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseTimingAppData(dat map[string]interface{}, timestamp time.Time) ([]Messages.Timing, error) {
+func (p *Parser) parseTimingAppData(dat []byte, timestamp time.Time) ([]Messages.Timing, error) {
 
 	result := make([]Messages.Timing, 0)
 
-	for driverStr, line := range dat["Lines"].(map[string]interface{}) {
+	var data timingAppData
+	if err := p.decode(connection.TimingAppDataFile, timestamp, dat, &data); err != nil {
+		return nil, err
+	}
+
+	data.Lines.each(func(driverStr string, line timingAppLine) {
 
 		currentDriver, exists := p.driverTimes[driverStr]
 		if !exists {
-			continue
+			return
 		}
 		currentDriver.Timestamp = timestamp
 
@@ -23,7 +28,7 @@ func (p *Parser) parseTimingAppData(dat map[string]interface{}, timestamp time.T
 		// the current positions with the start positions and things don't
 		// correct until there is a pit stop.
 		//
-		//value, exists := line.(map[string]interface{})["GridPos"]
+		//value, exists := line["GridPos"]
 		//if exists {
 		//	value, _ := strconv.ParseInt(value.(string), 10, 8)
 		//	currentDriver.Position = int(value)
@@ -32,45 +37,29 @@ func (p *Parser) parseTimingAppData(dat map[string]interface{}, timestamp time.T
 		// Don't use this to update the driver position because it results in multiple drivers
 		// with the same position.
 		//
-		//value, exists = line.(map[string]interface{})["Line"]
+		//value, exists = line["Line"]
 		//if exists {
 		//	currentDriver.Position = int(value.(float64))
 		//}
 
-		value, exists := line.(map[string]interface{})["Stints"]
-		if exists {
-
-			switch value.(type) {
-			case map[string]interface{}:
-				for _, stintData := range value.(map[string]interface{}) {
-					p.readTimingAppData(stintData, &currentDriver, timestamp)
-				}
-
-			case []interface{}:
-				for _, stintData := range value.([]interface{}) {
-					p.readTimingAppData(stintData, &currentDriver, timestamp)
-				}
-
-			default:
-				p.ParseErrorf(connection.TimingAppDataFile, timestamp, "Unhandled data format: %v", dat)
-			}
-		}
+		line.Stints.each(func(_ int, stintData timingAppStint) {
+			p.readTimingAppData(stintData, &currentDriver, timestamp)
+		})
 
 		p.driverTimes[driverStr] = currentDriver
 
 		result = append(result, currentDriver)
-	}
+	})
 
 	return result, nil
 }
 
-func (p *Parser) readTimingAppData(stintData interface{}, currentDriver *Messages.Timing, timestamp time.Time) {
-	tyre, hasTyre := stintData.(map[string]interface{})["Compound"]
-	if !hasTyre {
+func (p *Parser) readTimingAppData(stintData timingAppStint, currentDriver *Messages.Timing, timestamp time.Time) {
+	if stintData.Compound == nil {
 		return
 	}
 
-	switch tyre.(string) {
+	switch *stintData.Compound {
 	case "SOFT":
 		currentDriver.Tire = Messages.Soft
 	case "MEDIUM":
@@ -92,7 +81,7 @@ func (p *Parser) readTimingAppData(stintData interface{}, currentDriver *Message
 	case "ULTRASOFT":
 		currentDriver.Tire = Messages.ULTRASOFT
 	default:
-		p.ParseErrorf(connection.TimingAppDataFile, timestamp, "Unhandled Compound '%s'", tyre.(string))
+		p.ParseErrorf(connection.TimingAppDataFile, timestamp, "Unhandled Compound '%s'", *stintData.Compound)
 	}
 
 	//drivers[driverNumber].PitStops = append(drivers[driverNumber].PitStops, driver.PitStop{
@@ -101,8 +90,7 @@ func (p *Parser) readTimingAppData(stintData interface{}, currentDriver *Message
 
 	// TODO - Handle: LapFlags, New, TyresNotChanged, TotalLaps, StartLaps
 
-	totalLaps, exists := stintData.(map[string]interface{})["TotalLaps"]
-	if exists {
-		currentDriver.LapsOnTire = int(totalLaps.(float64))
+	if stintData.TotalLaps != nil {
+		currentDriver.LapsOnTire = *stintData.TotalLaps
 	}
 }
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,112 +10,80 @@ import (
 	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time) ([]Messages.Timing, error) {
+func (p *Parser) parseTimingData(dat []byte, timestamp time.Time) ([]Messages.Timing, []Messages.Event, error) {
 
 	result := make([]Messages.Timing, 0)
 
-	lines, exists := dat["Lines"]
-	if !exists {
-		return result, nil
+	var data timingData
+	if err := p.decode(connection.TimingDataFile, timestamp, dat, &data); err != nil {
+		return nil, nil, err
 	}
 
+	sessionBest := p.eventState.SessionBest
+	cutoff := p.eventState.KnockoutCutoff
+	progressing := p.eventState.ProgressingCars
+
+	data.NoEntries.each(func(part int, entries int) {
+		p.qualifyingEntries[part] = entries
+	})
+
 	fastestLapChanged := false
 	var currentFastestLap int64
-	var err error
-
-	for driverNumber, data := range lines.(map[string]interface{}) {
-		record := data.(map[string]interface{})
 
+	data.Lines.each(func(driverNumber string, record timingLine) {
 		currentDriver, exists := p.driverTimes[driverNumber]
 		if !exists {
-			continue
+			return
 		}
+		previousLap := currentDriver.Lap
 
 		currentDriver.Timestamp = timestamp
 
-		value, exists := record["Stopped"]
-
-		intValue, exists := record["NumberOfPitStops"]
-		if exists {
-			currentDriver.Pitstops = int(intValue.(float64))
+		if record.NumberOfPitStops != nil {
+			currentDriver.Pitstops = *record.NumberOfPitStops
 		}
 
-		value, exists = record["Position"].(string)
-		if exists {
-			pos, _ := strconv.Atoi(value.(string))
+		if record.Position != nil {
+			pos, _ := strconv.Atoi(*record.Position)
 			currentDriver.Position = pos
 		}
 
 		// TODO - do we ever get both values at the same time? Should we just use the value we get as the gap?
-		value, exists = record["TimeDiffToFastest"].(string)
-		if exists {
-			var t time.Duration
-
-			if len(value.(string)) > 0 {
-				t, err = parseDuration(value.(string))
-				if err != nil {
-					p.ParseTimeError(connection.TimingDataFile, timestamp, "TimeDiffToFastest", err)
-				}
-			}
-
-			currentDriver.TimeDiffToFastest = t.Milliseconds()
+		if record.TimeDiffToFastest != nil {
+			currentDriver.TimeDiffToFastest = p.timingDuration(timestamp, "TimeDiffToFastest", *record.TimeDiffToFastest)
 		}
 
-		value, exists = record["TimeDiffToPositionAhead"].(string)
-		if exists {
-			var t time.Duration
-
-			if len(value.(string)) > 0 {
-				t, err = parseDuration(value.(string))
-				if err != nil {
-					p.ParseTimeError(connection.TimingDataFile, timestamp, "TimeDiffToPositionAhead", err)
-				}
-			}
-
-			currentDriver.TimeDiffToPositionAhead = t.Milliseconds()
+		if record.TimeDiffToPositionAhead != nil {
+			currentDriver.TimeDiffToPositionAhead = p.timingDuration(timestamp, "TimeDiffToPositionAhead", *record.TimeDiffToPositionAhead)
 		}
 
-		value, exists = record["GapToLeader"].(string)
-		if exists {
-			var t time.Duration
-
-			if len(value.(string)) > 0 &&
-				!strings.HasPrefix(value.(string), "LAP") &&
-				!strings.HasSuffix(value.(string), "L") {
+		if record.GapToLeader != nil {
+			currentDriver.GapToLeader = 0
+			currentDriver.GapToLeaderLaps = 0
+			currentDriver.LeaderLap = 0
 
-				t, err = parseDuration(value.(string))
-				if err != nil {
-					p.ParseTimeError(connection.TimingDataFile, timestamp, "GapToLeader", err)
-				}
+			// The leader's gap is the lap they are on
+			if lap, isLap := leaderLap(*record.GapToLeader); isLap {
+				currentDriver.LeaderLap = lap
+			} else if laps, isLaps := lapsBehind(*record.GapToLeader); isLaps {
+				currentDriver.GapToLeaderLaps = laps
+			} else {
+				currentDriver.GapToLeader = p.timingDuration(timestamp, "GapToLeader", *record.GapToLeader)
 			}
-
-			// TODO - handle gap being '1 L'
-
-			// TODO -  Leaders lap number - use it somewhere else?
-
-			currentDriver.GapToLeader = t.Milliseconds()
 		}
 
-		value, exists = record["IntervalToPositionAhead"].(map[string]interface{})
-		if exists {
-			interval, exists := value.(map[string]interface{})["Value"]
-
-			if exists {
-
-				strInterval := interval.(string)
-
-				if len(strInterval) > 0 &&
-					!strings.HasPrefix(strInterval, "LAP") &&
-					!strings.HasSuffix(strInterval, "L") {
-
-					t, err := parseDuration(strInterval)
-					if err != nil {
-						p.ParseTimeError(connection.TimingDataFile, timestamp, "IntervalToPositionAhead Value", err)
-					}
-
-					currentDriver.TimeDiffToPositionAhead = t.Milliseconds()
+		if record.IntervalToPositionAhead != nil {
+			if interval := record.IntervalToPositionAhead.Value; interval != nil && len(*interval) > 0 {
+				currentDriver.TimeDiffToPositionAhead = 0
+				currentDriver.IntervalLaps = 0
+
+				if _, isLap := leaderLap(*interval); isLap {
+					// Leading so nobody ahead
+				} else if laps, isLaps := lapsBehind(*interval); isLaps {
+					currentDriver.IntervalLaps = laps
+				} else {
+					currentDriver.TimeDiffToPositionAhead = p.timingDuration(timestamp, "IntervalToPositionAhead Value", *interval)
 				}
-
 			}
 		} else {
 			// For races the leader doesn't have there time ahead cleared
@@ -125,119 +92,51 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 			}
 		}
 
-		stats, exists := record["Stats"].(map[string]interface{})
-		if exists {
-			// TODO - has per sector (0, 1, 2) data but do we care?
-			for _, diff := range stats {
-				value, exists = diff.(map[string]interface{})["TimeDiffToPositionAhead:"].(string)
-				if exists {
-					var t time.Duration
-
-					if len(value.(string)) > 0 {
-						t, err = parseDuration(value.(string))
-						if err != nil {
-							p.ParseTimeError(connection.TimingDataFile, timestamp, "TimeDiffToPositionAhead", err)
-						}
-					}
-
-					currentDriver.TimeDiffToPositionAhead = t.Milliseconds()
-				}
-
-				value, exists = diff.(map[string]interface{})["TimeDiffToFastest"].(string)
-				if exists {
-					var t time.Duration
-
-					if len(value.(string)) > 0 {
-						t, err = parseDuration(value.(string))
-						if err != nil {
-							p.ParseTimeError(connection.TimingDataFile, timestamp, "TimeDiffToFastest", err)
-						}
-					}
-
-					currentDriver.TimeDiffToFastest = t.Milliseconds()
-				}
+		// TODO - has per sector (0, 1, 2) data but do we care?
+		record.Stats.each(func(_ int, diff timingStats) {
+			if diff.TimeDiffToFastest != nil {
+				currentDriver.TimeDiffToFastest = p.timingDuration(timestamp, "TimeDiffToFastest", *diff.TimeDiffToFastest)
 			}
-		}
+		})
 
 		// TODO - handle Status = 96, 608, 288, 800, 768, 576
 
-		// Handle NumberOfLaps
-		value, exists = record["NumberOfLaps"].(float64)
-		if exists {
-			currentDriver.Lap = int(value.(float64))
+		if record.NumberOfLaps != nil {
+			currentDriver.Lap = *record.NumberOfLaps
 			if currentDriver.Location == Messages.OutLap {
 				currentDriver.Location = Messages.OnTrack
 			}
 		}
 
-		sectors, exists := record["Sectors"]
-
 		// TODO - sectors count from 0?
-		if exists {
-			// Work out how many segments this track has
+		if record.Sectors.len() > 0 {
+			// Work out how many segments this track has from the first message with all of them. Older data doesn't
+			// have segments.
 			if p.eventState.Sector1Segments == 0 {
-				var one, two, three interface{}
-
-				switch sectors.(type) {
-				case map[string]interface{}:
-					one = sectors.(map[string]interface{})["0"]
-					two = sectors.(map[string]interface{})["1"]
-					three = sectors.(map[string]interface{})["2"]
-
-				case []interface{}:
-					one = sectors.([]interface{})[0]
-					two = sectors.([]interface{})[1]
-					three = sectors.([]interface{})[2]
-
-				default:
-					p.ParseErrorf(connection.TimingDataFile, timestamp, "Unhandled data format: %v", dat)
-				}
-
-				// Older data doesn't have this
-				if one != nil {
-					// Older data doesn't have this
-					_, exists = one.(map[string]interface{})["Segments"]
-					if exists {
-
-						if reflect.TypeOf(one.(map[string]interface{})["Segments"]).Kind() == reflect.Slice {
-							p.eventState.Sector1Segments = len(one.(map[string]interface{})["Segments"].([]interface{}))
-							p.eventState.Sector2Segments = len(two.(map[string]interface{})["Segments"].([]interface{}))
-							p.eventState.Sector3Segments = len(three.(map[string]interface{})["Segments"].([]interface{}))
-							p.eventState.TotalSegments = p.eventState.Sector1Segments + p.eventState.Sector2Segments + p.eventState.Sector3Segments
-						}
-					}
+				one, hasOne := record.Sectors.get(0)
+				two, hasTwo := record.Sectors.get(1)
+				three, hasThree := record.Sectors.get(2)
+
+				if hasOne && hasTwo && hasThree && one.Segments.whole && two.Segments.whole && three.Segments.whole {
+					p.eventState.Sector1Segments = one.Segments.len()
+					p.eventState.Sector2Segments = two.Segments.len()
+					p.eventState.Sector3Segments = three.Segments.len()
+					p.eventState.TotalSegments = p.eventState.Sector1Segments + p.eventState.Sector2Segments + p.eventState.Sector3Segments
 				}
 			}
 
-			switch sectors.(type) {
-			case map[string]interface{}:
-				for key, value2 := range sectors.(map[string]interface{}) {
-					p.processSectorTimes(key, value2, &currentDriver, timestamp)
-				}
-
-			case []interface{}:
-				for key, value2 := range sectors.([]interface{}) {
-					p.processSectorTimes(string(key), value2, &currentDriver, timestamp)
-				}
-
-			default:
-				p.ParseErrorf(connection.TimingDataFile, timestamp, "Unhandled data format: %v", dat)
-			}
+			record.Sectors.each(func(key int, sector timingSector) {
+				p.processSectorTimes(key, sector, &currentDriver, timestamp)
+			})
 		}
 
 		// Override location after reading if from the segments
-		value, exists = record["Stopped"]
-		if exists {
-			if value.(bool) {
-				currentDriver.Location = Messages.Stopped
-			}
+		if record.Stopped != nil && *record.Stopped {
+			currentDriver.Location = Messages.Stopped
 		}
 
-		value, exists = record["Retired"].(string)
-		if exists {
-			if value.(bool) {
-				currentDriver.Location = Messages.OutOfRace
-			}
+		if record.Retired != nil && *record.Retired {
+			currentDriver.Location = Messages.OutOfRace
 		}
 
 		// We use the segments to work out when we are in the pitlane
@@ -258,43 +157,30 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 		//		currentDriver.Location = Messages.OutLap
 		//}
 
-		bestLapTime, exists := record["BestLapTime"].(map[string]interface{})
-		if exists {
-			var t time.Duration
+		if record.BestLapTime != nil && record.BestLapTime.Value != nil {
+			currentDriver.FastestLap = p.timingDuration(timestamp, "BestLapTime Value", *record.BestLapTime.Value)
 
-			lapTime, exists := bestLapTime["Value"]
-			if exists {
-				if len(lapTime.(string)) > 0 {
-					t, err = parseDuration(lapTime.(string))
-					if err != nil {
-						p.ParseTimeError(connection.TimingDataFile, timestamp, "BestLapTime Value", err)
-					}
-				}
-
-				currentDriver.FastestLap = t.Milliseconds()
+			// Older data doesn't have the best lap for each part of qualifying
+			if part := p.qualifyingPart(); part >= 0 && currentDriver.FastestLap > 0 {
+				currentDriver.QualifyingBestLaps[part] = currentDriver.FastestLap
 			}
 
-			//lapTime, exists := bestLapTime["_deleted"]
-			//if exists {
-			//	// TODO - handle deleted lap time
-			//}
+			// TODO - handle deleted lap time
 		}
 
-		lastLapTime, exists := record["LastLapTime"].(map[string]interface{})
-		if exists {
-			value, exists := lastLapTime["Value"]
-			if exists && len(value.(string)) > 0 {
-				t, err := parseDuration(value.(string))
-				if err != nil {
-					p.ParseTimeError(connection.TimingDataFile, timestamp, "LastLapTime Value", err)
-				}
+		record.BestLapTimes.each(func(part int, bestLap timingValue) {
+			if part < len(currentDriver.QualifyingBestLaps) && bestLap.Value != nil {
+				currentDriver.QualifyingBestLaps[part] = p.timingDuration(timestamp, "BestLapTimes Value", *bestLap.Value)
+			}
+		})
 
-				currentDriver.LastLap = t.Milliseconds()
+		if lastLapTime := record.LastLapTime; lastLapTime != nil {
+			if lastLapTime.Value != nil && len(*lastLapTime.Value) > 0 {
+				currentDriver.LastLap = p.timingDuration(timestamp, "LastLapTime Value", *lastLapTime.Value)
 			}
 
-			overallFastest, exists := lastLapTime["OverallFastest"]
-			if exists {
-				currentDriver.LastLapOverallFastest = overallFastest.(bool)
+			if lastLapTime.OverallFastest != nil {
+				currentDriver.LastLapOverallFastest = *lastLapTime.OverallFastest
 				if currentDriver.LastLapOverallFastest {
 					fastestLapChanged = true
 					currentFastestLap = currentDriver.LastLap
@@ -302,54 +188,51 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 				}
 			}
 
-			personalFastest, exists := lastLapTime["PersonalFastest"]
-			if exists {
-				currentDriver.LastLapPersonalFastest = personalFastest.(bool)
+			if lastLapTime.PersonalFastest != nil {
+				currentDriver.LastLapPersonalFastest = *lastLapTime.PersonalFastest
 			}
 		}
 
-		speeds, exists := record["Speeds"]
-		if exists {
-			// TODO - handle 'I1', 'I2', 'FL'
+		if record.Speeds != nil && record.Speeds.ST != nil {
+			speedTrap := record.Speeds.ST
 
-			speedTrap, exists := speeds.(map[string]interface{})["ST"]
-			if exists {
-				val, exists := speedTrap.(map[string]interface{})["Value"]
-				if exists {
-					st, _ := strconv.Atoi(val.(string))
-					currentDriver.SpeedTrap = st // KM/hr
-				}
+			if speedTrap.Value != nil {
+				st, _ := strconv.Atoi(*speedTrap.Value)
+				currentDriver.SpeedTrap = st // KM/hr
+			}
 
-				overallFastest, exists := speedTrap.(map[string]interface{})["OverallFastest"]
-				if exists {
-					currentDriver.SpeedTrapOverallFastest = overallFastest.(bool)
-				}
+			if speedTrap.OverallFastest != nil {
+				currentDriver.SpeedTrapOverallFastest = *speedTrap.OverallFastest
+			}
 
-				personalFastest, exists := speedTrap.(map[string]interface{})["PersonalFastest"]
-				if exists {
-					currentDriver.SpeedTrapPersonalFastest = personalFastest.(bool)
-				}
+			if speedTrap.PersonalFastest != nil {
+				currentDriver.SpeedTrapPersonalFastest = *speedTrap.PersonalFastest
 			}
 		}
 
-		knockedOut, exists := record["KnockedOut"]
-		if exists {
-			currentDriver.KnockedOutOfQualifying = knockedOut.(bool)
+		if record.KnockedOut != nil {
+			currentDriver.KnockedOutOfQualifying = *record.KnockedOut
 		}
 
 		p.driverTimes[driverNumber] = currentDriver
 
+		if currentDriver.Lap > previousLap {
+			p.lapReference.lapCompleted(currentDriver.Number, timestamp, currentDriver.Location)
+		} else {
+			p.lapReference.locationChanged(currentDriver.Number, currentDriver.Location)
+		}
+
 		result = append(result, currentDriver)
-	}
+	})
 
 	// Quali doesn't give us gap times so we have to calculate them when the overall fastest lap changes
-	if fastestLapChanged && p.session == Messages.QualifyingSession {
+	if fastestLapChanged && (p.session == Messages.QualifyingSession || p.session == Messages.SprintQualifyingSession) {
 		result = make([]Messages.Timing, 0)
 
 		orderedDrivers := make([]Messages.Timing, 0)
 
-		for _, info := range p.driverTimes {
-			orderedDrivers = append(orderedDrivers, info)
+		for _, driverNumber := range p.driverNumbers() {
+			orderedDrivers = append(orderedDrivers, p.driverTimes[driverNumber])
 		}
 
 		sort.SliceStable(orderedDrivers, func(i, j int) bool {
@@ -376,70 +259,112 @@ func (p *Parser) parseTimingData(dat map[string]interface{}, timestamp time.Time
 			p.driverTimes[strconv.Itoa(orderedDrivers[x].Number)] = orderedDrivers[x]
 			result = append(result, orderedDrivers[x])
 		}
-	} else if fastestLapChanged && p.session == Messages.RaceSession || p.session == Messages.SprintSession {
+	} else if fastestLapChanged && (p.session == Messages.RaceSession || p.session == Messages.SprintSession) {
 		// For races we need to know who has the overall fastest lap
 		result = make([]Messages.Timing, 0)
-		for x, info := range p.driverTimes {
+		for _, x := range p.driverNumbers() {
+			info := p.driverTimes[x]
 			info.OverallFastestLap = info.FastestLap == currentFastestLap
 			p.driverTimes[strconv.Itoa(p.driverTimes[x].Number)] = info
 			result = append(result, p.driverTimes[x])
 		}
 	}
 
-	return result, nil
+	if p.qualifyingPart() >= 0 {
+		result = p.updateKnockoutZone(result)
+	}
+
+	// Completed laps are only sent once so later messages for the driver don't repeat them
+	for number, driver := range p.driverTimes {
+		if driver.CompletedLap != nil {
+			driver.CompletedLap = nil
+			p.driverTimes[number] = driver
+		}
+	}
+
+	var events []Messages.Event
+	if p.eventState.SessionBest != sessionBest ||
+		p.eventState.KnockoutCutoff != cutoff ||
+		p.eventState.ProgressingCars != progressing {
+		p.eventState.Timestamp = timestamp
+		events = append(events, p.eventState)
+	}
+
+	return result, events, nil
 }
 
-func (p *Parser) processSectorTimes(key string, value interface{}, driver *Messages.Timing, timestamp time.Time) {
+// timingDuration is the time in milliseconds, an empty value clears the time
+func (p *Parser) timingDuration(timestamp time.Time, field string, value string) int64 {
+	if len(value) == 0 {
+		return 0
+	}
 
-	segments, exists := value.(map[string]interface{})["Segments"]
-	if exists {
-		segmentState := Messages.None
-		currentSegmentIndex := 0
-		useSegmentChange := true
+	t, err := parseDuration(value)
+	if err != nil {
+		p.ParseTimeError(connection.TimingDataFile, timestamp, field, err)
+	}
 
-		if reflect.TypeOf(segments).Kind() == reflect.Slice {
-			for x, info := range segments.([]interface{}) {
-				currentSegmentIndex = x
-				segmentState, useSegmentChange = p.calcSegment(key, info, timestamp, currentSegmentIndex, driver)
+	return t.Milliseconds()
+}
 
-				if useSegmentChange {
-					p.updateLocation(driver, segmentState, timestamp)
-				}
-			}
+// leaderLap reads the leader's gap which is the lap they are on, like "LAP 5"
+func leaderLap(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "LAP") {
+		return 0, false
+	}
 
-		} else if reflect.TypeOf(segments).Kind() == reflect.Map {
-			for x, info := range segments.(map[string]interface{}) {
-				currentSegmentIndex, _ = strconv.Atoi(x)
-				segmentState, useSegmentChange = p.calcSegment(key, info, timestamp, currentSegmentIndex, driver)
+	lap, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(value, "LAP")))
+	return lap, err == nil
+}
 
-				if useSegmentChange {
-					p.updateLocation(driver, segmentState, timestamp)
-				}
-			}
+// lapsBehind reads a gap for a lapped car, like "1L", "+2 L" or "1 LAP"
+func lapsBehind(value string) (int, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "+")
+
+	for _, suffix := range []string{"LAPS", "LAP", "L"} {
+		if strings.HasSuffix(value, suffix) {
+			laps, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(value, suffix)))
+			return laps, err == nil && laps > 0
 		}
 	}
 
-	previousValue, exists := value.(map[string]interface{})["Value"]
-	if exists {
+	return 0, false
+}
+
+func (p *Parser) processSectorTimes(key int, sector timingSector, driver *Messages.Timing, timestamp time.Time) {
+
+	sector.Segments.each(func(currentSegmentIndex int, info timingSegment) {
+		segmentState, useSegmentChange := p.calcSegment(key, info, timestamp, currentSegmentIndex, driver)
+
+		if useSegmentChange {
+			p.updateLocation(driver, segmentState, timestamp)
+		}
+	})
+
+	if sector.Value != nil {
 		var sectorTime time.Duration
 		var err error
 
-		if len(previousValue.(string)) > 0 {
-			sectorTime, err = parseDuration(previousValue.(string))
+		if len(*sector.Value) > 0 {
+			sectorTime, err = parseDuration(*sector.Value)
 			if err != nil {
 				p.ParseTimeError(connection.TimingDataFile, timestamp, "Sector Value", err)
 			}
 		}
 
 		switch key {
-		case "0":
+		case 0:
 			driver.Sector1 = sectorTime.Milliseconds()
+			driver.CurrentLapSectors.Sector1 = driver.Sector1
 
-		case "1":
+		case 1:
 			driver.Sector2 = sectorTime.Milliseconds()
+			driver.CurrentLapSectors.Sector2 = driver.Sector2
 
-		case "2":
+		case 2:
 			driver.Sector3 = sectorTime.Milliseconds()
+			driver.CurrentLapSectors.Sector3 = driver.Sector3
 
 			if p.eventState.TrackStatus == Messages.ChequeredFlag {
 				driver.ChequeredFlag = true
@@ -447,33 +372,39 @@ func (p *Parser) processSectorTimes(key string, value interface{}, driver *Messa
 
 			if sectorTime != 0 {
 				driver.LapsOnTire++
+
+				// The lap count comes before the sectors so is already the lap just finished
+				driver.CurrentLapSectors.Lap = driver.Lap
+				completed := driver.CurrentLapSectors
+				driver.CompletedLap = &completed
+				driver.CurrentLapSectors = Messages.LapSectors{}
 			}
 		}
+
+		p.updateBestSector(driver, key, sectorTime.Milliseconds())
 	}
 
-	isFastest, exists := value.(map[string]interface{})["OverallFastest"]
-	if exists {
+	if sector.OverallFastest != nil {
 		switch key {
-		case "0":
-			driver.Sector1OverallFastest = isFastest.(bool)
+		case 0:
+			driver.Sector1OverallFastest = *sector.OverallFastest
 
-		case "1":
-			driver.Sector2OverallFastest = isFastest.(bool)
+		case 1:
+			driver.Sector2OverallFastest = *sector.OverallFastest
 
-		case "2":
-			driver.Sector3OverallFastest = isFastest.(bool)
+		case 2:
+			driver.Sector3OverallFastest = *sector.OverallFastest
 		}
 	}
 
-	isFastest, exists = value.(map[string]interface{})["PersonalFastest"]
-	if exists {
+	if sector.PersonalFastest != nil {
 		switch key {
-		case "0":
-			driver.Sector1PersonalFastest = isFastest.(bool)
-		case "1":
-			driver.Sector2PersonalFastest = isFastest.(bool)
-		case "2":
-			driver.Sector3PersonalFastest = isFastest.(bool)
+		case 0:
+			driver.Sector1PersonalFastest = *sector.PersonalFastest
+		case 1:
+			driver.Sector2PersonalFastest = *sector.PersonalFastest
+		case 2:
+			driver.Sector3PersonalFastest = *sector.PersonalFastest
 		}
 	}
 }
@@ -522,24 +453,31 @@ func (p *Parser) updateLocation(driver *Messages.Timing, segmentState Messages.S
 }
 
 func (p *Parser) calcSegment(
-	key string,
-	info interface{},
+	key int,
+	info timingSegment,
 	timestamp time.Time,
 	currentSegment int,
 	driver *Messages.Timing) (Messages.SegmentType, bool) {
 
 	segmentState := Messages.None
-	abc := info.(map[string]interface{})
-	status := int(abc["Status"].(float64))
+	status := 0
+	if info.Status != nil {
+		status = *info.Status
+	}
 	useSegmentChange := false
 
 	segmentIndex := currentSegment
-	if key == "1" {
+	if key == 1 {
 		segmentIndex = p.eventState.Sector1Segments + currentSegment
-	} else if key == "2" {
+	} else if key == 2 {
 		segmentIndex = p.eventState.Sector1Segments + p.eventState.Sector2Segments + currentSegment
 	}
 
+	if segmentIndex >= Messages.MaxSegments {
+		p.ParseErrorf(connection.TimingDataFile, timestamp, "Segment %d of sector %d is past the last segment", currentSegment, key)
+		return segmentState, false
+	}
+
 	if status != 0 {
 		switch status {
 		case 2048:
@@ -570,29 +508,34 @@ func (p *Parser) calcSegment(
 				segmentIndex < p.eventState.Sector1Segments)
 
 		switch key {
-		case "0":
+		case 0:
 			// If the last segment was in the third sector then we have started a new lap so clear everything
 			if driver.PreviousSegmentIndex > (p.eventState.Sector1Segments + p.eventState.Sector2Segments) {
 				for y := 0; y < len(driver.Segment); y++ {
 					driver.Segment[y] = Messages.None
 				}
+				// Only has anything left if the lap didn't get a sector 3 time
+				driver.CurrentLapSectors.MiniSectors = [Messages.MaxSegments]Messages.MiniSector{}
 			}
 
+			p.recordMiniSector(driver, segmentIndex, segmentState, timestamp)
 			driver.Segment[segmentIndex] = segmentState
 			if segmentIndex > driver.PreviousSegmentIndex ||
 				driver.PreviousSegmentIndex > (p.eventState.Sector1Segments+p.eventState.Sector2Segments) {
 				driver.PreviousSegmentIndex = segmentIndex
 			}
 
-		case "1":
+		case 1:
+			p.recordMiniSector(driver, segmentIndex, segmentState, timestamp)
 			driver.Segment[segmentIndex] = segmentState
 
 			if segmentIndex > driver.PreviousSegmentIndex {
 				driver.PreviousSegmentIndex = segmentIndex
 			}
-		case "2":
+		case 2:
 			// If we get late data and we have already started a new lap then ignore
 			if !(driver.PreviousSegmentIndex < p.eventState.Sector1Segments) {
+				p.recordMiniSector(driver, segmentIndex, segmentState, timestamp)
 				driver.Segment[segmentIndex] = segmentState
 
 				if segmentIndex > driver.PreviousSegmentIndex {
@@ -604,3 +547,80 @@ func (p *Parser) calcSegment(
 
 	return segmentState, useSegmentChange
 }
+
+// recordMiniSector adds the segment to the lap in progress. It is timed from the previous segment when that was the
+// last one passed.
+func (p *Parser) recordMiniSector(driver *Messages.Timing, segmentIndex int, segmentState Messages.SegmentType, timestamp time.Time) {
+	miniSector := &driver.CurrentLapSectors.MiniSectors[segmentIndex]
+	firstPass := miniSector.Status == Messages.None
+	miniSector.Status = segmentState
+
+	// The status can change after passing, like a yellow flag being cleared, but the time doesn't
+	if !firstPass {
+		return
+	}
+
+	previousSegment := segmentIndex - 1
+	if previousSegment < 0 {
+		previousSegment = p.eventState.TotalSegments - 1
+	}
+
+	timed := segmentState == Messages.YellowSegment ||
+		segmentState == Messages.GreenSegment ||
+		segmentState == Messages.PurpleSegment
+
+	// Segments sent together have the same timestamp so can't be timed
+	if timed &&
+		driver.PreviousSegmentIndex == previousSegment &&
+		!driver.PreviousSegmentTime.IsZero() &&
+		timestamp.After(driver.PreviousSegmentTime) {
+
+		miniSector.Time = timestamp.Sub(driver.PreviousSegmentTime).Milliseconds()
+
+		updateBest(&driver.PersonalBest.MiniSectors[segmentIndex], miniSector.Time)
+		updateBest(&p.eventState.SessionBest.MiniSectors[segmentIndex], miniSector.Time)
+	}
+
+	driver.PreviousSegmentTime = timestamp
+}
+
+// updateBestSector keeps the fastest time for the sector for the driver and for everyone
+func (p *Parser) updateBestSector(driver *Messages.Timing, key int, sectorTime int64) {
+	if updateBest(bestSector(&driver.PersonalBest, key), sectorTime) {
+		setIdealLap(&driver.PersonalBest)
+	}
+
+	if updateBest(bestSector(&p.eventState.SessionBest, key), sectorTime) {
+		setIdealLap(&p.eventState.SessionBest)
+	}
+}
+
+func bestSector(best *Messages.BestSectors, key int) *int64 {
+	switch key {
+	case 0:
+		return &best.Sector1
+	case 1:
+		return &best.Sector2
+	default:
+		return &best.Sector3
+	}
+}
+
+// updateBest replaces the best time if the new time is faster. A time of 0 isn't a time.
+func updateBest(best *int64, value int64) bool {
+	if value <= 0 || (*best != 0 && *best <= value) {
+		return false
+	}
+
+	*best = value
+	return true
+}
+
+func setIdealLap(best *Messages.BestSectors) {
+	if best.Sector1 == 0 || best.Sector2 == 0 || best.Sector3 == 0 {
+		best.IdealLap = 0
+		return
+	}
+
+	best.IdealLap = best.Sector1 + best.Sector2 + best.Sector3
+}
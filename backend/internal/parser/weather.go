@@ -5,26 +5,34 @@ import (
 	"time"
 
 	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
 )
 
-func parseWeatherData(dat map[string]interface{}, timestamp time.Time) (Messages.Weather, error) {
-
-	airTemp, _ := strconv.ParseFloat(dat["AirTemp"].(string), 8)
-	humidity, _ := strconv.ParseFloat(dat["Humidity"].(string), 8)
-	pressure, _ := strconv.ParseFloat(dat["Pressure"].(string), 8)
-	rainfall, _ := strconv.ParseBool(dat["Rainfall"].(string))
-	trackTemp, _ := strconv.ParseFloat(dat["TrackTemp"].(string), 8)
-	windDirection, _ := strconv.ParseFloat(dat["WindDirection"].(string), 8)
-	windSpeed, _ := strconv.ParseFloat(dat["WindSpeed"].(string), 8)
-
-	return Messages.Weather{
-		Timestamp:     timestamp,
-		AirTemp:       airTemp,
-		Humidity:      humidity,
-		AirPressure:   pressure,
-		Rainfall:      rainfall,
-		TrackTemp:     trackTemp,
-		WindDirection: windDirection,
-		WindSpeed:     windSpeed,
-	}, nil
+func (p *Parser) parseWeatherData(dat []byte, timestamp time.Time) (Messages.Weather, error) {
+
+	var data weatherData
+	if err := p.decode(connection.WeatherDataFile, timestamp, dat, &data); err != nil {
+		return Messages.Weather{}, err
+	}
+
+	// Updates only have the values that changed
+	weatherValue(data.AirTemp, &p.weather.AirTemp)
+	weatherValue(data.Humidity, &p.weather.Humidity)
+	weatherValue(data.Pressure, &p.weather.AirPressure)
+	weatherValue(data.TrackTemp, &p.weather.TrackTemp)
+	weatherValue(data.WindDirection, &p.weather.WindDirection)
+	weatherValue(data.WindSpeed, &p.weather.WindSpeed)
+	if data.Rainfall != nil {
+		p.weather.Rainfall, _ = strconv.ParseBool(*data.Rainfall)
+	}
+
+	p.weather.Timestamp = timestamp
+
+	return p.weather, nil
+}
+
+func weatherValue(value *string, field *float64) {
+	if value != nil {
+		*field, _ = strconv.ParseFloat(*value, 8)
+	}
 }
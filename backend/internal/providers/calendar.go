@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+)
+
+type SessionStatus int
+
+const (
+	Completed SessionStatus = iota
+	Live
+	Upcoming
+)
+
+func (s SessionStatus) String() string {
+	return [...]string{"Completed", "Live", "Upcoming"}[s]
+}
+
+// CalendarSession is a single session of a meeting with the information that RaceEvent keeps private
+type CalendarSession struct {
+	Type      Messages.SessionType
+	TypeName  string
+	EventTime time.Time
+	Timezone  string
+	Url       string
+	Status    SessionStatus
+	Cached    bool
+
+	event RaceEvent
+}
+
+func (c *CalendarSession) Event() RaceEvent {
+	return c.event
+}
+
+// Meeting is all the sessions that make up one event weekend
+type Meeting struct {
+	Name              string
+	Country           string
+	TrackName         string
+	TrackYearCreated  int
+	Year              int
+	RaceTime          time.Time
+	Timezone          string
+	TimeLostInPitlane time.Duration
+
+	Sessions []CalendarSession
+}
+
+type CalendarFilter struct {
+	// Zero means all years
+	Year    int
+	Country string
+	Track   string
+	// Empty means all session types
+	SessionTypes []Messages.SessionType
+	// Case insensitive match against the meeting name, country or track
+	Search          string
+	IncludeUpcoming bool
+}
+
+// Calendar returns the meetings matching the filter, newest first, with the sessions for each meeting in the order
+// they happen. If cache is set then each session reports whether its data has already been downloaded.
+func Calendar(filter CalendarFilter, cache string) []Meeting {
+	result := make([]Meeting, 0)
+	meetings := make(map[string]int)
+
+	live, next, hasLive, hasNext := HappeningSessions()
+	now := time.Now()
+
+	for _, session := range allSessions() {
+		status := Completed
+		if hasLive && session.Url() == live.Url() {
+			status = Live
+		} else if hasNext && session.Url() == next.Url() {
+			status = Upcoming
+		} else if session.EventTime.After(now) {
+			status = Upcoming
+		} else if sessionEnd(session).After(now) {
+			status = Live
+		}
+
+		if status != Completed && !filter.IncludeUpcoming {
+			continue
+		}
+
+		if !filter.matches(session) {
+			continue
+		}
+
+		key := meetingKey(session)
+		index, exists := meetings[key]
+		if !exists {
+			index = len(result)
+			meetings[key] = index
+			result = append(result, Meeting{
+				Name:              session.Name,
+				Country:           session.Country,
+				TrackName:         session.TrackName,
+				TrackYearCreated:  session.TrackYearCreated,
+				Year:              session.RaceTime.Year(),
+				RaceTime:          session.RaceTime,
+				Timezone:          session.timezone,
+				TimeLostInPitlane: session.TimeLostInPitlane,
+			})
+		}
+
+		// History is newest first so prepend to get the sessions in the order they happen
+		result[index].Sessions = append([]CalendarSession{{
+			Type:      session.Type,
+			TypeName:  session.Type.String(),
+			EventTime: session.EventTime,
+			Timezone:  session.timezone,
+			Url:       session.Url(),
+			Status:    status,
+			Cached:    len(cache) > 0 && IsCached(cache, session),
+			event:     session,
+		}}, result[index].Sessions...)
+	}
+
+	return result
+}
+
+// FindSession returns the completed session for the meeting with the given name in the given year. A year of zero
+// uses the most recent meeting with the name and if no session type is given then the last session of the meeting is
+// used.
+func FindSession(year int, meetingName string, sessionType *Messages.SessionType) (RaceEvent, bool) {
+	for _, session := range RaceHistory() {
+		if session.Name != meetingName || (year != 0 && session.RaceTime.Year() != year) {
+			continue
+		}
+
+		if sessionType == nil || session.Type == *sessionType {
+			return session, true
+		}
+	}
+
+	return RaceEvent{}, false
+}
+
+// ParseSessionType accepts either the numeric value or the name of a session type
+func ParseSessionType(value string) (Messages.SessionType, bool) {
+	value = strings.TrimSpace(value)
+
+	number, err := strconv.Atoi(value)
+	if err == nil {
+		if number < int(Messages.Practice1Session) || number > int(Messages.SprintQualifyingSession) {
+			return 0, false
+		}
+		return Messages.SessionType(number), true
+	}
+
+	for x := Messages.Practice1Session; x <= Messages.SprintQualifyingSession; x++ {
+		if strings.EqualFold(value, x.String()) ||
+			strings.EqualFold(value, strings.ReplaceAll(x.String(), " ", "")) {
+			return x, true
+		}
+	}
+
+	return 0, false
+}
+
+// CachePath is the folder the data for an event is cached in
+func CachePath(cache string, event RaceEvent) string {
+	return filepath.Join(cache, fmt.Sprintf("%d", event.RaceTime.Year()), fmt.Sprintf("%s_%s", event.RaceTime.Format("2006-01-02"), event.Name), event.Type.String())
+}
+
+// IsCached is true if the timing data for the event has been downloaded to the cache
+func IsCached(cache string, event RaceEvent) bool {
+	_, err := os.Stat(filepath.Join(CachePath(cache, event), connection.ExtrapolatedClockFile+".jsonStream"))
+	return err == nil
+}
+
+func (c *CalendarFilter) matches(session RaceEvent) bool {
+	if c.Year != 0 && session.RaceTime.Year() != c.Year {
+		return false
+	}
+
+	if len(c.Country) > 0 && !strings.EqualFold(session.Country, c.Country) {
+		return false
+	}
+
+	if len(c.Track) > 0 && !strings.EqualFold(session.TrackName, c.Track) {
+		return false
+	}
+
+	if len(c.SessionTypes) > 0 {
+		found := false
+		for _, sessionType := range c.SessionTypes {
+			if session.Type == sessionType {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(c.Search) > 0 {
+		search := strings.ToLower(c.Search)
+		if !strings.Contains(strings.ToLower(session.Name), search) &&
+			!strings.Contains(strings.ToLower(session.Country), search) &&
+			!strings.Contains(strings.ToLower(session.TrackName), search) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func meetingKey(session RaceEvent) string {
+	return session.RaceTime.Format(time.RFC3339) + "_" + session.Name
+}
+
+func sessionEnd(session RaceEvent) time.Time {
+	sessionEnd := session.EventTime
+	switch session.Type {
+	case Messages.Practice1Session, Messages.Practice2Session, Messages.Practice3Session:
+		sessionEnd = sessionEnd.Add(time.Hour * 1)
+
+	case Messages.QualifyingSession, Messages.SprintQualifyingSession:
+		sessionEnd = sessionEnd.Add(time.Hour * 1)
+
+	case Messages.SprintSession:
+		sessionEnd = sessionEnd.Add(time.Hour * 1)
+
+	case Messages.RaceSession:
+		sessionEnd = sessionEnd.Add(time.Hour * 3)
+	}
+
+	return sessionEnd
+}
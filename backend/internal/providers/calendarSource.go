@@ -0,0 +1,453 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+	"gopkg.in/yaml.v3"
+)
+
+const LiveTimingStaticUrl = connection.LiveTimingStaticUrl
+
+const defaultTimeLostInPitlane = time.Duration(20000) * time.Millisecond
+
+var calendarLock sync.RWMutex
+var calendarSessions = builtInSessions()
+
+// calendarFile is the layout of a local calendar file. Sessions use the same fields as RaceEvent with the session
+// type given by name ("Practice 1", "Qualifying", "Race"...) and the pitlane time as a duration ("20s"). If no url is
+// given it is built from the url name in the same way as CreateRaceEvent.
+type calendarFile struct {
+	Sessions []calendarFileEntry `json:"sessions" yaml:"sessions"`
+}
+
+type calendarFileEntry struct {
+	Country           string    `json:"country" yaml:"country"`
+	Name              string    `json:"name" yaml:"name"`
+	Type              string    `json:"type" yaml:"type"`
+	RaceTime          time.Time `json:"raceTime" yaml:"raceTime"`
+	EventTime         time.Time `json:"eventTime" yaml:"eventTime"`
+	Timezone          string    `json:"timezone" yaml:"timezone"`
+	TrackName         string    `json:"trackName" yaml:"trackName"`
+	TrackYearCreated  int       `json:"trackYearCreated" yaml:"trackYearCreated"`
+	TimeLostInPitlane string    `json:"timeLostInPitlane" yaml:"timeLostInPitlane"`
+	Url               string    `json:"url" yaml:"url"`
+	UrlName           string    `json:"urlName" yaml:"urlName"`
+}
+
+// indexFile is the layout of the livetiming <year>/Index.json file
+type indexFile struct {
+	Year     int
+	Meetings []struct {
+		Name     string
+		Location string
+		Country  struct {
+			Name string
+		}
+		Circuit struct {
+			ShortName string
+		}
+		Sessions []struct {
+			Type      string
+			Number    int
+			Name      string
+			StartDate string
+			GmtOffset string
+			Path      string
+		}
+	}
+}
+
+func builtInSessions() []RaceEvent {
+	result := make([]RaceEvent, len(sessionHistory))
+	copy(result, sessionHistory[:])
+	sortSessions(result)
+	return result
+}
+
+// allSessions is every known session, newest first. The returned slice is never modified so it is safe to use
+// without holding the lock.
+func allSessions() []RaceEvent {
+	calendarLock.RLock()
+	defer calendarLock.RUnlock()
+	return calendarSessions
+}
+
+func sortSessions(sessions []RaceEvent) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].EventTime.After(sessions[j].EventTime)
+	})
+}
+
+// MergeCalendar adds the sessions to the calendar. Sessions with the same url as an existing session replace it.
+// Invalid sessions are skipped and reported in the returned error.
+func MergeCalendar(events []RaceEvent) error {
+	var errs []error
+	valid := make(map[string]RaceEvent)
+
+	for _, event := range events {
+		if err := ValidateRaceEvent(event); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		valid[event.Url()] = event
+	}
+
+	calendarLock.Lock()
+	defer calendarLock.Unlock()
+
+	merged := make([]RaceEvent, 0, len(calendarSessions)+len(valid))
+	for _, existing := range calendarSessions {
+		if _, replaced := valid[existing.Url()]; !replaced {
+			merged = append(merged, existing)
+		}
+	}
+	for _, event := range valid {
+		merged = append(merged, event)
+	}
+	sortSessions(merged)
+
+	calendarSessions = merged
+
+	return errors.Join(errs...)
+}
+
+// ResetCalendar removes any loaded sessions and goes back to the built in calendar
+func ResetCalendar() {
+	calendarLock.Lock()
+	defer calendarLock.Unlock()
+	calendarSessions = builtInSessions()
+}
+
+// ValidateRaceEvent checks the event has a known session type, a loadable timezone and a folder url for the data
+func ValidateRaceEvent(event RaceEvent) error {
+	if event.Type < Messages.Practice1Session || event.Type > Messages.SprintQualifyingSession {
+		return fmt.Errorf("%s: invalid session type %d", event.Name, event.Type)
+	}
+
+	if len(event.timezone) == 0 {
+		return fmt.Errorf("%s - %s: missing timezone", event.Name, event.Type)
+	}
+
+	if _, err := time.LoadLocation(event.timezone); err != nil {
+		return fmt.Errorf("%s - %s: invalid timezone '%s': %w", event.Name, event.Type, event.timezone, err)
+	}
+
+	eventUrl, err := url.Parse(event.urlName)
+	if err != nil {
+		return fmt.Errorf("%s - %s: invalid url '%s': %w", event.Name, event.Type, event.urlName, err)
+	}
+
+	// Local folders can be relative so don't need a host
+	isFolder := strings.HasSuffix(event.urlName, "/")
+	switch eventUrl.Scheme {
+	case "http", "https":
+		isFolder = isFolder && len(eventUrl.Host) > 0
+	case "file":
+	default:
+		isFolder = false
+	}
+	if !isFolder {
+		return fmt.Errorf("%s - %s: url must be an http(s) or file folder: '%s'", event.Name, event.Type, event.urlName)
+	}
+
+	if event.EventTime.IsZero() || event.RaceTime.IsZero() {
+		return fmt.Errorf("%s - %s: missing event or race time", event.Name, event.Type)
+	}
+
+	return nil
+}
+
+// LoadCalendarFile reads sessions from a JSON or YAML file and merges them with the calendar
+func LoadCalendarFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file calendarFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		return fmt.Errorf("unsupported calendar file type: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading calendar file '%s': %w", path, err)
+	}
+
+	events := make([]RaceEvent, 0, len(file.Sessions))
+	var errs []error
+	for _, entry := range file.Sessions {
+		event, err := entry.raceEvent()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	errs = append(errs, MergeCalendar(events))
+	return errors.Join(errs...)
+}
+
+func (c *calendarFileEntry) raceEvent() (RaceEvent, error) {
+	sessionType, ok := ParseSessionType(c.Type)
+	if !ok {
+		return RaceEvent{}, fmt.Errorf("%s: invalid session type '%s'", c.Name, c.Type)
+	}
+
+	pitlaneTime := defaultTimeLostInPitlane
+	if len(c.TimeLostInPitlane) > 0 {
+		var err error
+		pitlaneTime, err = time.ParseDuration(c.TimeLostInPitlane)
+		if err != nil {
+			return RaceEvent{}, fmt.Errorf("%s - %s: invalid time lost in pitlane '%s': %w", c.Name, c.Type, c.TimeLostInPitlane, err)
+		}
+	}
+
+	if len(c.Url) == 0 {
+		if len(c.UrlName) == 0 {
+			return RaceEvent{}, fmt.Errorf("%s - %s: needs either a url or a url name", c.Name, c.Type)
+		}
+
+		return *CreateRaceEvent(
+			c.Country,
+			c.RaceTime.UTC(),
+			c.EventTime.UTC(),
+			sessionType,
+			c.Name,
+			c.TrackName,
+			c.TrackYearCreated,
+			pitlaneTime,
+			c.UrlName,
+			c.Timezone), nil
+	}
+
+	return RaceEvent{
+		Country:           c.Country,
+		RaceTime:          c.RaceTime.UTC(),
+		EventTime:         c.EventTime.UTC(),
+		Type:              sessionType,
+		Name:              c.Name,
+		timezone:          c.Timezone,
+		TrackName:         c.TrackName,
+		TrackYearCreated:  c.TrackYearCreated,
+		TimeLostInPitlane: pitlaneTime,
+		urlName:           c.Url,
+	}, nil
+}
+
+// RefreshCalendar downloads the Index.json for the year from the livetiming site (or a mirror with the same layout)
+// and merges the sessions with the calendar. Details that the index doesn't have, like the timezone and time lost in
+// the pitlane, are taken from the most recent known session for the same meeting.
+func RefreshCalendar(baseUrl string, year int) error {
+	events, err := fetchIndex(baseUrl, year)
+	if events == nil {
+		return err
+	}
+
+	// Sessions that couldn't be read are reported but don't stop the rest being used
+	return errors.Join(err, MergeCalendar(events))
+}
+
+func fetchIndex(baseUrl string, year int) ([]RaceEvent, error) {
+	if !strings.HasSuffix(baseUrl, "/") {
+		baseUrl += "/"
+	}
+	indexUrl := fmt.Sprintf("%s%d/Index.json", baseUrl, year)
+
+	body, err := httpClient().Get(context.Background(), indexUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The files start with a byte order mark which the json decoder doesn't like
+	data = []byte(strings.TrimPrefix(string(data), "\ufeff"))
+
+	var index indexFile
+	if err = json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("reading '%s': %w", indexUrl, err)
+	}
+
+	return index.raceEvents(baseUrl)
+}
+
+func (i *indexFile) raceEvents(baseUrl string) ([]RaceEvent, error) {
+	result := make([]RaceEvent, 0)
+	var errs []error
+
+	for _, meeting := range i.Meetings {
+		isTest := strings.Contains(meeting.Name, "Test")
+		known, hasKnown := knownMeeting(meeting.Name)
+
+		type session struct {
+			sessionType Messages.SessionType
+			start       time.Time
+			offset      time.Duration
+			path        string
+		}
+		sessions := make([]session, 0)
+		var raceTime time.Time
+
+		for _, s := range meeting.Sessions {
+			var sessionType Messages.SessionType
+			switch {
+			case isTest:
+				sessionType = Messages.PreSeasonSession
+			case s.Name == "Practice 1":
+				sessionType = Messages.Practice1Session
+			case s.Name == "Practice 2":
+				sessionType = Messages.Practice2Session
+			case s.Name == "Practice 3":
+				sessionType = Messages.Practice3Session
+			case s.Name == "Qualifying":
+				sessionType = Messages.QualifyingSession
+			case s.Name == "Sprint Qualifying", s.Name == "Sprint Shootout":
+				sessionType = Messages.SprintQualifyingSession
+			case s.Name == "Sprint":
+				sessionType = Messages.SprintSession
+			case s.Name == "Race":
+				sessionType = Messages.RaceSession
+			default:
+				errs = append(errs, fmt.Errorf("%s: unhandled session '%s'", meeting.Name, s.Name))
+				continue
+			}
+
+			offset, err := parseGmtOffset(s.GmtOffset)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s - %s: invalid gmt offset '%s': %w", meeting.Name, s.Name, s.GmtOffset, err))
+				continue
+			}
+
+			// Start dates are in the local time of the circuit
+			start, err := time.Parse("2006-01-02T15:04:05", s.StartDate)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s - %s: invalid start date '%s': %w", meeting.Name, s.Name, s.StartDate, err))
+				continue
+			}
+			start = start.Add(-offset)
+
+			if sessionType == Messages.RaceSession || start.After(raceTime) {
+				raceTime = start
+			}
+
+			sessions = append(sessions, session{
+				sessionType: sessionType,
+				start:       start,
+				offset:      offset,
+				path:        s.Path,
+			})
+		}
+
+		for _, s := range sessions {
+			event := RaceEvent{
+				Country:           meeting.Country.Name,
+				RaceTime:          raceTime,
+				EventTime:         s.start,
+				Type:              s.sessionType,
+				Name:              meeting.Name,
+				timezone:          fixedTimezone(s.offset),
+				TrackName:         meeting.Circuit.ShortName,
+				TimeLostInPitlane: defaultTimeLostInPitlane,
+				urlName:           baseUrl + s.path,
+			}
+
+			// Test days are each their own meeting
+			if isTest {
+				event.RaceTime = s.start
+			}
+
+			// The index doesn't say when the track layout was created so it stays unknown (0) for new meetings
+			if hasKnown {
+				event.Country = known.Country
+				event.timezone = known.timezone
+				event.TrackName = known.TrackName
+				event.TrackYearCreated = known.TrackYearCreated
+				event.TimeLostInPitlane = known.TimeLostInPitlane
+			}
+
+			result = append(result, event)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func knownMeeting(name string) (RaceEvent, bool) {
+	for _, session := range allSessions() {
+		if session.Name == name {
+			return session, true
+		}
+	}
+
+	return RaceEvent{}, false
+}
+
+// parseGmtOffset handles offsets in the format "03:00:00" and "-04:00:00"
+func parseGmtOffset(offset string) (time.Duration, error) {
+	negative := strings.HasPrefix(offset, "-")
+	offset = strings.TrimPrefix(offset, "-")
+
+	var hours, minutes, seconds int
+	if _, err := fmt.Sscanf(offset, "%d:%d:%d", &hours, &minutes, &seconds); err != nil {
+		return 0, err
+	}
+
+	result := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	if negative {
+		result = -result
+	}
+
+	return result, nil
+}
+
+// Zones without daylight saving for the offsets that aren't whole hours, which don't have an Etc zone
+var partHourTimezones = map[time.Duration]string{
+	-(9*time.Hour + 30*time.Minute): "Pacific/Marquesas",
+	3*time.Hour + 30*time.Minute:    "Asia/Tehran",
+	4*time.Hour + 30*time.Minute:    "Asia/Kabul",
+	5*time.Hour + 30*time.Minute:    "Asia/Kolkata",
+	5*time.Hour + 45*time.Minute:    "Asia/Kathmandu",
+	6*time.Hour + 30*time.Minute:    "Asia/Yangon",
+	8*time.Hour + 45*time.Minute:    "Australia/Eucla",
+	9*time.Hour + 30*time.Minute:    "Australia/Darwin",
+}
+
+// fixedTimezone returns the IANA name for a fixed offset from UTC. The Etc zones have the sign inverted.
+func fixedTimezone(offset time.Duration) string {
+	if offset%time.Hour != 0 {
+		return partHourTimezones[offset]
+	}
+
+	hours := int(offset / time.Hour)
+	switch {
+	case hours == 0:
+		return "UTC"
+	case hours > 0:
+		return fmt.Sprintf("Etc/GMT-%d", hours)
+	default:
+		return fmt.Sprintf("Etc/GMT+%d", -hours)
+	}
+}
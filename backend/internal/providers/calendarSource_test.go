@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+func TestRefreshCalendarFromIndex(t *testing.T) {
+	defer ResetCalendar()
+
+	// Stand in for the livetiming static site
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/static")))
+	defer server.Close()
+
+	err := RefreshCalendar(server.URL, 2030)
+	if err == nil || !strings.Contains(err.Error(), "Elsewhere Grand Prix") {
+		t.Fatalf("expected an error for the session with a timezone that has no zone, got: %v", err)
+	}
+
+	meetings := Calendar(CalendarFilter{Year: 2030, IncludeUpcoming: true}, "")
+	if len(meetings) != 4 {
+		t.Fatalf("expected 4 meetings, got %d", len(meetings))
+	}
+
+	australia := meetings[2]
+	if australia.Name != "Australian Grand Prix" || len(australia.Sessions) != 3 {
+		t.Fatalf("unexpected meeting: %+v", australia)
+	}
+
+	// Details not in the index come from the built in calendar
+	if australia.Timezone != "Australia/Melbourne" || australia.Country != "Australia" {
+		t.Errorf("expected details from the known meeting, got timezone %s and country %s", australia.Timezone, australia.Country)
+	}
+
+	// Local start time minus the gmt offset
+	expectedRace := time.Date(2030, 3, 17, 4, 0, 0, 0, time.UTC)
+	if !australia.RaceTime.Equal(expectedRace) {
+		t.Errorf("expected race time %v, got %v", expectedRace, australia.RaceTime)
+	}
+
+	race := australia.Sessions[2]
+	if race.Type != Messages.RaceSession || race.Url != server.URL+"/2030/2030-03-17_Australian_Grand_Prix/2030-03-17_Race/" {
+		t.Errorf("unexpected race session: %+v", race)
+	}
+
+	test := meetings[3]
+	if test.Sessions[0].Type != Messages.PreSeasonSession || test.Timezone != "Etc/GMT-3" {
+		t.Errorf("unexpected test session: %+v", test)
+	}
+
+	// Half hour offsets use a zone with the same offset and an unknown track has no year
+	nowhere := meetings[1]
+	if nowhere.Name != "Nowhere Grand Prix" || nowhere.Timezone != "Asia/Kolkata" || nowhere.TrackYearCreated != 0 {
+		t.Errorf("unexpected meeting: %+v", nowhere)
+	}
+
+	// Sprint qualifying is its own session so it doesn't share the qualifying cache folder
+	china := meetings[0]
+	expectedTypes := []Messages.SessionType{
+		Messages.Practice1Session,
+		Messages.SprintQualifyingSession,
+		Messages.SprintSession,
+		Messages.QualifyingSession,
+		Messages.RaceSession,
+	}
+	if china.Name != "Chinese Grand Prix" || len(china.Sessions) != len(expectedTypes) {
+		t.Fatalf("unexpected meeting: %+v", china)
+	}
+	for x, expected := range expectedTypes {
+		if china.Sessions[x].Type != expected {
+			t.Errorf("expected session %d to be %s, got %s", x, expected, china.Sessions[x].Type)
+		}
+	}
+	if CachePath("cache", china.Sessions[1].Event()) == CachePath("cache", china.Sessions[3].Event()) {
+		t.Error("expected sprint qualifying and qualifying to be cached separately")
+	}
+
+	if sessionType, ok := ParseSessionType("Sprint Qualifying"); !ok || sessionType != Messages.SprintQualifyingSession {
+		t.Errorf("expected sprint qualifying to be a session type that can be asked for, got %s", sessionType)
+	}
+}
+
+func TestLoadCalendarFile(t *testing.T) {
+	defer ResetCalendar()
+
+	err := LoadCalendarFile("testdata/calendar.yaml")
+	if err == nil || !strings.Contains(err.Error(), "Not/AZone") {
+		t.Fatalf("expected an error for the invalid timezone, got: %v", err)
+	}
+
+	meetings := Calendar(CalendarFilter{Year: 2030, IncludeUpcoming: true}, "")
+	if len(meetings) != 1 || len(meetings[0].Sessions) != 1 {
+		t.Fatalf("expected only the valid session to be loaded, got: %+v", meetings)
+	}
+
+	race := meetings[0].Sessions[0]
+	if race.Url != "https://livetiming.formula1.com/static/2030/2030-09-14_Madrid_Grand_Prix/2030-09-14_Race/" {
+		t.Errorf("unexpected url: %s", race.Url)
+	}
+
+	if race.Event().TimeLostInPitlane != time.Second*21 {
+		t.Errorf("unexpected time lost in pitlane: %v", race.Event().TimeLostInPitlane)
+	}
+}
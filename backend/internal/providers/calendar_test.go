@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+func TestFindSessionByYear(t *testing.T) {
+	race := Messages.RaceSession
+
+	older, found := FindSession(2019, "Bahrain Grand Prix", &race)
+	if !found {
+		t.Fatal("expected to find the 2019 Bahrain Grand Prix")
+	}
+	if older.RaceTime.Year() != 2019 || older.Type != Messages.RaceSession {
+		t.Errorf("expected the 2019 race, got %d %s", older.RaceTime.Year(), older.Type)
+	}
+
+	latest, found := FindSession(0, "Bahrain Grand Prix", &race)
+	if !found {
+		t.Fatal("expected to find the latest Bahrain Grand Prix")
+	}
+	if latest.RaceTime.Year() <= 2019 {
+		t.Errorf("expected the most recent race without a year, got %d", latest.RaceTime.Year())
+	}
+
+	if _, found = FindSession(2017, "Bahrain Grand Prix", &race); found {
+		t.Error("expected no session for a year without data")
+	}
+}
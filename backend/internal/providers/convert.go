@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/f1gopher/f1gopherlib/connection"
+)
+
+// convertedCalendarFile is written next to the converted data so the session can be found again with
+// LoadConvertedSessions
+const convertedCalendarFile = "calendar.json"
+
+// ConvertCapture turns a live capture into the cached data for its event so it can be replayed with CreateReplay like
+// any other session. The event is added to the calendar, which means sessions missing from the official archive,
+// such as test days, show up with the other sessions.
+func ConvertCapture(captureFile string, cache string) (RaceEvent, error) {
+	header, hasHeader, err := connection.ReadCaptureHeader(captureFile)
+	if err != nil {
+		return RaceEvent{}, err
+	}
+	if !hasHeader {
+		return RaceEvent{}, fmt.Errorf("capture '%s' has no event info", captureFile)
+	}
+
+	event := captureEvent(header)
+	if err = ValidateRaceEvent(event); err != nil {
+		return RaceEvent{}, err
+	}
+
+	folder := CachePath(cache, event)
+	if err = connection.ConvertCapture(captureFile, folder); err != nil {
+		return RaceEvent{}, err
+	}
+
+	data, err := json.MarshalIndent(calendarFile{Sessions: []calendarFileEntry{{
+		Country:           event.Country,
+		Name:              event.Name,
+		Type:              event.Type.String(),
+		RaceTime:          event.RaceTime,
+		EventTime:         event.EventTime,
+		Timezone:          event.timezone,
+		TrackName:         event.TrackName,
+		TrackYearCreated:  event.TrackYearCreated,
+		TimeLostInPitlane: event.TimeLostInPitlane.String(),
+		Url:               event.urlName,
+	}}}, "", "  ")
+	if err != nil {
+		return RaceEvent{}, err
+	}
+	if err = os.WriteFile(filepath.Join(folder, convertedCalendarFile), data, 0644); err != nil {
+		return RaceEvent{}, err
+	}
+
+	f1Log.Infof("Converted capture '%s' for: %s", captureFile, event.string())
+
+	return event, MergeCalendar([]RaceEvent{event})
+}
+
+// LoadConvertedSessions adds every session converted with ConvertCapture in the cache to the calendar
+func LoadConvertedSessions(cache string) error {
+	var errs []error
+
+	err := filepath.WalkDir(cache, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && entry.Name() == convertedCalendarFile {
+			errs = append(errs, LoadCalendarFile(path))
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+
+	return errors.Join(append(errs, err)...)
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	"github.com/f1gopher/f1gopherlib/internal/parser"
+)
+
+func TestConvertCaptureForReplay(t *testing.T) {
+	defer ResetCalendar()
+
+	start := time.Date(2030, 2, 21, 7, 0, 0, 0, time.UTC)
+	capture := filepath.Join(t.TempDir(), "test.f1cap.gz")
+	cache := t.TempDir()
+
+	writer, err := connection.CreateCapture(capture, connection.CaptureHeader{
+		EventName: "Pre-Season Testing",
+		Session:   Messages.PreSeasonSession,
+		Track:     "Sakhir",
+		Country:   "Bahrain",
+		Timezone:  "Asia/Bahrain",
+		StartTime: start,
+		RaceTime:  start,
+		Url:       LiveTimingStaticUrl + "2030/2030-02-21_Pre-Season_Testing/2030-02-21_Day_1/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captured := []connection.Payload{
+		{Name: connection.CatchupFile, Data: []byte(`{
+			"ExtrapolatedClock":{"Utc":"2030-02-21T06:59:58Z","Remaining":"09:00:00","Extrapolating":false},
+			"RaceControlMessages":{"Messages":[{"Utc":"2030-02-21T06:59:00","Message":"TRACK CLEAR"}]}}`)},
+		{Name: connection.HeartbeatFile, Data: []byte(`{"Utc":"2030-02-21T07:00:00Z"}`), Timestamp: "2030-02-21T07:00:00.250Z"},
+		{Name: connection.ExtrapolatedClockFile, Data: []byte(`{"Utc":"2030-02-21T07:00:00Z","Remaining":"09:00:00","Extrapolating":true}`), Timestamp: "2030-02-21T07:00:00.5Z"},
+		{Name: connection.RaceControlMessagesFile, Data: []byte(`{"Messages":{"1":{"Utc":"2030-02-21T07:00:01","Message":"GREEN LIGHT - PIT EXIT OPEN"}}}`), Timestamp: "2030-02-21T07:00:01.125Z"},
+	}
+	for _, data := range captured {
+		if err = writer.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := ConvertCapture(capture, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsCached(cache, event) {
+		t.Fatal("converted session isn't cached")
+	}
+
+	// The data starts at the catchup clock, two seconds before the first message, with the catchup as the first line
+	content, err := os.ReadFile(filepath.Join(CachePath(cache, event), connection.RaceControlMessagesFile+".jsonStream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "00:00:00.000{") || !strings.HasPrefix(lines[1], "00:00:03.125{") {
+		t.Errorf("unexpected race control messages stream: %q", lines)
+	}
+
+	// The session can be found again after a restart
+	ResetCalendar()
+	if err = LoadConvertedSessions(cache); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, session := range allSessions() {
+		found = found || session.Url() == event.Url()
+	}
+	if !found {
+		t.Fatal("converted session isn't in the calendar")
+	}
+
+	replay, err := CreateReplay(parser.RaceControl, event, cache, flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	// The replay moves on a second at a time
+	messages := replay.RaceControlMessages()
+	for _, expected := range []string{"TRACK CLEAR", "GREEN LIGHT - PIT EXIT OPEN"} {
+		select {
+		case msg := <-messages:
+			if msg.Msg != expected {
+				t.Errorf("expected '%s', got '%s'", expected, msg.Msg)
+			}
+		case <-time.After(time.Second * 10):
+			t.Fatalf("timed out waiting for '%s'", expected)
+		}
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,10 +34,24 @@ type F1Lib interface {
 	Time() <-chan Messages.EventTime
 	Radio() <-chan Messages.Radio
 	Drivers() <-chan Messages.Drivers
+	CarState() <-chan Messages.CarState
+	// Every message in the order it was sent
+	Envelopes() <-chan Messages.Envelope
+	// Changes to the connection for live sessions, replays never send anything
+	ConnectionState() <-chan Messages.ConnectionState
+
+	// Record writes every message from now on to a recording file until the session is closed
+	Record(file string) error
+
+	// Subscribe gives a new stream of messages. Any number of consumers can subscribe and each has its own buffer and
+	// delivery policies. The channel getters above are a single shared subscription for each channel.
+	Subscribe(filter SubscriptionFilter) Subscription
 
 	Data() any
 
 	SelectTelemetrySources(drivers []int)
+	// SendStoppedCarTelemetry sends telemetry when the RPM is 0, which is skipped by default
+	SendStoppedCarTelemetry(send bool)
 
 	IncrementLap()
 	IncrementTime(duration time.Duration)
@@ -45,6 +59,14 @@ type F1Lib interface {
 	TogglePause()
 	IsPaused() bool
 
+	// SetDeliveryPolicy changes what happens to messages for a channel when the consumer isn't keeping up
+	SetDeliveryPolicy(channel flowControl.Channel, policy flowControl.DeliveryPolicy)
+	// SetBlockTimeout opts a blocking channel in to dropping messages the consumer doesn't read in time, zero waits
+	// forever which is the default
+	SetBlockTimeout(channel flowControl.Channel, timeout time.Duration)
+	// DroppedMessages is the number of messages dropped or coalesced for each channel
+	DroppedMessages() map[flowControl.Channel]uint64
+
 	Close()
 }
 
@@ -60,9 +82,13 @@ type f1lib struct {
 	timeLostInPitlane time.Duration
 
 	connection   connection.Connection
+	timeline     timeline
 	dataHandler  *parser.Parser
 	replayTiming flowControl.Flow
 
+	// Kept so it can be set before the parser is created
+	sendStoppedCarTelemetry bool
+
 	weather             chan Messages.Weather
 	raceControlMessages chan Messages.RaceControlMessage
 	timing              chan Messages.Timing
@@ -72,14 +98,21 @@ type f1lib struct {
 	eventTime           chan Messages.EventTime
 	radio               chan Messages.Radio
 	drivers             chan Messages.Drivers
+	carState            chan Messages.CarState
+	envelopes           chan Messages.Envelope
+	connectionStates    <-chan Messages.ConnectionState
+
+	subscribers *subscribers
 
 	ctxShutdown context.CancelFunc
 	ctx         context.Context
 	wg          sync.WaitGroup
+
+	// Recordings finish writing after everything else has stopped
+	recorders sync.WaitGroup
 }
 
-// TODO - do we ned different sizes for straight through and realtime flow control? Straight through will need
-// bigger buffers but realtime only needs to hold abotu 5 mins of data
+// How many messages can wait for the consumer before the delivery policy for the channel applies
 const weatherChannelSize = 100
 const rcmChannelSize = 100
 const timingChannelSize = 10000
@@ -89,13 +122,48 @@ const locationChannelSize = 1000
 const eventTimeChannelSize = 10
 const radioChannelSize = 100
 const driversChannelSize = 100
+const carStateChannelSize = 1000
+const envelopeChannelSize = 10000
 
 var f1Log = f1log.CreateLog()
 
+// Downloads the archived data and assets for every session, replaced by SetHttpOptions
+var httpClientLock sync.RWMutex
+var sharedHttpClient = connection.CreateHttpClient(connection.HttpOptions{}, f1Log)
+
+// timeline is the part of a data source that moves through the session
+type timeline interface {
+	IncrementTime(amount time.Duration)
+	JumpToStart() time.Time
+}
+
+// lapTimeline is a timeline that can also skip a lap
+type lapTimeline interface {
+	timeline
+	IncrementLap()
+}
+
 func SetLogOutput(w io.Writer) {
 	f1Log.SetLogOutput(w)
 }
 
+// SetHttpOptions changes how the archived data and assets are downloaded. Sessions already created keep the old
+// settings.
+func SetHttpOptions(options connection.HttpOptions) {
+	client := connection.CreateHttpClient(options, f1Log)
+
+	httpClientLock.Lock()
+	defer httpClientLock.Unlock()
+	sharedHttpClient = client
+}
+
+// httpClient is the client for new sessions
+func httpClient() *connection.HttpClient {
+	httpClientLock.RLock()
+	defer httpClientLock.RUnlock()
+	return sharedHttpClient
+}
+
 func CreateRaceEvent(
 	country string,
 	raceTime time.Time,
@@ -124,12 +192,18 @@ func CreateRaceEvent(
 		sessionName = "Race"
 	case Messages.PreSeasonSession:
 		sessionName = "Test"
+	case Messages.SprintQualifyingSession:
+		sessionName = "Sprint_Qualifying"
+		if raceTime.Year() == 2023 {
+			sessionName = "Sprint_Shootout"
+		}
 	default:
 		panic("Unhandled session type: " + sessionType.String())
 	}
 
 	urlName = fmt.Sprintf(
-		"https://livetiming.formula1.com/static/%d/%d-%02d-%02d_%s_Grand_Prix/%d-%02d-%02d_%s/",
+		"%s%d/%d-%02d-%02d_%s_Grand_Prix/%d-%02d-%02d_%s/",
+		LiveTimingStaticUrl,
 		raceTime.Year(),
 		raceTime.Year(),
 		raceTime.Month(),
@@ -183,21 +257,38 @@ func (r *RaceEvent) Url() string {
 	return r.urlName
 }
 
-func CreateLive(requestedData parser.DataSource, archive string, cache string) (F1Lib, error) {
-
-	// TODO - validate path
-	// TODO - create archive folder
-
-	currentEvent, exists := liveEvent()
-
-	// No event happening or about to happen so nothing we can do
-	if !exists {
-		return nil, errors.New("no live event currently happening")
+func (r *RaceEvent) captureHeader() connection.CaptureHeader {
+	return connection.CaptureHeader{
+		EventName:         r.Name,
+		Session:           r.Type,
+		Track:             r.TrackName,
+		TrackYear:         r.TrackYearCreated,
+		Country:           r.Country,
+		Timezone:          r.timezone,
+		StartTime:         r.EventTime,
+		RaceTime:          r.RaceTime,
+		TimeLostInPitlane: r.TimeLostInPitlane,
+		Url:               r.urlName,
 	}
+}
 
-	f1Log.Infof("Creating live session for: %v", currentEvent.string())
+func captureEvent(header connection.CaptureHeader) RaceEvent {
+	return RaceEvent{
+		Country:           header.Country,
+		RaceTime:          header.RaceTime,
+		EventTime:         header.StartTime,
+		Type:              header.Session,
+		Name:              header.EventName,
+		timezone:          header.Timezone,
+		TrackName:         header.Track,
+		TrackYearCreated:  header.TrackYear,
+		TimeLostInPitlane: header.TimeLostInPitlane,
+		urlName:           header.Url,
+	}
+}
 
-	data := f1lib{
+func createF1lib(event RaceEvent) *f1lib {
+	data := &f1lib{
 		weather:             make(chan Messages.Weather, weatherChannelSize),
 		raceControlMessages: make(chan Messages.RaceControlMessage, rcmChannelSize),
 		timing:              make(chan Messages.Timing, timingChannelSize),
@@ -207,23 +298,63 @@ func CreateLive(requestedData parser.DataSource, archive string, cache string) (
 		eventTime:           make(chan Messages.EventTime, eventTimeChannelSize),
 		radio:               make(chan Messages.Radio, radioChannelSize),
 		drivers:             make(chan Messages.Drivers, driversChannelSize),
-
-		archive:           archive,
-		session:           currentEvent.Type,
-		name:              currentEvent.Name,
-		timezone:          currentEvent.Timezone(),
-		sessionStart:      currentEvent.EventTime,
-		track:             currentEvent.TrackName,
-		trackYear:         currentEvent.TrackYearCreated,
-		timeLostInPitlane: currentEvent.TimeLostInPitlane,
+		carState:            make(chan Messages.CarState, carStateChannelSize),
+		envelopes:           make(chan Messages.Envelope, envelopeChannelSize),
+		session:             event.Type,
+		name:                event.Name,
+		timezone:            event.Timezone(),
+		sessionStart:        event.EventTime,
+		track:               event.TrackName,
+		trackYear:           event.TrackYearCreated,
+		timeLostInPitlane:   event.TimeLostInPitlane,
 	}
 	data.ctx, data.ctxShutdown = context.WithCancel(context.Background())
+	data.subscribers = createSubscribers(data.ctx)
+
+	return data
+}
+
+// createFlowControl sends the parsed messages to the output channels with the flow type
+func (f *f1lib) createFlowControl(dataFlow flowControl.FlowType) {
+	f.replayTiming = flowControl.CreateFlowControl(
+		f.ctx,
+		&f.wg,
+		dataFlow,
+		f.weather,
+		f.raceControlMessages,
+		f.timing,
+		f.event,
+		f.telemetry,
+		f.location,
+		f.eventTime,
+		f.radio,
+		f.drivers,
+		f.carState,
+		f.envelopes)
+}
+
+func CreateLive(requestedData parser.DataSource, archive string, cache string) (F1Lib, error) {
+
+	// TODO - validate path
+	// TODO - create archive folder
+
+	currentEvent, exists := liveEvent()
+
+	// No event happening or about to happen so nothing we can do
+	if !exists {
+		return nil, errors.New("no live event currently happening")
+	}
+
+	f1Log.Infof("Creating live session for: %v", currentEvent.string())
+
+	data := createF1lib(currentEvent)
+	data.archive = archive
 
 	err := data.connectLive(requestedData, archive, currentEvent, cache)
 	if err != nil {
 		return nil, err
 	}
-	return &data, nil
+	return data, nil
 }
 
 func CreateDebugReplay(
@@ -231,36 +362,49 @@ func CreateDebugReplay(
 	replayFile string,
 	dataFlow flowControl.FlowType) (F1Lib, error) {
 
-	// TODO - read event info from debug file
+	header, hasHeader, err := connection.ReadCaptureHeader(replayFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archives from before captures had a header don't say which event they are
 	event := RaceEvent{}
+	if hasHeader {
+		event = captureEvent(header)
+	} else {
+		f1Log.Warnf("Live replay file '%s' has no event info", replayFile)
+	}
 
 	f1Log.Infof("Creating live replay session for: %v", event.string())
 
-	data := f1lib{
-		weather:             make(chan Messages.Weather, weatherChannelSize),
-		raceControlMessages: make(chan Messages.RaceControlMessage, rcmChannelSize),
-		timing:              make(chan Messages.Timing, timingChannelSize),
-		event:               make(chan Messages.Event, eventChannelSize),
-		telemetry:           make(chan Messages.Telemetry, telemetryChannelSize),
-		location:            make(chan Messages.Location, locationChannelSize),
-		eventTime:           make(chan Messages.EventTime, eventTimeChannelSize),
-		radio:               make(chan Messages.Radio, radioChannelSize),
-		drivers:             make(chan Messages.Drivers, driversChannelSize),
-		session:             event.Type,
-		name:                event.Name,
-		timezone:            event.Timezone(),
-		sessionStart:        event.EventTime,
-		track:               event.TrackName,
-		trackYear:           event.TrackYearCreated,
-		timeLostInPitlane:   event.TimeLostInPitlane,
+	data := createF1lib(event)
+
+	err = data.connectDebugReplay(requestedData, replayFile, event, dataFlow)
+	if err != nil {
+		return nil, err
 	}
-	data.ctx, data.ctxShutdown = context.WithCancel(context.Background())
+	return data, nil
+}
+
+// CreateFromUrl plays the feeds from another source, such as a local folder of jsonStream files (file://), a mirror of
+// the archive (https://) or a stand in for the live timing (ws://). The event describes the session being played. Use
+// the realtime data flow for live sources.
+func CreateFromUrl(
+	requestedData parser.DataSource,
+	source string,
+	event RaceEvent,
+	cache string,
+	dataFlow flowControl.FlowType) (F1Lib, error) {
+
+	f1Log.Infof("Creating session from '%s' for: %v", source, event.string())
+
+	data := createF1lib(event)
 
-	err := data.connectDebugReplay(requestedData, replayFile, event, dataFlow)
+	err := data.connectSource(requestedData, source, event, cache, dataFlow)
 	if err != nil {
 		return nil, err
 	}
-	return &data, nil
+	return data, nil
 }
 
 func CreateReplay(
@@ -271,67 +415,43 @@ func CreateReplay(
 
 	f1Log.Infof("Creating replay session for: %v", event.string())
 
-	data := f1lib{
-		weather:             make(chan Messages.Weather, weatherChannelSize),
-		raceControlMessages: make(chan Messages.RaceControlMessage, rcmChannelSize),
-		timing:              make(chan Messages.Timing, timingChannelSize),
-		event:               make(chan Messages.Event, eventChannelSize),
-		telemetry:           make(chan Messages.Telemetry, telemetryChannelSize),
-		location:            make(chan Messages.Location, locationChannelSize),
-		eventTime:           make(chan Messages.EventTime, eventTimeChannelSize),
-		radio:               make(chan Messages.Radio, radioChannelSize),
-		drivers:             make(chan Messages.Drivers, driversChannelSize),
-		session:             event.Type,
-		name:                event.Name,
-		timezone:            event.Timezone(),
-		sessionStart:        event.EventTime,
-		track:               event.TrackName,
-		trackYear:           event.TrackYearCreated,
-		timeLostInPitlane:   event.TimeLostInPitlane,
-	}
-	data.ctx, data.ctxShutdown = context.WithCancel(context.Background())
+	data := createF1lib(event)
 
-	err := data.connectReplay(requestedData, event, cache, dataFlow)
+	err := data.connectSource(requestedData, event.Url(), event, cache, dataFlow)
 	if err != nil {
 		return nil, err
 	}
-	return &data, nil
+	return data, nil
 }
 
 func (f *f1lib) connectLive(requestedData parser.DataSource, archiveFile string, event RaceEvent, cache string) error {
 
-	cache = f.cachePath(cache, event)
+	cache = CachePath(cache, event)
 
 	if len(archiveFile) == 0 {
 		f.connection = connection.CreateLive(f.ctx, &f.wg, f1Log)
 	} else {
 		var connErr error
-		f.connection, connErr = connection.CreateArchivingLive(f.ctx, &f.wg, f1Log, archiveFile)
+		f.connection, connErr = connection.CreateArchivingLive(f.ctx, &f.wg, f1Log, archiveFile, event.captureHeader())
 		if connErr != nil {
 			return connErr
 		}
 	}
 
+	if reporter, ok := f.connection.(connection.StateReporter); ok {
+		f.connectionStates = reporter.States()
+	}
+
 	err, dataChannel := f.connection.Connect()
 	if err != nil {
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		flowControl.Realtime,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
+	f.timeline = f.connection
 
-	assetStore := connection.CreateAssetStore(event.Url(), cache, f1Log)
+	f.createFlowControl(flowControl.Realtime)
+
+	assetStore := connection.CreateAssetStore(f.ctx, event.Url(), cache, httpClient(), f1Log)
 
 	f.dataHandler = parser.Create(
 		f.ctx,
@@ -344,8 +464,10 @@ func (f *f1lib) connectLive(requestedData parser.DataSource, archiveFile string,
 		f1Log,
 		event.Timezone())
 
+	f.dataHandler.SendStoppedCarTelemetry(f.sendStoppedCarTelemetry)
 	go f.dataHandler.Process()
 	go f.replayTiming.Run()
+	f.publish()
 
 	return nil
 }
@@ -363,22 +485,12 @@ func (f *f1lib) connectDebugReplay(
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		dataFlow,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
+	f.timeline = f.connection
+
+	f.createFlowControl(dataFlow)
 
 	// Don't use a cache for debug replays because we don't always know the event yet to give it a useful folder name
-	assetStore := connection.CreateAssetStore(event.Url(), "", f1Log)
+	assetStore := connection.CreateAssetStore(f.ctx, event.Url(), "", httpClient(), f1Log)
 
 	f.dataHandler = parser.Create(
 		f.ctx,
@@ -391,43 +503,50 @@ func (f *f1lib) connectDebugReplay(
 		f1Log,
 		event.Timezone())
 
+	f.dataHandler.SendStoppedCarTelemetry(f.sendStoppedCarTelemetry)
 	go f.dataHandler.Process()
 	go f.replayTiming.Run()
+	f.publish()
 
 	return nil
 }
 
-func (f *f1lib) connectReplay(
+// connectSource reads the feeds from wherever the url points, see connection.CreateFromUrl
+func (f *f1lib) connectSource(
 	requestedData parser.DataSource,
+	source string,
 	event RaceEvent,
 	cache string,
 	dataFlow flowControl.FlowType) error {
 
-	url := event.Url()
-	cache = f.cachePath(cache, event)
+	cache = CachePath(cache, event)
 
-	f.connection = connection.CreateReplay(f.ctx, &f.wg, f1Log, url, event.Type, event.RaceTime.Year(), cache)
-	err, dataChannel := f.connection.Connect()
+	var err error
+	f.connection, err = connection.CreateFromUrl(f.ctx, &f.wg, f1Log, httpClient(), source, event.Type, event.RaceTime.Year(), cache)
+	if err != nil {
+		return err
+	}
+
+	// A mirror of the archive has the team radio with the data, anything else gets it from the event
+	assetUrl := event.Url()
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		assetUrl = strings.TrimSuffix(source, "/") + "/"
+	}
+
+	if reporter, ok := f.connection.(connection.StateReporter); ok {
+		f.connectionStates = reporter.States()
+	}
 
+	err, dataChannel := f.connection.Connect()
 	if err != nil {
 		return err
 	}
 
-	f.replayTiming = flowControl.CreateFlowControl(
-		f.ctx,
-		&f.wg,
-		dataFlow,
-		f.weather,
-		f.raceControlMessages,
-		f.timing,
-		f.event,
-		f.telemetry,
-		f.location,
-		f.eventTime,
-		f.radio,
-		f.drivers)
+	f.timeline = f.connection
 
-	assetStore := connection.CreateAssetStore(event.Url(), cache, f1Log)
+	f.createFlowControl(dataFlow)
+
+	assetStore := connection.CreateAssetStore(f.ctx, assetUrl, cache, httpClient(), f1Log)
 
 	f.dataHandler = parser.Create(
 		f.ctx,
@@ -440,16 +559,14 @@ func (f *f1lib) connectReplay(
 		f1Log,
 		event.Timezone())
 
+	f.dataHandler.SendStoppedCarTelemetry(f.sendStoppedCarTelemetry)
 	go f.dataHandler.Process()
 	go f.replayTiming.Run()
+	f.publish()
 
 	return nil
 }
 
-func (f *f1lib) cachePath(cache string, event RaceEvent) string {
-	return filepath.Join(cache, fmt.Sprintf("%d", event.RaceTime.Year()), fmt.Sprintf("%s_%s", event.RaceTime.Format("2006-01-02"), event.Name), event.Type.String())
-}
-
 func (f *f1lib) Session() Messages.SessionType {
 	return f.session
 }
@@ -479,39 +596,85 @@ func (f *f1lib) TimeLostInPitlane() time.Duration {
 }
 
 func (f *f1lib) Weather() <-chan Messages.Weather {
-	return f.weather
+	return f.legacySubscription(flowControl.WeatherChannel, weatherChannelSize).Weather()
 }
 
 func (f *f1lib) RaceControlMessages() <-chan Messages.RaceControlMessage {
-	return f.raceControlMessages
+	return f.legacySubscription(flowControl.RaceControlChannel, rcmChannelSize).RaceControlMessages()
 }
 
 func (f *f1lib) Timing() <-chan Messages.Timing {
-	return f.timing
+	return f.legacySubscription(flowControl.TimingChannel, timingChannelSize).Timing()
 }
 
 func (f *f1lib) Event() <-chan Messages.Event {
-	return f.event
+	return f.legacySubscription(flowControl.EventChannel, eventChannelSize).Event()
 }
 
 func (f *f1lib) Telemetry() <-chan Messages.Telemetry {
-	return f.telemetry
+	return f.legacySubscription(flowControl.TelemetryChannel, telemetryChannelSize).Telemetry()
 }
 
 func (f *f1lib) Location() <-chan Messages.Location {
-	return f.location
+	return f.legacySubscription(flowControl.LocationChannel, locationChannelSize).Location()
 }
 
 func (f *f1lib) Time() <-chan Messages.EventTime {
-	return f.eventTime
+	return f.legacySubscription(flowControl.EventTimeChannel, eventTimeChannelSize).Time()
 }
 
 func (f *f1lib) Radio() <-chan Messages.Radio {
-	return f.radio
+	return f.legacySubscription(flowControl.RadioChannel, radioChannelSize).Radio()
 }
 
 func (f *f1lib) Drivers() <-chan Messages.Drivers {
-	return f.drivers
+	return f.legacySubscription(flowControl.DriversChannel, driversChannelSize).Drivers()
+}
+
+func (f *f1lib) CarState() <-chan Messages.CarState {
+	return f.legacySubscription(flowControl.CarStateChannel, carStateChannelSize).CarState()
+}
+
+// legacySubscription is the subscription behind a channel getter, delivered to like the flow control's channel
+func (f *f1lib) legacySubscription(channel flowControl.Channel, bufferSize int) *subscription {
+	policy := flowControl.Block
+	var blockTimeout time.Duration
+	if f.replayTiming != nil {
+		policy = f.replayTiming.DeliveryPolicy(channel)
+		blockTimeout = f.replayTiming.BlockTimeout(channel)
+	}
+
+	return f.subscribers.legacySubscription(channel, bufferSize, policy, blockTimeout)
+}
+
+func (f *f1lib) Subscribe(filter SubscriptionFilter) Subscription {
+	return f.subscribers.subscribe(filter)
+}
+
+// publish starts sending the flow control output to the subscribers
+func (f *f1lib) publish() {
+	f.wg.Add(1)
+	go f.subscribers.run(
+		&f.wg,
+		f.weather,
+		f.raceControlMessages,
+		f.timing,
+		f.event,
+		f.telemetry,
+		f.location,
+		f.eventTime,
+		f.radio,
+		f.drivers,
+		f.carState,
+		f.envelopes)
+}
+
+func (f *f1lib) Envelopes() <-chan Messages.Envelope {
+	return f.legacySubscription(flowControl.EnvelopeChannel, envelopeChannelSize).Envelopes()
+}
+
+func (f *f1lib) ConnectionState() <-chan Messages.ConnectionState {
+	return f.connectionStates
 }
 
 func (f *f1lib) Data() any {
@@ -529,27 +692,45 @@ func (f *f1lib) Data() any {
 		sessionStart:      f.sessionStart,
 		location:          f.location,
 		eventTime:         f.eventTime,
+		carState:          f.carState,
+		envelopes:         f.envelopes,
+		// The channel getters read from the subscriptions
+		subscribers:  f.subscribers,
+		replayTiming: f.replayTiming,
 	}
 }
 
 func (f *f1lib) SelectTelemetrySources(drivers []int) {
-	f.dataHandler.SelectTelemetrySources(drivers)
+	// Recordings only have the telemetry that was selected when they were made
+	if f.dataHandler != nil {
+		f.dataHandler.SelectTelemetrySources(drivers)
+	}
+}
+
+func (f *f1lib) SendStoppedCarTelemetry(send bool) {
+	f.sendStoppedCarTelemetry = send
+	if f.dataHandler != nil {
+		f.dataHandler.SendStoppedCarTelemetry(send)
+	}
 }
 
 func (f *f1lib) IncrementLap() {
 	// Only makes sense for races
 	if f.session == Messages.RaceSession || f.session == Messages.SprintSession {
+		if laps, ok := f.timeline.(lapTimeline); ok {
+			laps.IncrementLap()
+		}
 		f.replayTiming.IncrementLap()
 	}
 }
 
 func (f *f1lib) IncrementTime(duration time.Duration) {
-	f.connection.IncrementTime(duration)
+	f.timeline.IncrementTime(duration)
 	f.replayTiming.IncrementTime(duration)
 }
 
 func (f *f1lib) SkipToSessionStart() {
-	sessionStart := f.connection.JumpToStart()
+	sessionStart := f.timeline.JumpToStart()
 	fmt.Println("sessionStart", sessionStart)
 	if !sessionStart.IsZero() {
 		f.replayTiming.SkipToSessionStart(sessionStart)
@@ -564,6 +745,27 @@ func (f *f1lib) IsPaused() bool {
 	return f.replayTiming.IsPaused()
 }
 
+// SetDeliveryPolicy also changes the channel getter's subscription so the policy applies however far behind the
+// consumer is
+func (f *f1lib) SetDeliveryPolicy(channel flowControl.Channel, policy flowControl.DeliveryPolicy) {
+	f.replayTiming.SetDeliveryPolicy(channel, policy)
+	f.subscribers.setLegacyDelivery(channel, policy, f.replayTiming.BlockTimeout(channel))
+}
+
+func (f *f1lib) SetBlockTimeout(channel flowControl.Channel, timeout time.Duration) {
+	f.replayTiming.SetBlockTimeout(channel, timeout)
+	f.subscribers.setLegacyDelivery(channel, f.replayTiming.DeliveryPolicy(channel), timeout)
+}
+
+// DroppedMessages includes the messages dropped by the channel getters' subscriptions
+func (f *f1lib) DroppedMessages() map[flowControl.Channel]uint64 {
+	result := f.replayTiming.Dropped()
+	for channel, count := range f.subscribers.legacyDropped() {
+		result[channel] += count
+	}
+	return result
+}
+
 func (f *f1lib) Close() {
 	f.name = ""
 	f.track = ""
@@ -572,8 +774,12 @@ func (f *f1lib) Close() {
 	f.wg.Wait()
 
 	f.connection = nil
+	f.timeline = nil
 	f.dataHandler = nil
 
+	f.subscribers.closeAll()
+	f.recorders.Wait()
+
 	close(f.weather)
 	close(f.raceControlMessages)
 	close(f.timing)
@@ -583,4 +789,6 @@ func (f *f1lib) Close() {
 	close(f.eventTime)
 	close(f.radio)
 	close(f.drivers)
+	close(f.carState)
+	close(f.envelopes)
 }
@@ -9,23 +9,8 @@ import (
 func RaceHistory() []RaceEvent {
 	result := make([]RaceEvent, 0)
 
-	for _, session := range sessionHistory {
-		sessionEnd := session.EventTime
-		switch session.Type {
-		case Messages.Practice1Session, Messages.Practice2Session, Messages.Practice3Session:
-			sessionEnd = sessionEnd.Add(time.Hour * 1)
-
-		case Messages.QualifyingSession:
-			sessionEnd = sessionEnd.Add(time.Hour * 1)
-
-		case Messages.SprintSession:
-			sessionEnd = sessionEnd.Add(time.Hour * 1)
-
-		case Messages.RaceSession:
-			sessionEnd = sessionEnd.Add(time.Hour * 3)
-		}
-
-		if sessionEnd.Before(time.Now()) {
+	for _, session := range allSessions() {
+		if sessionEnd(session).Before(time.Now()) {
 			result = append(result, session)
 		}
 	}
@@ -34,7 +19,7 @@ func RaceHistory() []RaceEvent {
 }
 
 func HappeningSessions() (liveSession RaceEvent, nextSession RaceEvent, hasLiveSession bool, hasNextSession bool) {
-	all := sessionHistory
+	all := allSessions()
 	utcNow := time.Now().UTC()
 
 	for x := 0; x < len(all); x++ {
@@ -52,7 +37,8 @@ func HappeningSessions() (liveSession RaceEvent, nextSession RaceEvent, hasLiveS
 					// Usually 60 mins but tire tests are 90 so cover both since it won't overlap with anything else
 					duringEvent = utcNow.Before(all[x].EventTime.Add(time.Hour * 2))
 
-				case Messages.QualifyingSession, Messages.SprintSession, Messages.RaceSession, Messages.PreSeasonSession:
+				case Messages.QualifyingSession, Messages.SprintQualifyingSession, Messages.SprintSession, Messages.RaceSession,
+					Messages.PreSeasonSession:
 					// Last events in the day so just assume it's that event
 					duringEvent = true
 
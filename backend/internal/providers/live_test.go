@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	"github.com/f1gopher/f1gopherlib/internal/parser"
+)
+
+func TestReconnectCatchupIsNotRepeated(t *testing.T) {
+	capture := filepath.Join(t.TempDir(), "reconnect.f1cap")
+
+	writer, err := connection.CreateCapture(capture, connection.CaptureHeader{
+		EventName: "Bahrain Grand Prix",
+		Session:   Messages.RaceSession,
+		Timezone:  "Asia/Bahrain",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captured := []connection.Payload{
+		{Name: connection.CatchupFile, Data: []byte(`{"RaceControlMessages":{"Messages":[
+			{"Utc":"2030-03-02T15:00:00","Message":"TRACK CLEAR"}]}}`)},
+		{Name: connection.RaceControlMessagesFile, Data: []byte(`{"Messages":{"1":
+			{"Utc":"2030-03-02T15:01:00","Message":"GREEN LIGHT - PIT EXIT OPEN"}}}`), Timestamp: "2030-03-02T15:01:00Z"},
+		// The connection dropped and this is the catchup after reconnecting
+		{Name: connection.CatchupFile, Data: []byte(`{"RaceControlMessages":{"Messages":[
+			{"Utc":"2030-03-02T15:00:00","Message":"TRACK CLEAR"},
+			{"Utc":"2030-03-02T15:01:00","Message":"GREEN LIGHT - PIT EXIT OPEN"},
+			{"Utc":"2030-03-02T15:02:00","Message":"DRS ENABLED"}]}}`)},
+		{Name: connection.RaceControlMessagesFile, Data: []byte(`{"Messages":{"4":
+			{"Utc":"2030-03-02T15:03:00","Message":"DRS DISABLED"}}}`), Timestamp: "2030-03-02T15:03:00Z"},
+	}
+	for _, data := range captured {
+		if err = writer.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := CreateDebugReplay(parser.RaceControl, capture, flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	if replay.Name() != "Bahrain Grand Prix" || replay.Session() != Messages.RaceSession {
+		t.Errorf("unexpected event from capture: %s - %s", replay.Name(), replay.Session())
+	}
+
+	messages := replay.RaceControlMessages()
+	for _, expected := range []string{"TRACK CLEAR", "GREEN LIGHT - PIT EXIT OPEN", "DRS ENABLED", "DRS DISABLED"} {
+		if msg := receive(t, messages); msg.Msg != expected {
+			t.Errorf("expected '%s', got '%s'", expected, msg.Msg)
+		}
+	}
+
+	select {
+	case msg := <-messages:
+		t.Errorf("unexpected repeated message: %s", msg.Msg)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	"github.com/f1gopher/f1gopherlib/recording"
+)
+
+// CreateRecordingReplay plays back a recording of the parsed messages made with Record. Using straight through flow
+// gives exactly the same messages in the same order every time.
+func CreateRecordingReplay(
+	file string,
+	dataFlow flowControl.FlowType) (F1Lib, error) {
+
+	reader, err := recording.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	info := reader.Info()
+
+	f1Log.Infof("Creating recording replay for: %s - %s", info.Name, info.Session.String())
+
+	data := createF1lib(RaceEvent{
+		Type:              info.Session,
+		Name:              info.Name,
+		timezone:          info.Timezone,
+		EventTime:         info.SessionStart,
+		TrackName:         info.Track,
+		TrackYearCreated:  info.TrackYear,
+		TimeLostInPitlane: info.TimeLostInPitlane,
+	})
+	// Recordings without a timezone we know are in UTC
+	if data.timezone == nil {
+		data.timezone = time.UTC
+	}
+
+	data.connectRecording(reader, dataFlow)
+	return data, nil
+}
+
+func (f *f1lib) connectRecording(reader *recording.Reader, dataFlow flowControl.FlowType) {
+	// Straight through flow control doesn't skip so the recording does it by seeking
+	source := recording.CreateSource(f.ctx, &f.wg, f1Log, reader, dataFlow == flowControl.StraightThrough)
+	f.timeline = source
+	_, dataChannel := source.Connect()
+
+	f.createFlowControl(dataFlow)
+
+	f.wg.Add(1)
+	go f.playRecording(dataChannel)
+	go f.replayTiming.Run()
+	f.publish()
+}
+
+// playRecording gives the recorded messages to the flow control in the order they were recorded
+func (f *f1lib) playRecording(envelopes <-chan Messages.Envelope) {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+
+		case envelope, ok := <-envelopes:
+			if !ok {
+				return
+			}
+
+			switch msg := envelope.Payload.(type) {
+			case Messages.Weather:
+				f.replayTiming.AddWeather(msg)
+			case Messages.RaceControlMessage:
+				f.replayTiming.AddRaceControlMessage(msg)
+			case Messages.Timing:
+				f.replayTiming.AddTiming(msg)
+			case Messages.Event:
+				f.replayTiming.AddEvent(msg)
+			case Messages.Telemetry:
+				f.replayTiming.AddTelemetry(msg)
+			case Messages.Location:
+				f.replayTiming.AddLocation(msg)
+			case Messages.Radio:
+				f.replayTiming.AddRadio(msg)
+			case Messages.Drivers:
+				f.replayTiming.AddDrivers(msg)
+			case Messages.CarState:
+				f.replayTiming.AddCarState(msg)
+			case Messages.EventTime:
+				// The flow control makes its own times as it plays the messages
+			}
+		}
+	}
+}
+
+func (f *f1lib) Record(file string) error {
+	timezone := ""
+	if f.timezone != nil {
+		timezone = f.timezone.String()
+	}
+
+	writer, err := recording.Create(file, recording.Info{
+		Name:              f.name,
+		Session:           f.session,
+		Track:             f.track,
+		TrackYear:         f.trackYear,
+		Timezone:          timezone,
+		SessionStart:      f.sessionStart,
+		TimeLostInPitlane: f.timeLostInPitlane,
+	})
+	if err != nil {
+		return err
+	}
+
+	sub := f.Subscribe(SubscriptionFilter{
+		Channels:   []flowControl.Channel{flowControl.EnvelopeChannel},
+		BufferSize: envelopeChannelSize,
+	})
+
+	f.recorders.Add(1)
+	go func() {
+		defer f.recorders.Done()
+
+		// Runs until the subscription is closed with the session
+		if err := writer.WriteAll(sub.Envelopes()); err != nil {
+			f1Log.Errorf("Writing recording '%s': %v", file, err)
+			sub.Close()
+		}
+
+		if err := writer.Close(); err != nil {
+			f1Log.Errorf("Closing recording '%s': %v", file, err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	"github.com/f1gopher/f1gopherlib/recording"
+)
+
+func TestRecordingReplayIsDeterministic(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "race.f1rec")
+
+	start := time.Date(2030, 3, 17, 4, 0, 0, 0, time.UTC)
+	writer, err := recording.Create(file, recording.Info{
+		Name:     "Australian Grand Prix",
+		Session:  Messages.RaceSession,
+		Timezone: "Australia/Melbourne",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := []Messages.Payload{
+		Messages.Drivers{Timestamp: start, Drivers: []Messages.DriverInfo{{Number: 44}}},
+		Messages.Event{Timestamp: start, CurrentLap: 1, Status: Messages.Started},
+		Messages.RaceControlMessage{Timestamp: start.Add(time.Second), Msg: "BLUE FLAG FOR CAR 44"},
+		Messages.Timing{Timestamp: start.Add(time.Second), Number: 44},
+		Messages.Weather{Timestamp: start.Add(time.Second * 2), AirTemp: 21},
+		Messages.Event{Timestamp: start.Add(time.Second * 3), CurrentLap: 2, Status: Messages.Started},
+	}
+	for x, payload := range recorded {
+		if err = writer.Write(Messages.CreateEnvelope(payload, uint64(x+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flow control adds the event times so the order is the same every time it is played
+	expected := []Messages.Kind{
+		Messages.DriversKind,
+		Messages.EventKind,
+		Messages.EventTimeKind,
+		Messages.RaceControlMessageKind,
+		Messages.TimingKind,
+		Messages.WeatherKind,
+		Messages.EventKind,
+		Messages.EventTimeKind,
+	}
+
+	for run := 0; run < 2; run++ {
+		replay, err := CreateRecordingReplay(file, flowControl.StraightThrough)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if replay.Name() != "Australian Grand Prix" || replay.CircuitTimezone().String() != "Australia/Melbourne" {
+			t.Errorf("unexpected session: %s in %s", replay.Name(), replay.CircuitTimezone())
+		}
+
+		envelopes := replay.Envelopes()
+		for x, kind := range expected {
+			msg := receive(t, envelopes)
+			if msg.Kind != kind || msg.Sequence != uint64(x+1) {
+				t.Errorf("run %d message %d: expected %s, got %s with sequence %d", run, x, kind, msg.Kind, msg.Sequence)
+			}
+		}
+
+		replay.Close()
+	}
+}
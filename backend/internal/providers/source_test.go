@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/connection"
+	"github.com/f1gopher/f1gopherlib/connection/livetest"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	"github.com/f1gopher/f1gopherlib/internal/parser"
+)
+
+var sourceEvent = RaceEvent{
+	Name:      "Madrid Grand Prix",
+	Type:      Messages.RaceSession,
+	timezone:  "Europe/Madrid",
+	RaceTime:  time.Date(2030, 9, 14, 13, 0, 0, 0, time.UTC),
+	EventTime: time.Date(2030, 9, 14, 13, 0, 0, 0, time.UTC),
+	urlName:   LiveTimingStaticUrl + "2030/2030-09-14_Madrid_Grand_Prix/2030-09-14_Race/",
+}
+
+func receiveMessages(t *testing.T, messages <-chan Messages.RaceControlMessage, expected ...string) {
+	t.Helper()
+
+	for _, msg := range expected {
+		select {
+		case received := <-messages:
+			if received.Msg != msg {
+				t.Errorf("expected '%s', got '%s'", msg, received.Msg)
+			}
+		case <-time.After(time.Second * 10):
+			t.Fatalf("timed out waiting for '%s'", msg)
+		}
+	}
+}
+
+func TestCreateFromUrlLocalFolder(t *testing.T) {
+	folder := t.TempDir()
+
+	files := map[string]string{
+		connection.ExtrapolatedClockFile: "00:00:00.000{\"Utc\":\"2030-09-14T12:59:00Z\",\"Remaining\":\"02:00:00\",\"Extrapolating\":false}\n" +
+			"00:01:00.000{\"Utc\":\"2030-09-14T13:00:00Z\",\"Remaining\":\"02:00:00\",\"Extrapolating\":true}\n",
+		connection.RaceControlMessagesFile: "00:00:00.500{\"Messages\":[{\"Utc\":\"2030-09-14T12:59:00\",\"Message\":\"TRACK CLEAR\"}]}\n" +
+			"00:00:01.500{\"Messages\":{\"1\":{\"Utc\":\"2030-09-14T12:59:01\",\"Message\":\"PIT EXIT OPEN\"}}}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(folder, name+".jsonStream"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replay, err := CreateFromUrl(parser.RaceControl, "file://"+folder, sourceEvent, t.TempDir(), flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	receiveMessages(t, replay.RaceControlMessages(), "TRACK CLEAR", "PIT EXIT OPEN")
+}
+
+func TestCreateFromUrlLiveServer(t *testing.T) {
+	start := time.Date(2030, 9, 14, 13, 0, 0, 0, time.UTC)
+	server := livetest.Start([]livetest.Message{
+		{
+			Name:      connection.RaceControlMessagesFile,
+			Data:      json.RawMessage(`{"Messages":[{"Utc":"2030-09-14T13:00:00","Message":"TRACK CLEAR"}]}`),
+			Timestamp: start,
+		},
+		{
+			Name:      connection.RaceControlMessagesFile,
+			Data:      json.RawMessage(`{"Messages":{"1":{"Utc":"2030-09-14T13:00:01","Message":"PIT EXIT OPEN"}}}`),
+			Timestamp: start.Add(time.Second),
+		},
+	}, livetest.Options{StartAt: time.Millisecond * 500})
+	defer server.Close()
+
+	live, err := CreateFromUrl(
+		parser.RaceControl,
+		"ws"+strings.TrimPrefix(server.URL, "http"),
+		sourceEvent,
+		t.TempDir(),
+		flowControl.StraightThrough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+
+	states := live.ConnectionState()
+	for _, expected := range []Messages.ConnectionStatus{Messages.Connecting, Messages.Connected} {
+		if state := receive(t, states); state.Status != expected {
+			t.Errorf("expected %s, got %s", expected, state.Status)
+		}
+	}
+
+	// The first message is only in the catchup
+	receiveMessages(t, live.RaceControlMessages(), "TRACK CLEAR", "PIT EXIT OPEN")
+}
+
+func TestCreateFromUrlUnsupported(t *testing.T) {
+	if _, err := CreateFromUrl(parser.RaceControl, "ftp://example.com/session/", sourceEvent, "", flowControl.StraightThrough); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,643 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+	"github.com/f1gopher/f1gopherlib/metrics"
+)
+
+var subscriptionDropped = metrics.Default.CounterVec(
+	"f1gopher_subscription_dropped_total",
+	"Messages dropped or coalesced because a subscriber wasn't keeping up",
+	"channel")
+
+var subscriptionsActive = metrics.Default.GaugeVec(
+	"f1gopher_subscriptions",
+	"Subscriptions currently receiving messages")
+
+const defaultSubscriptionBufferSize = 100
+
+// Envelopes aren't included so subscribers don't get every message twice unless they ask for them
+var allChannels = []flowControl.Channel{
+	flowControl.WeatherChannel,
+	flowControl.RaceControlChannel,
+	flowControl.TimingChannel,
+	flowControl.EventChannel,
+	flowControl.TelemetryChannel,
+	flowControl.LocationChannel,
+	flowControl.EventTimeChannel,
+	flowControl.RadioChannel,
+	flowControl.DriversChannel,
+	flowControl.CarStateChannel,
+}
+
+// SubscriptionFilter picks which messages a subscription gets and how they are delivered
+type SubscriptionFilter struct {
+	// Channels to receive messages for, empty is every channel apart from envelopes
+	Channels []flowControl.Channel
+	// Only send timing, telemetry, location and car state messages for these drivers, empty is every driver
+	Drivers []int
+	// What happens when the subscriber isn't keeping up, the default is to block. Up to BufferSize messages for each
+	// channel wait in the subscription's queue, as well as in the channel, before the policy applies. Once a blocking
+	// channel's queue is full it holds up the session, and every other subscription, until the subscriber reads.
+	Policies flowControl.Policies
+	// How long a blocking channel waits for the subscriber before dropping, the default is to wait forever
+	BlockTimeout time.Duration
+	// How many messages can wait in each channel, the default is 100
+	BufferSize int
+}
+
+// Subscription is one consumer's own stream of messages. Channels that weren't asked for never receive anything and
+// every channel is closed when the subscription or the session is closed.
+type Subscription interface {
+	Weather() <-chan Messages.Weather
+	RaceControlMessages() <-chan Messages.RaceControlMessage
+	Timing() <-chan Messages.Timing
+	Event() <-chan Messages.Event
+	Telemetry() <-chan Messages.Telemetry
+	Location() <-chan Messages.Location
+	Time() <-chan Messages.EventTime
+	Radio() <-chan Messages.Radio
+	Drivers() <-chan Messages.Drivers
+	CarState() <-chan Messages.CarState
+	// Every message in the order it was sent
+	Envelopes() <-chan Messages.Envelope
+
+	// Dropped is the number of messages dropped or coalesced for each channel of this subscription
+	Dropped() map[flowControl.Channel]uint64
+
+	Close()
+}
+
+type queued struct {
+	channel flowControl.Channel
+	driver  int
+	send    func()
+}
+
+type subscription struct {
+	owner *subscribers
+
+	channels     map[flowControl.Channel]bool
+	driverFilter map[int]bool
+	delivery     *flowControl.Delivery
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	lock      sync.Mutex
+	closed    bool
+	closeOnce sync.Once
+
+	// Messages waiting to be delivered, in the order they were published, with up to limit for each channel
+	queueLock sync.Mutex
+	queue     []queued
+	pending   map[flowControl.Channel]int
+	limit     int
+	// Channels whose subscriber didn't read within the block timeout
+	stalled map[flowControl.Channel]bool
+	// Signalled when something is queued or taken off the queue
+	queued chan struct{}
+	room   chan struct{}
+
+	weather             chan Messages.Weather
+	raceControlMessages chan Messages.RaceControlMessage
+	timing              chan Messages.Timing
+	event               chan Messages.Event
+	telemetry           chan Messages.Telemetry
+	location            chan Messages.Location
+	eventTime           chan Messages.EventTime
+	radio               chan Messages.Radio
+	drivers             chan Messages.Drivers
+	carState            chan Messages.CarState
+	envelopes           chan Messages.Envelope
+}
+
+// subscribers fans the messages from the flow control out to every subscription. Each subscription has its own queue
+// and goroutine delivering to it so a subscriber that is slow to read doesn't hold up the others.
+type subscribers struct {
+	ctx context.Context
+
+	lock          sync.Mutex
+	subscriptions map[*subscription]bool
+	changed       chan struct{}
+	closed        bool
+
+	// One subscription per channel for the F1Lib channel getters, created when the getter is first used
+	legacyLock sync.Mutex
+	legacy     map[flowControl.Channel]*subscription
+}
+
+func createSubscribers(ctx context.Context) *subscribers {
+	return &subscribers{
+		ctx:           ctx,
+		subscriptions: make(map[*subscription]bool),
+		changed:       make(chan struct{}, 1),
+		legacy:        make(map[flowControl.Channel]*subscription),
+	}
+}
+
+func (s *subscribers) subscribe(filter SubscriptionFilter) *subscription {
+	bufferSize := filter.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultSubscriptionBufferSize
+	}
+
+	sub := &subscription{
+		owner:               s,
+		channels:            make(map[flowControl.Channel]bool),
+		weather:             make(chan Messages.Weather, bufferSize),
+		raceControlMessages: make(chan Messages.RaceControlMessage, bufferSize),
+		timing:              make(chan Messages.Timing, bufferSize),
+		event:               make(chan Messages.Event, bufferSize),
+		telemetry:           make(chan Messages.Telemetry, bufferSize),
+		location:            make(chan Messages.Location, bufferSize),
+		eventTime:           make(chan Messages.EventTime, bufferSize),
+		radio:               make(chan Messages.Radio, bufferSize),
+		drivers:             make(chan Messages.Drivers, bufferSize),
+		carState:            make(chan Messages.CarState, bufferSize),
+		envelopes:           make(chan Messages.Envelope, bufferSize),
+		pending:             make(map[flowControl.Channel]int),
+		limit:               bufferSize,
+		stalled:             make(map[flowControl.Channel]bool),
+		queued:              make(chan struct{}, 1),
+		room:                make(chan struct{}, 1),
+	}
+	sub.ctx, sub.ctxCancel = context.WithCancel(s.ctx)
+	sub.delivery = flowControl.CreateDelivery(sub.ctx, filter.Policies, subscriptionDropped)
+
+	channels := filter.Channels
+	if len(channels) == 0 {
+		channels = allChannels
+	}
+	for _, channel := range channels {
+		sub.channels[channel] = true
+		sub.delivery.SetBlockTimeout(channel, filter.BlockTimeout)
+	}
+
+	if len(filter.Drivers) > 0 {
+		sub.driverFilter = make(map[int]bool)
+		for _, driver := range filter.Drivers {
+			sub.driverFilter[driver] = true
+		}
+	}
+
+	s.lock.Lock()
+	if s.closed {
+		// Nothing more will be published so the subscriber just sees the channels closed
+		s.lock.Unlock()
+		sub.closeChannels()
+		return sub
+	}
+	s.subscriptions[sub] = true
+	s.lock.Unlock()
+	subscriptionsActive.With().Inc()
+
+	go sub.deliver()
+
+	s.notify()
+
+	return sub
+}
+
+// legacySubscription is the subscription behind a F1Lib channel getter. It is delivered to with the flow control's
+// policy and block timeout for the channel so it behaves like reading the flow control output directly used to.
+func (s *subscribers) legacySubscription(
+	channel flowControl.Channel,
+	bufferSize int,
+	policy flowControl.DeliveryPolicy,
+	blockTimeout time.Duration) *subscription {
+
+	s.legacyLock.Lock()
+	defer s.legacyLock.Unlock()
+
+	sub, exists := s.legacy[channel]
+	if !exists {
+		var policies flowControl.Policies
+		policies[channel] = policy
+		sub = s.subscribe(SubscriptionFilter{
+			Channels:     []flowControl.Channel{channel},
+			Policies:     policies,
+			BlockTimeout: blockTimeout,
+			BufferSize:   bufferSize,
+		})
+		s.legacy[channel] = sub
+	}
+
+	return sub
+}
+
+// setLegacyDelivery changes the policy and block timeout for the channel getter's subscription if it has been used
+func (s *subscribers) setLegacyDelivery(channel flowControl.Channel, policy flowControl.DeliveryPolicy, blockTimeout time.Duration) {
+	s.legacyLock.Lock()
+	defer s.legacyLock.Unlock()
+
+	if sub, exists := s.legacy[channel]; exists {
+		sub.delivery.SetDeliveryPolicy(channel, policy)
+		sub.delivery.SetBlockTimeout(channel, blockTimeout)
+	}
+}
+
+// legacyDropped is the number of messages dropped for each channel by the channel getters' subscriptions
+func (s *subscribers) legacyDropped() map[flowControl.Channel]uint64 {
+	s.legacyLock.Lock()
+	defer s.legacyLock.Unlock()
+
+	result := make(map[flowControl.Channel]uint64)
+	for channel, sub := range s.legacy {
+		result[channel] += sub.delivery.Dropped()[channel]
+	}
+	return result
+}
+
+func (s *subscribers) remove(sub *subscription) {
+	s.lock.Lock()
+	_, exists := s.subscriptions[sub]
+	delete(s.subscriptions, sub)
+	s.lock.Unlock()
+
+	if exists {
+		subscriptionsActive.With().Dec()
+	}
+
+	s.notify()
+}
+
+func (s *subscribers) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// wanted is true for each channel that at least one subscription wants. Channels nobody wants aren't read so the
+// messages wait in the flow control until someone subscribes. Envelopes are read once anyone subscribes so the flow
+// control never waits for envelopes nobody wants, and while anyone wants the envelopes every channel is read because
+// the flow control sends the envelope and then waits on the channel for the message.
+func (s *subscribers) wanted() map[flowControl.Channel]bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make(map[flowControl.Channel]bool)
+	for sub := range s.subscriptions {
+		result[flowControl.EnvelopeChannel] = true
+
+		if sub.channels[flowControl.EnvelopeChannel] {
+			for _, channel := range allChannels {
+				result[channel] = true
+			}
+			return result
+		}
+
+		for channel := range sub.channels {
+			result[channel] = true
+		}
+	}
+	return result
+}
+
+func (s *subscribers) current() []*subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make([]*subscription, 0, len(s.subscriptions))
+	for sub := range s.subscriptions {
+		result = append(result, sub)
+	}
+	return result
+}
+
+// closeAll closes every subscription once nothing else will be published
+func (s *subscribers) closeAll() {
+	s.lock.Lock()
+	s.closed = true
+	s.lock.Unlock()
+
+	for _, sub := range s.current() {
+		sub.Close()
+	}
+}
+
+// run reads from the flow control outputs and publishes to the subscriptions until the context is done
+func (s *subscribers) run(
+	wg *sync.WaitGroup,
+	weather chan Messages.Weather,
+	raceControlMessages chan Messages.RaceControlMessage,
+	timing chan Messages.Timing,
+	event chan Messages.Event,
+	telemetry chan Messages.Telemetry,
+	location chan Messages.Location,
+	eventTime chan Messages.EventTime,
+	radio chan Messages.Radio,
+	drivers chan Messages.Drivers,
+	carState chan Messages.CarState,
+	envelopes chan Messages.Envelope) {
+
+	defer wg.Done()
+
+	for {
+		wanted := s.wanted()
+
+		select {
+		case <-s.ctx.Done():
+			return
+
+		case <-s.changed:
+			// Work out which channels are wanted again
+
+		case msg := <-onlyIf(wanted[flowControl.WeatherChannel], weather):
+			publish(s, flowControl.WeatherChannel, msg, func(sub *subscription) chan Messages.Weather { return sub.weather })
+
+		case msg := <-onlyIf(wanted[flowControl.RaceControlChannel], raceControlMessages):
+			publish(s, flowControl.RaceControlChannel, msg, func(sub *subscription) chan Messages.RaceControlMessage { return sub.raceControlMessages })
+
+		case msg := <-onlyIf(wanted[flowControl.TimingChannel], timing):
+			publish(s, flowControl.TimingChannel, msg, func(sub *subscription) chan Messages.Timing { return sub.timing })
+
+		case msg := <-onlyIf(wanted[flowControl.EventChannel], event):
+			publish(s, flowControl.EventChannel, msg, func(sub *subscription) chan Messages.Event { return sub.event })
+
+		case msg := <-onlyIf(wanted[flowControl.TelemetryChannel], telemetry):
+			publish(s, flowControl.TelemetryChannel, msg, func(sub *subscription) chan Messages.Telemetry { return sub.telemetry })
+
+		case msg := <-onlyIf(wanted[flowControl.LocationChannel], location):
+			publish(s, flowControl.LocationChannel, msg, func(sub *subscription) chan Messages.Location { return sub.location })
+
+		case msg := <-onlyIf(wanted[flowControl.EventTimeChannel], eventTime):
+			publish(s, flowControl.EventTimeChannel, msg, func(sub *subscription) chan Messages.EventTime { return sub.eventTime })
+
+		case msg := <-onlyIf(wanted[flowControl.RadioChannel], radio):
+			publish(s, flowControl.RadioChannel, msg, func(sub *subscription) chan Messages.Radio { return sub.radio })
+
+		case msg := <-onlyIf(wanted[flowControl.DriversChannel], drivers):
+			publish(s, flowControl.DriversChannel, msg, func(sub *subscription) chan Messages.Drivers { return sub.drivers })
+
+		case msg := <-onlyIf(wanted[flowControl.CarStateChannel], carState):
+			publish(s, flowControl.CarStateChannel, msg, func(sub *subscription) chan Messages.CarState { return sub.carState })
+
+		case msg := <-onlyIf(wanted[flowControl.EnvelopeChannel], envelopes):
+			publish(s, flowControl.EnvelopeChannel, msg, func(sub *subscription) chan Messages.Envelope { return sub.envelopes })
+		}
+	}
+}
+
+// onlyIf gives a nil channel, which is never ready, when the condition is false
+func onlyIf[T any](condition bool, channel chan T) chan T {
+	if !condition {
+		return nil
+	}
+	return channel
+}
+
+func publish[T any](s *subscribers, channel flowControl.Channel, msg T, output func(sub *subscription) chan T) {
+	driver := flowControl.DriverOf(msg)
+	if envelope, ok := any(msg).(Messages.Envelope); ok {
+		driver = flowControl.DriverOf(envelope.Payload)
+	}
+
+	for _, sub := range s.current() {
+		if !sub.channels[channel] {
+			continue
+		}
+
+		if driver != 0 && sub.driverFilter != nil && !sub.driverFilter[driver] {
+			continue
+		}
+
+		sub.enqueue(channel, driver, func() {
+			flowControl.Send(sub.delivery, channel, output(sub), msg)
+		})
+	}
+}
+
+// enqueue adds the message to the queue. Once there are limit messages waiting for the channel its policy decides
+// whether this message waits for room, is dropped or replaces one that is waiting.
+func (s *subscription) enqueue(channel flowControl.Channel, driver int, send func()) {
+	policy := s.delivery.DeliveryPolicy(channel)
+	var timeout <-chan time.Time
+
+	for {
+		s.queueLock.Lock()
+		full := s.pending[channel] >= s.limit
+		if full {
+			switch policy {
+			case flowControl.DropNewest:
+				s.queueLock.Unlock()
+				s.delivery.Drop(channel)
+				return
+
+			case flowControl.DropOldest:
+				s.removeQueued(channel, 0, false)
+				s.delivery.Drop(channel)
+				full = false
+
+			case flowControl.CoalesceLatestPerDriver:
+				// The latest waiting for the same driver, otherwise the oldest for the channel
+				if !s.removeQueued(channel, driver, true) {
+					s.removeQueued(channel, 0, false)
+				}
+				s.delivery.Drop(channel)
+				full = false
+
+			default:
+				if s.stalled[channel] {
+					s.queueLock.Unlock()
+					s.delivery.Drop(channel)
+					return
+				}
+			}
+		}
+
+		if !full {
+			s.queue = append(s.queue, queued{channel: channel, driver: driver, send: send})
+			s.pending[channel]++
+			s.stalled[channel] = false
+			s.queueLock.Unlock()
+
+			select {
+			case s.queued <- struct{}{}:
+			default:
+			}
+			return
+		}
+		s.queueLock.Unlock()
+
+		// Blocking, wait for the subscriber to make room
+		if timeout == nil {
+			if blockTimeout := s.delivery.BlockTimeout(channel); blockTimeout > 0 {
+				timer := time.NewTimer(blockTimeout)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+		}
+
+		select {
+		case <-s.room:
+		case <-s.ctx.Done():
+			return
+		case <-timeout:
+			s.queueLock.Lock()
+			s.stalled[channel] = true
+			s.queueLock.Unlock()
+			s.delivery.Drop(channel)
+			return
+		}
+	}
+}
+
+// removeQueued takes a message for the channel off the queue, either the latest for the driver or the oldest. Must
+// hold the queue lock.
+func (s *subscription) removeQueued(channel flowControl.Channel, driver int, matchDriver bool) bool {
+	index := -1
+	if matchDriver {
+		for x := len(s.queue) - 1; x >= 0; x-- {
+			if s.queue[x].channel == channel && s.queue[x].driver == driver {
+				index = x
+				break
+			}
+		}
+	} else {
+		for x := range s.queue {
+			if s.queue[x].channel == channel {
+				index = x
+				break
+			}
+		}
+	}
+
+	if index == -1 {
+		return false
+	}
+
+	s.queue = append(s.queue[:index], s.queue[index+1:]...)
+	s.pending[channel]--
+	return true
+}
+
+func (s *subscription) dequeue() (func(), bool) {
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+
+	next := s.queue[0]
+	s.queue[0] = queued{}
+	s.queue = s.queue[1:]
+	s.pending[next.channel]--
+
+	select {
+	case s.room <- struct{}{}:
+	default:
+	}
+
+	return next.send, true
+}
+
+// queueLength is the number of messages waiting for the channel
+func (s *subscription) queueLength(channel flowControl.Channel) int {
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+	return s.pending[channel]
+}
+
+// deliver sends the queued messages until the subscription is closed
+func (s *subscription) deliver() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.queued:
+		}
+
+		for send, exists := s.dequeue(); exists; send, exists = s.dequeue() {
+			// Holding the lock stops the subscription closing the channel while we are sending. Closing cancels the
+			// subscription context first so a blocked send gives up.
+			s.lock.Lock()
+			if s.closed {
+				s.lock.Unlock()
+				return
+			}
+			send()
+			s.lock.Unlock()
+		}
+	}
+}
+
+func (s *subscription) Weather() <-chan Messages.Weather {
+	return s.weather
+}
+
+func (s *subscription) RaceControlMessages() <-chan Messages.RaceControlMessage {
+	return s.raceControlMessages
+}
+
+func (s *subscription) Timing() <-chan Messages.Timing {
+	return s.timing
+}
+
+func (s *subscription) Event() <-chan Messages.Event {
+	return s.event
+}
+
+func (s *subscription) Telemetry() <-chan Messages.Telemetry {
+	return s.telemetry
+}
+
+func (s *subscription) Location() <-chan Messages.Location {
+	return s.location
+}
+
+func (s *subscription) Time() <-chan Messages.EventTime {
+	return s.eventTime
+}
+
+func (s *subscription) Radio() <-chan Messages.Radio {
+	return s.radio
+}
+
+func (s *subscription) Drivers() <-chan Messages.Drivers {
+	return s.drivers
+}
+
+func (s *subscription) CarState() <-chan Messages.CarState {
+	return s.carState
+}
+
+func (s *subscription) Envelopes() <-chan Messages.Envelope {
+	return s.envelopes
+}
+
+func (s *subscription) Dropped() map[flowControl.Channel]uint64 {
+	return s.delivery.Dropped()
+}
+
+func (s *subscription) Close() {
+	s.owner.remove(s)
+	s.ctxCancel()
+	s.closeChannels()
+}
+
+func (s *subscription) closeChannels() {
+	s.closeOnce.Do(func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.closed = true
+
+		close(s.weather)
+		close(s.raceControlMessages)
+		close(s.timing)
+		close(s.event)
+		close(s.telemetry)
+		close(s.location)
+		close(s.eventTime)
+		close(s.radio)
+		close(s.drivers)
+		close(s.carState)
+		close(s.envelopes)
+	})
+}
@@ -0,0 +1,308 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/flowControl"
+)
+
+func TestSubscribersFanOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	timing := make(chan Messages.Timing, 10)
+	weather := make(chan Messages.Weather, 10)
+
+	s := createSubscribers(ctx)
+	everything := s.subscribe(SubscriptionFilter{})
+	hamilton := s.subscribe(SubscriptionFilter{
+		Channels: []flowControl.Channel{flowControl.TimingChannel},
+		Drivers:  []int{44},
+	})
+
+	wg.Add(1)
+	go s.run(&wg, weather, nil, timing, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	timing <- Messages.Timing{Number: 1}
+	timing <- Messages.Timing{Number: 44}
+	weather <- Messages.Weather{AirTemp: 20}
+
+	for _, expected := range []int{1, 44} {
+		if msg := receive(t, everything.Timing()); msg.Number != expected {
+			t.Errorf("expected driver %d, got %d", expected, msg.Number)
+		}
+	}
+	if msg := receive(t, everything.Weather()); msg.AirTemp != 20 {
+		t.Errorf("unexpected weather: %v", msg)
+	}
+
+	// Only gets timing for the driver it asked for
+	if msg := receive(t, hamilton.Timing()); msg.Number != 44 {
+		t.Errorf("expected driver 44, got %d", msg.Number)
+	}
+
+	// The getters share one subscription per channel
+	if s.legacySubscription(flowControl.TimingChannel, 10, flowControl.Block, 0) != s.legacySubscription(flowControl.TimingChannel, 10, flowControl.Block, 0) {
+		t.Error("expected the same subscription for the same channel")
+	}
+
+	hamilton.Close()
+	if _, ok := <-hamilton.Timing(); ok {
+		t.Error("expected the closed subscription's channels to be closed")
+	}
+
+	cancel()
+	wg.Wait()
+	s.closeAll()
+
+	if _, ok := <-everything.Timing(); ok {
+		t.Error("expected every subscription to be closed")
+	}
+
+	// Subscribing after the session has closed gives closed channels rather than ones that never get anything
+	if _, ok := <-s.subscribe(SubscriptionFilter{}).Event(); ok {
+		t.Error("expected a late subscription to be closed")
+	}
+}
+
+func receive[T any](t *testing.T, channel <-chan T) T {
+	t.Helper()
+
+	select {
+	case msg := <-channel:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+
+	var empty T
+	return empty
+}
+
+func TestStalledSubscriberDoesNotHoldUpOthers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+
+	timing := make(chan Messages.Timing)
+
+	s := createSubscribers(ctx)
+	// Never reads and only wants the latest messages
+	var policies flowControl.Policies
+	policies[flowControl.TimingChannel] = flowControl.DropOldest
+	stalled := s.subscribe(SubscriptionFilter{Policies: policies, BufferSize: 1})
+	reading := s.subscribe(SubscriptionFilter{BufferSize: 1})
+
+	wg.Add(1)
+	go s.run(&wg, nil, nil, timing, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	const count = 50
+	go func() {
+		for x := 1; x <= count; x++ {
+			timing <- Messages.Timing{Number: x}
+		}
+	}()
+
+	for x := 1; x <= count; x++ {
+		if msg := receive(t, reading.Timing()); msg.Number != x {
+			t.Fatalf("expected driver %d, got %d", x, msg.Number)
+		}
+	}
+
+	// Only the latest are kept for the stalled subscriber
+	if length := stalled.queueLength(flowControl.TimingChannel); length > 1 {
+		t.Errorf("expected at most 1 message queued, got %d", length)
+	}
+	if dropped := stalled.Dropped()[flowControl.TimingChannel]; dropped < count-3 {
+		t.Errorf("expected the older messages to be dropped, got %d", dropped)
+	}
+	if msg := receive(t, stalled.Timing()); msg.Number == 1 {
+		t.Errorf("expected the oldest messages to be dropped, got %d", msg.Number)
+	}
+
+	stalled.Close()
+	reading.Close()
+	cancel()
+	wg.Wait()
+}
+
+func TestBlockingSubscriberHoldsUpUntilTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := createSubscribers(ctx)
+	stalled := s.subscribe(SubscriptionFilter{BufferSize: 1, BlockTimeout: 20 * time.Millisecond})
+	defer stalled.Close()
+
+	// Nobody reads so the first message waits in the channel, the next waits for the timeout and then the rest are
+	// dropped straight away until the subscriber reads
+	const count = 10
+	start := time.Now()
+	for x := 1; x <= count; x++ {
+		publish(s, flowControl.TimingChannel, Messages.Timing{Number: x}, func(sub *subscription) chan Messages.Timing { return sub.timing })
+	}
+	eventually(t, func() bool { return stalled.queueLength(flowControl.TimingChannel) == 0 })
+	if waited := time.Since(start); waited < 20*time.Millisecond || waited > count*20*time.Millisecond {
+		t.Errorf("expected to wait for the timeout once, waited %v", waited)
+	}
+
+	if dropped := stalled.Dropped()[flowControl.TimingChannel]; dropped < count-2 {
+		t.Errorf("expected the messages after the timeout to be dropped, got %d", dropped)
+	}
+	if msg := receive(t, stalled.Timing()); msg.Number != 1 {
+		t.Errorf("expected the first message to be kept, got %d", msg.Number)
+	}
+}
+
+func TestStalledChannelGetterIsBounded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+
+	telemetry := make(chan Messages.Telemetry)
+	timing := make(chan Messages.Timing, 1)
+
+	f := &f1lib{
+		ctx:          ctx,
+		replayTiming: flowControl.CreateFlowControl(ctx, &wg, flowControl.StraightThrough, nil, nil, timing, nil, telemetry, nil, nil, nil, nil, nil, nil),
+	}
+	f.subscribers = createSubscribers(ctx)
+	f.SetDeliveryPolicy(flowControl.TelemetryChannel, flowControl.CoalesceLatestPerDriver)
+
+	// Never read
+	const bufferSize = 10
+	legacy := f.legacySubscription(flowControl.TelemetryChannel, bufferSize)
+	if policy := legacy.delivery.DeliveryPolicy(flowControl.TelemetryChannel); policy != flowControl.CoalesceLatestPerDriver {
+		t.Errorf("expected the channel getter to use the flow control's policy, got %s", policy)
+	}
+
+	wg.Add(1)
+	go f.subscribers.run(&wg, nil, nil, timing, nil, telemetry, nil, nil, nil, nil, nil, nil)
+
+	const count = 5000
+	for x := 0; x < count; x++ {
+		telemetry <- Messages.Telemetry{DriverNumber: x % 20, RPM: int16(x)}
+	}
+
+	if length := legacy.queueLength(flowControl.TelemetryChannel); length > bufferSize {
+		t.Errorf("expected at most %d messages queued, got %d", bufferSize, length)
+	}
+	// Only what fits in the queue and the channel is kept
+	if dropped := f.DroppedMessages()[flowControl.TelemetryChannel]; dropped < count-2*bufferSize-1 {
+		t.Errorf("expected the stalled channel to drop, got %d", dropped)
+	}
+
+	// Changing the policy changes the channel getters that are already in use
+	f.Timing()
+	f.SetDeliveryPolicy(flowControl.TimingChannel, flowControl.DropNewest)
+	f.SetBlockTimeout(flowControl.TimingChannel, time.Second)
+	timingSub := f.legacySubscription(flowControl.TimingChannel, 0)
+	if policy := timingSub.delivery.DeliveryPolicy(flowControl.TimingChannel); policy != flowControl.DropNewest {
+		t.Errorf("expected the new policy, got %s", policy)
+	}
+	if timeout := timingSub.delivery.BlockTimeout(flowControl.TimingChannel); timeout != time.Second {
+		t.Errorf("expected the new block timeout, got %v", timeout)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatal("timed out waiting")
+}
+
+func TestDataGetters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := &f1lib{
+		ctx:    ctx,
+		timing: make(chan Messages.Timing, 1),
+	}
+	f.subscribers = createSubscribers(ctx)
+
+	data, ok := f.Data().(*f1lib)
+	if !ok {
+		t.Fatal("expected the data to be a f1lib")
+	}
+
+	// Getters on the copy use the same subscriptions as the original
+	if data.Timing() != f.Timing() {
+		t.Error("expected the copy to share the timing subscription")
+	}
+}
+
+func TestOnlyWantedChannelsAreRead(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+
+	timing := make(chan Messages.Timing, 10)
+	weather := make(chan Messages.Weather, 10)
+
+	s := createSubscribers(ctx)
+	wg.Add(1)
+	go s.run(&wg, weather, nil, timing, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	timingOnly := s.subscribe(SubscriptionFilter{Channels: []flowControl.Channel{flowControl.TimingChannel}})
+
+	weather <- Messages.Weather{AirTemp: 20}
+	timing <- Messages.Timing{Number: 44}
+	receive(t, timingOnly.Timing())
+
+	// Nobody wanted the weather yet so it waited for a getter made later
+	if msg := receive(t, s.legacySubscription(flowControl.WeatherChannel, 10, flowControl.DropOldest, 0).weather); msg.AirTemp != 20 {
+		t.Errorf("unexpected weather: %v", msg)
+	}
+
+	timingOnly.Close()
+	cancel()
+	wg.Wait()
+}
+
+func TestEnvelopeSubscribersReadEveryChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+
+	timing := make(chan Messages.Timing)
+	envelopes := make(chan Messages.Envelope)
+
+	s := createSubscribers(ctx)
+	wg.Add(1)
+	go s.run(&wg, nil, nil, timing, nil, nil, nil, nil, nil, nil, nil, envelopes)
+
+	recorder := s.subscribe(SubscriptionFilter{Channels: []flowControl.Channel{flowControl.EnvelopeChannel}})
+
+	// The flow control sends the envelope then the message, which must be taken even though nobody wants timing
+	for x := 1; x <= 3; x++ {
+		msg := Messages.Timing{Number: x}
+		envelopes <- Messages.CreateEnvelope(msg, uint64(x))
+
+		select {
+		case timing <- msg:
+		case <-time.After(time.Second):
+			t.Fatal("timing wasn't read while recording")
+		}
+
+		if envelope := receive(t, recorder.Envelopes()); envelope.Sequence != uint64(x) {
+			t.Errorf("expected envelope %d, got %d", x, envelope.Sequence)
+		}
+	}
+
+	recorder.Close()
+	cancel()
+	wg.Wait()
+}
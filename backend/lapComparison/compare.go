@@ -0,0 +1,106 @@
+package lapComparison
+
+import (
+	"sort"
+)
+
+// Comparison is two laps lined up by distance with how far apart they are in time
+type Comparison struct {
+	ReferenceDriver int
+	ReferenceLap    int
+	// Milliseconds
+	ReferenceTime int64
+	OtherDriver   int
+	OtherLap      int
+	// Milliseconds
+	OtherTime int64
+
+	Points []Point
+}
+
+// Point is both laps at the same distance
+type Point struct {
+	// Metres from the finish line
+	Distance  float64
+	Reference Sample
+	Other     Sample
+	// Milliseconds the other lap is behind the reference at this distance, negative when it is ahead
+	Delta int64
+}
+
+// MinStep is the smallest distance between the points in a comparison in metres, which keeps a comparison to a few
+// thousand points for a lap
+const MinStep = 1.0
+
+// Compare lines up the laps every step metres. The laps are measured against the same reference lap so the
+// distances match even if one lap was slightly longer. Steps smaller than MinStep give no points.
+func Compare(reference Lap, other Lap, step float64) Comparison {
+	result := Comparison{
+		ReferenceDriver: reference.DriverNumber,
+		ReferenceLap:    reference.Number,
+		ReferenceTime:   reference.Time,
+		OtherDriver:     other.DriverNumber,
+		OtherLap:        other.Number,
+		OtherTime:       other.Time,
+	}
+
+	// Not a number is never at least MinStep either
+	if !(step >= MinStep) || len(reference.Samples) == 0 || len(other.Samples) == 0 {
+		return result
+	}
+
+	length := min(reference.Length, other.Length)
+	for distance := 0.0; ; distance += step {
+		last := distance >= length
+		if last {
+			distance = length
+		}
+
+		point := Point{
+			Distance:  distance,
+			Reference: sampleAt(reference, distance),
+			Other:     sampleAt(other, distance),
+		}
+		point.Delta = point.Other.Time - point.Reference.Time
+		result.Points = append(result.Points, point)
+
+		if last {
+			break
+		}
+	}
+
+	return result
+}
+
+// sampleAt interpolates the time, RPM, speed and throttle at the distance and uses the sample before for the brake,
+// gear and DRS
+func sampleAt(lap Lap, distance float64) Sample {
+	index := sort.Search(len(lap.Samples), func(x int) bool {
+		return lap.Samples[x].Distance >= distance
+	})
+
+	if index == 0 {
+		result := lap.Samples[0]
+		result.Distance = distance
+		return result
+	}
+	if index == len(lap.Samples) {
+		result := lap.Samples[len(lap.Samples)-1]
+		result.Distance = distance
+		return result
+	}
+
+	before, after := lap.Samples[index-1], lap.Samples[index]
+	fraction := 0.0
+	if after.Distance > before.Distance {
+		fraction = (distance - before.Distance) / (after.Distance - before.Distance)
+	}
+
+	result := before
+	result.Distance = distance
+	result.Time = before.Time + int64(fraction*float64(after.Time-before.Time))
+	result.RPM = int16(float64(before.RPM) + fraction*float64(after.RPM-before.RPM))
+	result.Speed = before.Speed + float32(fraction)*(after.Speed-before.Speed)
+	result.Throttle = before.Throttle + float32(fraction)*(after.Throttle-before.Throttle)
+	return result
+}
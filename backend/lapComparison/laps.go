@@ -0,0 +1,264 @@
+package lapComparison
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// The timing says a lap is complete a little after the car crosses the line, a crossing further away than this isn't
+// for the same lap
+const maxCrossingDifference = 5 * time.Second
+
+// Sample is the telemetry for a point on the lap
+type Sample struct {
+	// Metres from the finish line
+	Distance float64
+	// Milliseconds since the start of the lap
+	Time     int64
+	RPM      int16
+	Speed    float32
+	Throttle float32
+	Brake    float32
+	Gear     byte
+	DRS      bool
+}
+
+// Lap is the telemetry for one lap ordered by distance
+type Lap struct {
+	DriverNumber int
+	Number       int
+	Start        time.Time
+	// Milliseconds
+	Time int64
+	// Metres
+	Length  float64
+	Samples []Sample
+}
+
+// Laps keeps the telemetry for each lap of each driver so any completed lap can be taken out. The laps are numbered by
+// the timing and start and end where the car crossed the finish line. Car states are only kept for the laps that
+// haven't been completed yet, once a lap is over only its samples are kept.
+type Laps struct {
+	lock    sync.Mutex
+	drivers map[int]*driverLaps
+}
+
+type driverLaps struct {
+	// When the timing said each lap was completed, indexed by lap number
+	completed map[int]time.Time
+	lastLap   int
+
+	crossings []time.Time
+	length    float64
+
+	// Car states from the start of the first lap that hasn't been taken out yet
+	states []Messages.CarState
+	// The laps taken out of the states, every lap up to finished has been done
+	laps     map[int]Lap
+	finished int
+}
+
+func CreateLaps() *Laps {
+	return &Laps{
+		drivers: make(map[int]*driverLaps),
+	}
+}
+
+func (l *Laps) driver(driverNumber int) *driverLaps {
+	driver, exists := l.drivers[driverNumber]
+	if !exists {
+		driver = &driverLaps{
+			completed: make(map[int]time.Time),
+			laps:      make(map[int]Lap),
+		}
+		l.drivers[driverNumber] = driver
+	}
+	return driver
+}
+
+// AddTiming records when the timing says each lap was completed
+func (l *Laps) AddTiming(timing Messages.Timing) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	driver := l.driver(timing.Number)
+	if timing.Lap > driver.lastLap {
+		driver.lastLap = timing.Lap
+		driver.completed[timing.Lap] = timing.Timestamp
+	}
+}
+
+// AddCarState keeps the car states that have telemetry and know how far round the lap they are
+func (l *Laps) AddCarState(state Messages.CarState) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	driver := l.driver(state.DriverNumber)
+	if !state.FinishLineCrossing.IsZero() {
+		driver.crossings = append(driver.crossings, state.FinishLineCrossing)
+	}
+
+	if !state.TelemetryKnown || !state.LapDistanceKnown {
+		return
+	}
+
+	if state.LapFraction > 0 {
+		driver.length = state.LapDistance / state.LapFraction
+	}
+	driver.states = append(driver.states, state)
+
+	driver.finish(state.DriverNumber, state.Timestamp)
+}
+
+// Clear forgets every lap, for when the session is over
+func (l *Laps) Clear() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.drivers = make(map[int]*driverLaps)
+}
+
+// Lap gives the telemetry for a lap the driver has completed. The first lap starts from the grid rather than the line
+// so it isn't available.
+func (l *Laps) Lap(driverNumber int, lap int) (Lap, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	driver, exists := l.drivers[driverNumber]
+	if !exists {
+		return Lap{}, false
+	}
+
+	if lap <= driver.finished {
+		result, exists := driver.laps[lap]
+		return result, exists
+	}
+
+	return driver.lap(driverNumber, lap)
+}
+
+// finish takes the laps that are over out of the car states. A lap is over once its finish line crossing can't still
+// arrive.
+func (d *driverLaps) finish(driverNumber int, now time.Time) {
+	for lap := d.finished + 1; lap <= d.lastLap; lap++ {
+		completed, exists := d.completed[lap]
+		if exists {
+			if now.Sub(completed) <= maxCrossingDifference {
+				return
+			}
+
+			if result, ok := d.lap(driverNumber, lap); ok {
+				d.laps[lap] = result
+			}
+
+			// The next lap starts at the end of this one
+			end, _ := d.boundary(lap)
+			first := sort.Search(len(d.states), func(x int) bool {
+				return !d.states[x].Timestamp.Before(end)
+			})
+			d.states = append([]Messages.CarState(nil), d.states[first:]...)
+		}
+
+		d.finished = lap
+	}
+}
+
+// lap makes the lap from the car states
+func (d *driverLaps) lap(driverNumber int, lap int) (Lap, bool) {
+	if d.length == 0 {
+		return Lap{}, false
+	}
+
+	start, startFound := d.boundary(lap - 1)
+	end, endFound := d.boundary(lap)
+	if !startFound || !endFound || !end.After(start) {
+		return Lap{}, false
+	}
+
+	result := Lap{
+		DriverNumber: driverNumber,
+		Number:       lap,
+		Start:        start,
+		Time:         end.Sub(start).Milliseconds(),
+		Length:       d.length,
+	}
+
+	first := sort.Search(len(d.states), func(x int) bool {
+		return !d.states[x].Timestamp.Before(start)
+	})
+
+	previous := 0.0
+	for _, state := range d.states[first:] {
+		if !state.Timestamp.Before(end) {
+			break
+		}
+
+		// Only going forwards, and a big jump is a location from before the finish line
+		if state.LapDistance < previous || state.LapDistance-previous > result.Length/2 {
+			continue
+		}
+		previous = state.LapDistance
+
+		result.Samples = append(result.Samples, createSample(state, state.Timestamp.Sub(start).Milliseconds()))
+	}
+
+	if len(result.Samples) == 0 {
+		return Lap{}, false
+	}
+
+	// From the line to the line
+	lapStart := result.Samples[0]
+	lapStart.Distance = 0
+	lapStart.Time = 0
+	lapEnd := result.Samples[len(result.Samples)-1]
+	lapEnd.Distance = result.Length
+	lapEnd.Time = result.Time
+	result.Samples = append(append([]Sample{lapStart}, result.Samples...), lapEnd)
+
+	return result, true
+}
+
+// boundary is when the lap was completed, which is where the car crossed the finish line or when the timing said
+// the lap was completed if the crossing wasn't seen
+func (d *driverLaps) boundary(lap int) (time.Time, bool) {
+	completed, exists := d.completed[lap]
+	if !exists {
+		return time.Time{}, false
+	}
+
+	nearest := time.Time{}
+	for _, crossing := range d.crossings {
+		if absolute(crossing.Sub(completed)) <= maxCrossingDifference &&
+			(nearest.IsZero() || absolute(crossing.Sub(completed)) < absolute(nearest.Sub(completed))) {
+			nearest = crossing
+		}
+	}
+
+	if nearest.IsZero() {
+		return completed, true
+	}
+	return nearest, true
+}
+
+func createSample(state Messages.CarState, elapsed int64) Sample {
+	return Sample{
+		Distance: state.LapDistance,
+		Time:     elapsed,
+		RPM:      state.RPM,
+		Speed:    state.Speed,
+		Throttle: state.Throttle,
+		Brake:    state.Brake,
+		Gear:     state.Gear,
+		DRS:      state.DRS,
+	}
+}
+
+func absolute(duration time.Duration) time.Duration {
+	if duration < 0 {
+		return -duration
+	}
+	return duration
+}
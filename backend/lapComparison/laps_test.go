@@ -0,0 +1,139 @@
+package lapComparison
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+const trackLength = 5000.0
+
+// driveLaps sends the car states for a car going round at a constant speed for each lap, with the timing saying each
+// lap is complete a second after the car crosses the line
+func driveLaps(laps *Laps, driverNumber int, start time.Time, lapTimes []time.Duration) {
+	lapStart := start
+	for lap, lapTime := range lapTimes {
+		speed := trackLength / lapTime.Seconds()
+
+		for elapsed := time.Duration(0); elapsed < lapTime; elapsed += 250 * time.Millisecond {
+			distance := speed * elapsed.Seconds()
+			state := Messages.CarState{
+				Timestamp:        lapStart.Add(elapsed),
+				DriverNumber:     driverNumber,
+				TelemetryKnown:   true,
+				Speed:            float32(speed * 3.6),
+				Gear:             byte(lap + 1),
+				LapDistance:      distance,
+				LapFraction:      distance / trackLength,
+				LapDistanceKnown: true,
+			}
+			if elapsed == 0 && lap > 0 {
+				state.FinishLineCrossing = lapStart
+			}
+			laps.AddCarState(state)
+		}
+
+		lapStart = lapStart.Add(lapTime)
+		laps.AddTiming(Messages.Timing{Timestamp: lapStart.Add(time.Second), Number: driverNumber, Lap: lap + 1})
+	}
+
+	// Crossing the line at the end of the last lap
+	laps.AddCarState(Messages.CarState{Timestamp: lapStart, DriverNumber: driverNumber, FinishLineCrossing: lapStart})
+}
+
+func TestLaps(t *testing.T) {
+	laps := CreateLaps()
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+	driveLaps(laps, 1, start, []time.Duration{100 * time.Second, 90 * time.Second, 92 * time.Second})
+
+	if _, exists := laps.Lap(1, 1); exists {
+		t.Errorf("didn't expect the first lap as it starts on the grid")
+	}
+	if _, exists := laps.Lap(1, 4); exists {
+		t.Errorf("didn't expect a lap that hasn't been completed")
+	}
+
+	lap, exists := laps.Lap(1, 2)
+	if !exists {
+		t.Fatalf("expected lap 2")
+	}
+	if lap.Time != 90000 || !lap.Start.Equal(start.Add(100*time.Second)) || math.Abs(lap.Length-trackLength) > 0.001 {
+		t.Errorf("expected lap 2 to start where the line was crossed, got %v %d %f", lap.Start.Sub(start), lap.Time, lap.Length)
+	}
+
+	first, last := lap.Samples[0], lap.Samples[len(lap.Samples)-1]
+	if first.Distance != 0 || first.Time != 0 || last.Distance != lap.Length || last.Time != lap.Time {
+		t.Errorf("expected the lap to go from line to line, got %+v %+v", first, last)
+	}
+	for _, sample := range lap.Samples {
+		if sample.Gear != 2 {
+			t.Fatalf("expected only samples from lap 2, got %+v", sample)
+		}
+	}
+}
+
+func TestLapsDropOldCarStates(t *testing.T) {
+	laps := CreateLaps()
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+	lapTimes := make([]time.Duration, 10)
+	for x := range lapTimes {
+		lapTimes[x] = 90 * time.Second
+	}
+	driveLaps(laps, 1, start, lapTimes)
+
+	// Only the last lap, which could still get a later crossing, is left in the car states
+	if states := len(laps.drivers[1].states); states > 2*90*4 {
+		t.Errorf("expected the car states for the finished laps to be dropped, got %d", states)
+	}
+
+	for lap := 2; lap <= 10; lap++ {
+		result, exists := laps.Lap(1, lap)
+		if !exists || result.Time != 90000 || result.Samples[1].Gear != byte(lap) {
+			t.Errorf("expected lap %d to still be available, got %v", lap, exists)
+		}
+	}
+
+	laps.Clear()
+	if _, exists := laps.Lap(1, 2); exists {
+		t.Error("expected no laps once cleared")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	laps := CreateLaps()
+	start := time.Date(2023, 4, 29, 13, 0, 0, 0, time.UTC)
+	driveLaps(laps, 1, start, []time.Duration{100 * time.Second, 90 * time.Second})
+	driveLaps(laps, 16, start, []time.Duration{101 * time.Second, 92 * time.Second})
+
+	reference, _ := laps.Lap(1, 2)
+	other, _ := laps.Lap(16, 2)
+	comparison := Compare(reference, other, 100)
+
+	if comparison.ReferenceDriver != 1 || comparison.OtherDriver != 16 || comparison.OtherTime-comparison.ReferenceTime != 2000 {
+		t.Errorf("unexpected comparison %+v", comparison)
+	}
+	if len(comparison.Points) != 51 {
+		t.Fatalf("expected a point every 100m, got %d", len(comparison.Points))
+	}
+
+	halfway := comparison.Points[25]
+	if halfway.Distance != 2500 || halfway.Delta < 990 || halfway.Delta > 1010 {
+		t.Errorf("expected to be a second behind half way round, got %+v", halfway)
+	}
+	if math.Abs(float64(halfway.Reference.Speed)-200) > 0.01 {
+		t.Errorf("expected the reference speed, got %f", halfway.Reference.Speed)
+	}
+
+	end := comparison.Points[len(comparison.Points)-1]
+	if end.Distance != trackLength || end.Delta != 2000 {
+		t.Errorf("expected to finish two seconds behind, got %+v", end)
+	}
+
+	for _, step := range []float64{0, MinStep / 2, math.NaN()} {
+		if points := Compare(reference, other, step).Points; len(points) != 0 {
+			t.Errorf("expected no points for a %f step, got %d", step, len(points))
+		}
+	}
+}
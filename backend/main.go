@@ -1,8 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/api/auth"
+	"github.com/f1gopher/f1gopherlib/api/config"
+	health "github.com/f1gopher/f1gopherlib/api/handlers/health"
 	historic "github.com/f1gopher/f1gopherlib/api/handlers/historic"
 	websocket "github.com/f1gopher/f1gopherlib/api/websockets"
+	"github.com/f1gopher/f1gopherlib/connection"
+	providers "github.com/f1gopher/f1gopherlib/internal/providers"
+	"github.com/f1gopher/f1gopherlib/metrics"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 )
@@ -10,14 +26,127 @@ import (
 func main() {
 	e := echo.New()
 
+	serverConfig, err := config.Load(os.Args[1:])
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	providers.SetHttpOptions(connection.HttpOptions{
+		Timeout:   serverConfig.HttpTimeout,
+		MirrorUrl: serverConfig.MirrorUrl,
+	})
+
+	if len(serverConfig.CalendarFile) > 0 {
+		if err = providers.LoadCalendarFile(serverConfig.CalendarFile); err != nil {
+			e.Logger.Warn(err)
+		}
+	}
+
+	if serverConfig.RefreshCalendar {
+		// Sessions are added to the calendar as they are found so this doesn't need to hold up the start
+		go func() {
+			if err := providers.RefreshCalendar(providers.LiveTimingStaticUrl, time.Now().Year()); err != nil {
+				e.Logger.Warn(err)
+			}
+		}()
+	}
+
+	if err = providers.LoadConvertedSessions(serverConfig.CacheDir); err != nil {
+		e.Logger.Warn(err)
+	}
+
+	authConfig := serverConfig.Auth()
+	if authConfig.Insecure {
+		e.Logger.Warn("Running insecure, without API keys anyone who can reach the server can control sessions and " +
+			"without allowed origins any site can use it")
+	}
+
+	hub := websocket.CreateHub(serverConfig.CacheDir, &authConfig)
+
+	if err = os.MkdirAll(serverConfig.CacheDir, 0755); err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	status := health.CreateHealth()
+	status.AddCheck("cache", func() error {
+		info, err := os.Stat(serverConfig.CacheDir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a folder", serverConfig.CacheDir)
+		}
+		return nil
+	})
+
+	// The API key can be in the url for websockets so hide it before the url is logged
+	e.Use(auth.RedactKey())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
-
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: authConfig.AllowedOrigins,
+		AllowMethods: []string{http.MethodGet, http.MethodPost},
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization, "X-API-Key"},
+	}))
+	e.Use(authConfig.Middleware("/healthz", "/readyz", "/metrics"))
+
+	e.GET("/healthz", status.HandleHealthz)
+	e.GET("/readyz", status.HandleReadyz)
+	e.GET("/metrics", echo.WrapHandler(metrics.Default.Handler()))
 	e.GET("/historical", historic.HandleHistoric)
-	e.GET("/historical/:eventName", websocket.HandleHistoricalWs)
-
-	go e.Logger.Fatal(e.Start(":3000"))
+	e.GET("/calendar", historic.HandleCalendar(serverConfig.CacheDir))
+	e.GET("/calendar/types", historic.HandleSessionTypes)
+	e.GET("/telemetry/channels", historic.HandleTelemetryChannels)
+	hub.Register(e)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Listen before starting the server so it is only reported as ready once connections can be accepted
+	listener, err := net.Listen("tcp", serverConfig.ListenAddress)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+
+	server := e.Server
+	if serverConfig.UseTLS() {
+		certificate, err := tls.LoadX509KeyPair(serverConfig.TLSCertFile, serverConfig.TLSKeyFile)
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+
+		server = e.TLSServer
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			NextProtos:   []string{"h2"},
+		}
+		e.TLSListener = tls.NewListener(listener, server.TLSConfig)
+	} else {
+		e.Listener = listener
+	}
+
+	go func() {
+		startErr := e.StartServer(server)
+		if startErr != nil && startErr != http.ErrServerClosed {
+			e.Logger.Error(startErr)
+		}
+		stop()
+	}()
+	status.SetReady(true)
+
+	<-ctx.Done()
+	e.Logger.Info("Shutting down")
+	status.SetReady(false)
+
+	// Websockets are hijacked connections so the server shutdown doesn't wait for them, close them and the
+	// replays they are watching first
+	hub.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+	if err = e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Error(err)
+	}
 }
 
 // import (
@@ -0,0 +1,323 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds a set of metrics and writes them in the Prometheus text exposition format
+type Registry struct {
+	lock    sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// Default is the registry the library records its metrics in
+var Default = CreateRegistry()
+
+func CreateRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]bool),
+	}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.names[name] {
+		panic("metric already registered: " + name)
+	}
+
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write outputs every metric in the Prometheus text format
+func (r *Registry) Write(w io.Writer) {
+	r.lock.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.lock.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the metrics for scraping
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.Write(w)
+	})
+}
+
+// vec is the set of values for a metric keyed by the label values
+type vec[T any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	lock   sync.Mutex
+	values map[string]*T
+	keys   map[string][]string
+
+	create func() *T
+}
+
+func (v *vec[T]) with(labelValues ...string) *T {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d labels but got %d", v.name, len(v.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	value, exists := v.values[key]
+	if !exists {
+		value = v.create()
+		v.values[key] = value
+		v.keys[key] = labelValues
+	}
+
+	return value
+}
+
+func (v *vec[T]) each(f func(labels string, value *T)) {
+	v.lock.Lock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]*T, len(keys))
+	labels := make([]string, len(keys))
+	for x, key := range keys {
+		values[x] = v.values[key]
+		labels[x] = v.formatLabels(v.keys[key])
+	}
+	v.lock.Unlock()
+
+	for x := range values {
+		f(labels[x], values[x])
+	}
+}
+
+func (v *vec[T]) formatLabels(values []string, extra ...string) string {
+	parts := make([]string, 0, len(values)+len(extra)/2)
+	for x, value := range values {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", v.labels[x], escape(value)))
+	}
+	for x := 0; x+1 < len(extra); x += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", extra[x], escape(extra[x+1])))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func (v *vec[T]) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+}
+
+func newVec[T any](name string, help string, kind string, labels []string, create func() *T) vec[T] {
+	return vec[T]{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: make(map[string]*T),
+		keys:   make(map[string][]string),
+		create: create,
+	}
+}
+
+// Value is a single counter or gauge value
+type Value struct {
+	lock  sync.Mutex
+	value float64
+}
+
+func (v *Value) Inc() {
+	v.Add(1)
+}
+
+func (v *Value) Dec() {
+	v.Add(-1)
+}
+
+func (v *Value) Add(amount float64) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.value += amount
+}
+
+func (v *Value) Set(value float64) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.value = value
+}
+
+func (v *Value) Get() float64 {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.value
+}
+
+// CounterVec is a count that only goes up, split by the label values
+type CounterVec struct {
+	vec[Value]
+}
+
+func (r *Registry) CounterVec(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels, func() *Value { return &Value{} })}
+	r.register(name, c)
+	return c
+}
+
+func (c *CounterVec) With(labelValues ...string) *Value {
+	return c.with(labelValues...)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.writeHeader(w)
+	c.each(func(labels string, value *Value) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatFloat(value.Get()))
+	})
+}
+
+// GaugeVec is a value that can go up and down, split by the label values
+type GaugeVec struct {
+	vec[Value]
+}
+
+func (r *Registry) GaugeVec(name string, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels, func() *Value { return &Value{} })}
+	r.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) With(labelValues ...string) *Value {
+	return g.with(labelValues...)
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.writeHeader(w)
+	g.each(func(labels string, value *Value) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatFloat(value.Get()))
+	})
+}
+
+// Histogram counts observations into buckets
+type Histogram struct {
+	lock    sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for x, bound := range h.buckets {
+		if value <= bound {
+			h.counts[x]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// HistogramVec is a histogram split by the label values
+type HistogramVec struct {
+	vec[Histogram]
+}
+
+// DefaultBuckets cover 1ms to 10s which suits network latency
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+func (r *Registry) HistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{newVec(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{
+			buckets: sorted,
+			counts:  make([]uint64, len(sorted)),
+		}
+	})}
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) With(labelValues ...string) *Histogram {
+	return h.with(labelValues...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.writeHeader(w)
+
+	h.vec.lock.Lock()
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	h.vec.lock.Unlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		h.vec.lock.Lock()
+		histogram := h.values[key]
+		labelValues := h.keys[key]
+		h.vec.lock.Unlock()
+
+		histogram.lock.Lock()
+		for x, bound := range histogram.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(labelValues, "le", formatFloat(bound)), histogram.counts[x])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(labelValues, "le", "+Inf"), histogram.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.formatLabels(labelValues), formatFloat(histogram.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.formatLabels(labelValues), histogram.count)
+		histogram.lock.Unlock()
+	}
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escape(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	registry := CreateRegistry()
+
+	counter := registry.CounterVec("test_total", "A counter", "feed")
+	counter.With("TimingData").Inc()
+	counter.With("Weather\"Data").Add(2)
+
+	gauge := registry.GaugeVec("test_clients", "A gauge")
+	gauge.With().Inc()
+	gauge.With().Inc()
+	gauge.With().Dec()
+
+	histogram := registry.HistogramVec("test_seconds", "A histogram", []float64{1, 0.1}, "type")
+	histogram.With("TIMING").Observe(0.05)
+	histogram.With("TIMING").Observe(0.5)
+
+	var output bytes.Buffer
+	registry.Write(&output)
+
+	expected := `# HELP test_total A counter
+# TYPE test_total counter
+test_total{feed="TimingData"} 1
+test_total{feed="Weather\"Data"} 2
+# HELP test_clients A gauge
+# TYPE test_clients gauge
+test_clients 1
+# HELP test_seconds A histogram
+# TYPE test_seconds histogram
+test_seconds_bucket{type="TIMING",le="0.1"} 1
+test_seconds_bucket{type="TIMING",le="1"} 2
+test_seconds_bucket{type="TIMING",le="+Inf"} 2
+test_seconds_sum{type="TIMING"} 0.55
+test_seconds_count{type="TIMING"} 2
+`
+	if output.String() != expected {
+		t.Errorf("unexpected output:\n%s", output.String())
+	}
+}
+
+func TestDuplicateNamePanics(t *testing.T) {
+	registry := CreateRegistry()
+	registry.CounterVec("test_total", "A counter")
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "test_total") {
+			t.Errorf("expected a panic for the duplicate name, got: %v", r)
+		}
+	}()
+	registry.CounterVec("test_total", "A counter")
+}
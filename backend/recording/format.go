@@ -0,0 +1,142 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// A recording is the parsed message stream for a session stored as:
+//
+//	magic | version | header length | header JSON
+//	block length | record count | flate compressed records   (repeated)
+//	index length | index JSON
+//	index offset | end magic
+//
+// Each record in a block is its length as a uvarint followed by the message kind, the sequence number as a uvarint
+// and the message as JSON. All fixed size numbers are big endian. The index and end magic are written when the
+// recording is closed so a recording that was cut short can still be read from the start.
+
+const Version uint16 = 1
+
+var magic = [8]byte{'F', '1', 'G', 'O', 'R', 'E', 'C', 0}
+var endMagic = [8]byte{'F', '1', 'G', 'O', 'I', 'D', 'X', 0}
+
+// footerSize is the index offset and the end magic
+const footerSize = 8 + 8
+
+var ErrBadRecording = errors.New("not a f1gopher recording")
+
+// Info describes the session that was recorded
+type Info struct {
+	Name              string
+	Session           Messages.SessionType
+	Track             string
+	TrackYear         int
+	Timezone          string
+	SessionStart      time.Time
+	TimeLostInPitlane time.Duration
+	Created           time.Time
+}
+
+// Index lets a reader jump to a time or lap without reading everything before it
+type Index struct {
+	Blocks []BlockEntry
+	Laps   []LapEntry
+
+	// When the session status first changed to started
+	SessionStart time.Time
+
+	Messages uint64
+}
+
+type BlockEntry struct {
+	Offset    int64
+	Records   int
+	Sequence  uint64
+	Timestamp time.Time
+}
+
+type LapEntry struct {
+	Lap       int
+	Block     int
+	Sequence  uint64
+	Timestamp time.Time
+}
+
+func encodeRecord(buffer *bytes.Buffer, envelope Messages.Envelope) error {
+	data, err := json.Marshal(envelope.Payload)
+	if err != nil {
+		return err
+	}
+
+	var header [1 + binary.MaxVarintLen64]byte
+	header[0] = byte(envelope.Kind)
+	headerLength := 1 + binary.PutUvarint(header[1:], envelope.Sequence)
+
+	var length [binary.MaxVarintLen64]byte
+	buffer.Write(length[:binary.PutUvarint(length[:], uint64(headerLength+len(data)))])
+	buffer.Write(header[:headerLength])
+	buffer.Write(data)
+
+	return nil
+}
+
+func decodeRecord(record []byte) (Messages.Envelope, error) {
+	if len(record) < 2 {
+		return Messages.Envelope{}, fmt.Errorf("record too short: %d bytes", len(record))
+	}
+
+	kind := Messages.Kind(record[0])
+	sequence, size := binary.Uvarint(record[1:])
+	if size <= 0 {
+		return Messages.Envelope{}, errors.New("invalid record sequence number")
+	}
+
+	payload, err := decodePayload(kind, record[1+size:])
+	if err != nil {
+		return Messages.Envelope{}, fmt.Errorf("record %d: %w", sequence, err)
+	}
+
+	return Messages.CreateEnvelope(payload, sequence), nil
+}
+
+func decodePayload(kind Messages.Kind, data []byte) (Messages.Payload, error) {
+	switch kind {
+	case Messages.WeatherKind:
+		return decodeAs[Messages.Weather](data)
+	case Messages.RaceControlMessageKind:
+		return decodeAs[Messages.RaceControlMessage](data)
+	case Messages.TimingKind:
+		return decodeAs[Messages.Timing](data)
+	case Messages.EventKind:
+		return decodeAs[Messages.Event](data)
+	case Messages.TelemetryKind:
+		return decodeAs[Messages.Telemetry](data)
+	case Messages.LocationKind:
+		return decodeAs[Messages.Location](data)
+	case Messages.EventTimeKind:
+		return decodeAs[Messages.EventTime](data)
+	case Messages.RadioKind:
+		return decodeAs[Messages.Radio](data)
+	case Messages.DriversKind:
+		return decodeAs[Messages.Drivers](data)
+	case Messages.CarStateKind:
+		return decodeAs[Messages.CarState](data)
+	default:
+		return nil, fmt.Errorf("unknown message kind: %d", kind)
+	}
+}
+
+func decodeAs[T Messages.Payload](data []byte) (Messages.Payload, error) {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
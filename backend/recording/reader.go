@@ -0,0 +1,258 @@
+package recording
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+type Reader struct {
+	input  io.ReaderAt
+	closer io.Closer
+
+	info  Info
+	index Index
+	// False if the recording was cut short and has no index
+	indexed bool
+
+	// Where the blocks end
+	dataEnd int64
+	// The next block to read
+	offset int64
+	// Records that haven't been returned yet from the current block
+	records *bytes.Reader
+	// Records before this sequence number are skipped after seeking
+	skipBefore uint64
+}
+
+// Open reads the recording file
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	reader, err := CreateReader(file, stat.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading recording '%s': %w", path, err)
+	}
+	reader.closer = file
+
+	return reader, nil
+}
+
+// CreateReader reads a recording of the given size
+func CreateReader(input io.ReaderAt, size int64) (*Reader, error) {
+	r := &Reader{
+		input:   input,
+		dataEnd: size,
+	}
+
+	var header [len(magic) + 2 + 4]byte
+	if _, err := input.ReadAt(header[:], 0); err != nil {
+		return nil, ErrBadRecording
+	}
+	if !bytes.Equal(header[:len(magic)], magic[:]) {
+		return nil, ErrBadRecording
+	}
+
+	version := binary.BigEndian.Uint16(header[len(magic):])
+	if version != Version {
+		return nil, fmt.Errorf("unsupported recording version %d", version)
+	}
+
+	infoLength := int64(binary.BigEndian.Uint32(header[len(magic)+2:]))
+	infoData := make([]byte, infoLength)
+	if _, err := input.ReadAt(infoData, int64(len(header))); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if err := json.Unmarshal(infoData, &r.info); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	r.offset = int64(len(header)) + infoLength
+
+	// The recording may not have been closed properly in which case it can still be read from the start
+	if err := r.readIndex(size); err == nil {
+		r.indexed = true
+	}
+
+	return r, nil
+}
+
+func (r *Reader) Info() Info {
+	return r.info
+}
+
+// Index is empty when the recording wasn't closed properly
+func (r *Reader) Index() Index {
+	return r.index
+}
+
+// Next is the next message in the order they were recorded and io.EOF at the end of the recording
+func (r *Reader) Next() (Messages.Envelope, error) {
+	for {
+		if r.records == nil || r.records.Len() == 0 {
+			if err := r.readBlock(); err != nil {
+				return Messages.Envelope{}, err
+			}
+		}
+
+		length, err := binary.ReadUvarint(r.records)
+		if err != nil {
+			return Messages.Envelope{}, fmt.Errorf("reading record length: %w", err)
+		}
+
+		record := make([]byte, length)
+		if _, err = io.ReadFull(r.records, record); err != nil {
+			return Messages.Envelope{}, fmt.Errorf("reading record: %w", err)
+		}
+
+		envelope, err := decodeRecord(record)
+		if err != nil {
+			return Messages.Envelope{}, err
+		}
+
+		if envelope.Sequence < r.skipBefore {
+			continue
+		}
+
+		return envelope, nil
+	}
+}
+
+// SeekTime moves to the first message at or after the time
+func (r *Reader) SeekTime(timestamp time.Time) error {
+	if !r.indexed || len(r.index.Blocks) == 0 {
+		return errors.New("recording has no index")
+	}
+
+	// The last block that starts before the time
+	block := sort.Search(len(r.index.Blocks), func(x int) bool {
+		return r.index.Blocks[x].Timestamp.After(timestamp)
+	}) - 1
+	if block < 0 {
+		block = 0
+	}
+
+	r.seekBlock(block, 0)
+
+	// Skip through the block to the first message at the time
+	for {
+		envelope, err := r.Next()
+		if err != nil {
+			return err
+		}
+
+		if !envelope.Timestamp.Before(timestamp) {
+			r.seekBlock(block, envelope.Sequence)
+			return nil
+		}
+	}
+}
+
+// SeekLap moves to the first message of the lap
+func (r *Reader) SeekLap(lap int) error {
+	for _, entry := range r.index.Laps {
+		if entry.Lap == lap {
+			r.seekBlock(entry.Block, entry.Sequence)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("lap %d not found in recording", lap)
+}
+
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+func (r *Reader) seekBlock(block int, sequence uint64) {
+	r.offset = r.index.Blocks[block].Offset
+	r.records = nil
+	r.skipBefore = sequence
+}
+
+func (r *Reader) readBlock() error {
+	var header [8]byte
+	if r.offset+int64(len(header)) > r.dataEnd {
+		return io.EOF
+	}
+	if _, err := r.input.ReadAt(header[:], r.offset); err != nil {
+		// A recording that was cut short ends with a partial block
+		return io.EOF
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[:4]))
+	if r.offset+int64(len(header))+length > r.dataEnd {
+		return io.EOF
+	}
+
+	decompressor := flate.NewReader(io.NewSectionReader(r.input, r.offset+int64(len(header)), length))
+	defer decompressor.Close()
+
+	data, err := io.ReadAll(decompressor)
+	if err != nil {
+		return fmt.Errorf("reading block at %d: %w", r.offset, err)
+	}
+
+	r.offset += int64(len(header)) + length
+	r.records = bytes.NewReader(data)
+
+	return nil
+}
+
+func (r *Reader) readIndex(size int64) error {
+	if size < footerSize {
+		return ErrBadRecording
+	}
+
+	var footer [footerSize]byte
+	if _, err := r.input.ReadAt(footer[:], size-footerSize); err != nil {
+		return err
+	}
+	if !bytes.Equal(footer[8:], endMagic[:]) {
+		return ErrBadRecording
+	}
+
+	indexOffset := int64(binary.BigEndian.Uint64(footer[:8]))
+	if indexOffset < r.offset || indexOffset+4 > size-footerSize {
+		return ErrBadRecording
+	}
+
+	var lengthData [4]byte
+	if _, err := r.input.ReadAt(lengthData[:], indexOffset); err != nil {
+		return err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(lengthData[:]))
+	if _, err := r.input.ReadAt(data, indexOffset+4); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &r.index); err != nil {
+		return err
+	}
+
+	r.dataEnd = indexOffset
+	return nil
+}
@@ -0,0 +1,245 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/f1log"
+)
+
+var start = time.Date(2030, 3, 17, 4, 0, 0, 0, time.UTC)
+
+// testMessages is a lap's worth of messages every minute with timing and telemetry in between
+func testMessages(count int) []Messages.Envelope {
+	result := make([]Messages.Envelope, 0, count)
+
+	for x := 0; x < count; x++ {
+		timestamp := start.Add(time.Second * time.Duration(x))
+
+		var payload Messages.Payload
+		switch {
+		case x%60 == 0:
+			payload = Messages.Event{Timestamp: timestamp, CurrentLap: x/60 + 1, Status: Messages.Started}
+		case x%2 == 0:
+			payload = Messages.Timing{Timestamp: timestamp, Number: x % 20, Lap: x / 60}
+		default:
+			payload = Messages.Telemetry{Timestamp: timestamp, DriverNumber: x % 20, RPM: int16(x)}
+		}
+
+		result = append(result, Messages.CreateEnvelope(payload, uint64(x+1)))
+	}
+
+	return result
+}
+
+func writeRecording(t *testing.T, messages []Messages.Envelope, close bool) []byte {
+	t.Helper()
+
+	var output bytes.Buffer
+	writer, err := CreateWriter(&output, Info{Name: "Australian Grand Prix", Session: Messages.RaceSession})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range messages {
+		if err = writer.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if close {
+		if err = writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return output.Bytes()
+}
+
+func readAll(t *testing.T, reader *Reader) []Messages.Envelope {
+	t.Helper()
+
+	var result []Messages.Envelope
+	for {
+		msg, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return result
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, msg)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	messages := testMessages(recordsPerBlock*2 + 500)
+	data := writeRecording(t, messages, true)
+
+	reader, err := CreateReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.Info().Name != "Australian Grand Prix" || reader.Info().Session != Messages.RaceSession {
+		t.Errorf("unexpected info: %+v", reader.Info())
+	}
+
+	index := reader.Index()
+	if len(index.Blocks) != 3 || index.Messages != uint64(len(messages)) || !index.SessionStart.Equal(start) {
+		t.Errorf("unexpected index: %d blocks, %d messages, started %v", len(index.Blocks), index.Messages, index.SessionStart)
+	}
+
+	read := readAll(t, reader)
+	if len(read) != len(messages) {
+		t.Fatalf("expected %d messages, got %d", len(messages), len(read))
+	}
+
+	for x := range messages {
+		if read[x].Sequence != messages[x].Sequence || read[x].Kind != messages[x].Kind || !read[x].Timestamp.Equal(messages[x].Timestamp) {
+			t.Fatalf("message %d differs: expected %+v, got %+v", x, messages[x], read[x])
+		}
+	}
+
+	if telemetry := read[1].Payload.(Messages.Telemetry); telemetry.RPM != 1 || telemetry.DriverNumber != 1 {
+		t.Errorf("unexpected telemetry: %+v", telemetry)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	messages := testMessages(recordsPerBlock * 3)
+	data := writeRecording(t, messages, true)
+
+	reader, err := CreateReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lap 30 starts in the second block
+	if err = reader.SeekLap(30); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event, ok := msg.Payload.(Messages.Event); !ok || event.CurrentLap != 30 {
+		t.Errorf("expected the start of lap 30, got %+v", msg)
+	}
+
+	target := start.Add(time.Second * 2500)
+	if err = reader.SeekTime(target); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err = reader.Next(); err != nil || !msg.Timestamp.Equal(target) {
+		t.Errorf("expected the message at %v, got %v: %v", target, msg.Timestamp, err)
+	}
+}
+
+func playSource(t *testing.T, seekable bool, skip func(source *Source)) []Messages.Envelope {
+	t.Helper()
+
+	messages := testMessages(recordsPerBlock * 3)
+	data := writeRecording(t, messages, true)
+
+	reader, err := CreateReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+
+	source := CreateSource(ctx, &wg, f1log.CreateLog(), reader, seekable)
+	_, feed := source.Connect()
+
+	// Skip once playing has started
+	result := []Messages.Envelope{<-feed}
+	skip(source)
+	for msg := range feed {
+		result = append(result, msg)
+	}
+
+	wg.Wait()
+	return result
+}
+
+func TestSourceSeeks(t *testing.T) {
+	skip := func(source *Source) {
+		source.IncrementTime(time.Minute * 20)
+	}
+
+	if played := playSource(t, false, skip); len(played) != recordsPerBlock*3 {
+		t.Errorf("expected every message without seeking, got %d", len(played))
+	}
+
+	// The messages already read are still played then it jumps forward
+	played := playSource(t, true, skip)
+	if len(played) >= recordsPerBlock*3 {
+		t.Fatalf("expected messages to be skipped, got %d", len(played))
+	}
+	gap := false
+	for x := 1; x < len(played); x++ {
+		if played[x].Timestamp.Sub(played[x-1].Timestamp) >= time.Minute*20 {
+			gap = true
+		}
+	}
+	if !gap {
+		t.Error("expected a 20 minute jump in the played messages")
+	}
+
+	// Skipping a lap drops the rest of the lap being read and carries on from the start of the next one
+	played = playSource(t, true, func(source *Source) {
+		source.IncrementLap()
+	})
+	if len(played) >= recordsPerBlock*3 {
+		t.Fatalf("expected messages to be skipped, got %d", len(played))
+	}
+	lap := 0
+	for _, msg := range played {
+		if event, ok := msg.Payload.(Messages.Event); ok {
+			if event.CurrentLap != lap+1 {
+				t.Fatalf("expected lap %d to start after lap %d, got %d", lap+1, lap, event.CurrentLap)
+			}
+			lap = event.CurrentLap
+		}
+	}
+	if lap != recordsPerBlock*3/60 {
+		t.Errorf("expected to play to lap %d, got %d", recordsPerBlock*3/60, lap)
+	}
+}
+
+func TestReadUnfinishedRecording(t *testing.T) {
+	messages := testMessages(recordsPerBlock*2 + 10)
+
+	// The last partial block is never written and the end of the last full block is cut off
+	data := writeRecording(t, messages, false)
+	data = data[:len(data)-10]
+
+	reader, err := CreateReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read := readAll(t, reader); len(read) != recordsPerBlock {
+		t.Errorf("expected the first block of %d messages, got %d", recordsPerBlock, len(read))
+	}
+
+	if err = reader.SeekLap(1); err == nil {
+		t.Error("expected seeking to fail without an index")
+	}
+}
+
+func TestNotARecording(t *testing.T) {
+	data := []byte("Position.z\r\n{}\r\n2030-03-17T04:00:00Z\r\n")
+	if _, err := CreateReader(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrBadRecording) {
+		t.Errorf("expected a bad recording error, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+	"github.com/f1gopher/f1gopherlib/f1log"
+)
+
+// Source plays back a recording like a connection does for the raw data. Messages are read as fast as they are
+// taken and the flow control handles the timing.
+//
+// When seekable, skipping forward in time, a lap or to the session start moves the reader using the index and the
+// messages skipped over are never sent. Otherwise skipping does nothing here because the realtime flow control already
+// has the messages and skips through them itself.
+type Source struct {
+	log    *f1log.F1GopherLibLog
+	reader *Reader
+
+	dataFeed chan Messages.Envelope
+
+	sessionStartLock sync.Mutex
+	sessionStart     time.Time
+
+	seekable bool
+	seekLock sync.Mutex
+	// Skips asked for that the reader hasn't done yet
+	skipTime  time.Duration
+	skipLaps  int
+	skipStart time.Time
+	// Where the reader has got to
+	lastTimestamp time.Time
+	currentLap    int
+
+	ctx context.Context
+	wg  *sync.WaitGroup
+}
+
+// CreateSource plays back the reader which is closed when the end of the recording is reached
+func CreateSource(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	log *f1log.F1GopherLibLog,
+	reader *Reader,
+	seekable bool) *Source {
+
+	return &Source{
+		ctx:          ctx,
+		wg:           wg,
+		log:          log,
+		reader:       reader,
+		sessionStart: reader.Index().SessionStart,
+		dataFeed:     make(chan Messages.Envelope, 100),
+		seekable:     seekable,
+	}
+}
+
+func (s *Source) Connect() (error, <-chan Messages.Envelope) {
+	s.wg.Add(1)
+	go s.readEntries()
+
+	return nil, s.dataFeed
+}
+
+func (s *Source) readEntries() {
+	defer s.wg.Done()
+	defer close(s.dataFeed)
+	defer s.reader.Close()
+
+	for {
+		s.seek()
+
+		envelope, err := s.reader.Next()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			s.log.Errorf("Recording read error: %v", err)
+			return
+		}
+
+		s.seekLock.Lock()
+		s.lastTimestamp = envelope.Timestamp
+		if event, ok := envelope.Payload.(Messages.Event); ok {
+			s.currentLap = event.CurrentLap
+		}
+		s.seekLock.Unlock()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case s.dataFeed <- envelope:
+		}
+	}
+}
+
+// seek does the skips that have been asked for since the last message was read. The messages already waiting to be
+// taken are still sent.
+func (s *Source) seek() {
+	s.seekLock.Lock()
+	skipTime, skipLaps, skipStart := s.skipTime, s.skipLaps, s.skipStart
+	s.skipTime, s.skipLaps, s.skipStart = 0, 0, time.Time{}
+	lastTimestamp, currentLap := s.lastTimestamp, s.currentLap
+	s.seekLock.Unlock()
+
+	var err error
+	switch {
+	case skipLaps > 0:
+		err = s.reader.SeekLap(currentLap + skipLaps)
+	case !skipStart.IsZero() && skipStart.After(lastTimestamp):
+		err = s.reader.SeekTime(skipStart)
+	case skipTime > 0 && !lastTimestamp.IsZero():
+		err = s.reader.SeekTime(lastTimestamp.Add(skipTime))
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.log.Errorf("Recording seek error: %v", err)
+	}
+}
+
+func (s *Source) IncrementTime(amount time.Duration) {
+	if !s.seekable {
+		return
+	}
+
+	s.seekLock.Lock()
+	defer s.seekLock.Unlock()
+	s.skipTime += amount
+}
+
+// IncrementLap skips to the start of the next lap
+func (s *Source) IncrementLap() {
+	if !s.seekable {
+		return
+	}
+
+	s.seekLock.Lock()
+	defer s.seekLock.Unlock()
+	s.skipLaps++
+}
+
+// JumpToStart gives the time the session started, only once so we can't travel back in time
+func (s *Source) JumpToStart() time.Time {
+	s.sessionStartLock.Lock()
+	defer s.sessionStartLock.Unlock()
+
+	start := s.sessionStart
+	s.sessionStart = time.Time{}
+
+	if s.seekable && !start.IsZero() {
+		s.seekLock.Lock()
+		s.skipStart = start
+		s.seekLock.Unlock()
+	}
+
+	return start
+}
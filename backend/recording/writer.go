@@ -0,0 +1,230 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/f1gopher/f1gopherlib/Messages"
+)
+
+// How many records go in a block. Smaller blocks make seeking more precise but compress less well.
+const recordsPerBlock = 1000
+
+type Writer struct {
+	output io.Writer
+	closer io.Closer
+	offset int64
+
+	block        bytes.Buffer
+	blockRecords int
+	blockEntry   BlockEntry
+
+	index          Index
+	currentLap     int
+	sessionStarted bool
+
+	compressor *flate.Writer
+}
+
+// Create starts a new recording file
+func Create(path string, info Info) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewWriter(file)
+	writer, err := CreateWriter(buffered, info)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	writer.closer = closerFunc(func() error {
+		if err := buffered.Flush(); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	})
+
+	return writer, nil
+}
+
+// CreateWriter writes a recording to the output. The output isn't closed when the writer is closed.
+func CreateWriter(output io.Writer, info Info) (*Writer, error) {
+	if info.Created.IsZero() {
+		info.Created = time.Now().UTC()
+	}
+
+	compressor, err := flate.NewWriter(nil, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		output:     output,
+		compressor: compressor,
+	}
+
+	header, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = w.write(magic[:]); err != nil {
+		return nil, err
+	}
+	if err = w.writeNumber(Version); err != nil {
+		return nil, err
+	}
+	if err = w.writeNumber(uint32(len(header))); err != nil {
+		return nil, err
+	}
+	if err = w.write(header); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write adds the message to the recording
+func (w *Writer) Write(envelope Messages.Envelope) error {
+	if w.blockRecords == 0 {
+		w.blockEntry = BlockEntry{
+			Offset:    w.offset,
+			Sequence:  envelope.Sequence,
+			Timestamp: envelope.Timestamp,
+		}
+	}
+
+	if event, ok := envelope.Payload.(Messages.Event); ok {
+		if event.CurrentLap > w.currentLap {
+			w.currentLap = event.CurrentLap
+			w.index.Laps = append(w.index.Laps, LapEntry{
+				Lap:       event.CurrentLap,
+				Block:     len(w.index.Blocks),
+				Sequence:  envelope.Sequence,
+				Timestamp: envelope.Timestamp,
+			})
+		}
+
+		if !w.sessionStarted && event.Status == Messages.Started {
+			w.sessionStarted = true
+			w.index.SessionStart = envelope.Timestamp
+		}
+	}
+
+	if err := encodeRecord(&w.block, envelope); err != nil {
+		return err
+	}
+	w.blockRecords++
+	w.index.Messages++
+
+	if w.blockRecords >= recordsPerBlock {
+		return w.flushBlock()
+	}
+
+	return nil
+}
+
+// WriteAll writes every message from the channel until it is closed
+func (w *Writer) WriteAll(envelopes <-chan Messages.Envelope) error {
+	for envelope := range envelopes {
+		if err := w.Write(envelope); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes any remaining messages and the index
+func (w *Writer) Close() error {
+	err := w.flushBlock()
+
+	if err == nil {
+		err = w.writeIndex()
+	}
+
+	if w.closer != nil {
+		if closeErr := w.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func (w *Writer) flushBlock() error {
+	if w.blockRecords == 0 {
+		return nil
+	}
+
+	var compressed bytes.Buffer
+	w.compressor.Reset(&compressed)
+	if _, err := w.compressor.Write(w.block.Bytes()); err != nil {
+		return err
+	}
+	if err := w.compressor.Close(); err != nil {
+		return err
+	}
+
+	w.blockEntry.Records = w.blockRecords
+	w.index.Blocks = append(w.index.Blocks, w.blockEntry)
+	w.block.Reset()
+	w.blockRecords = 0
+
+	if err := w.writeNumber(uint32(compressed.Len())); err != nil {
+		return err
+	}
+	if err := w.writeNumber(uint32(w.blockEntry.Records)); err != nil {
+		return err
+	}
+	return w.write(compressed.Bytes())
+}
+
+func (w *Writer) writeIndex() error {
+	data, err := json.Marshal(w.index)
+	if err != nil {
+		return err
+	}
+
+	indexOffset := w.offset
+	if err = w.writeNumber(uint32(len(data))); err != nil {
+		return err
+	}
+	if err = w.write(data); err != nil {
+		return err
+	}
+	if err = w.writeNumber(uint64(indexOffset)); err != nil {
+		return err
+	}
+	return w.write(endMagic[:])
+}
+
+func (w *Writer) write(data []byte) error {
+	written, err := w.output.Write(data)
+	w.offset += int64(written)
+	return err
+}
+
+func (w *Writer) writeNumber(value any) error {
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.BigEndian, value); err != nil {
+		return err
+	}
+	return w.write(buffer.Bytes())
+}
+
+type closerFunc func() error
+
+func (c closerFunc) Close() error {
+	return c()
+}
@@ -10,6 +10,11 @@ export type Driver = {
   TimeDiffToFastest: number;
   TimeDiffToPositionAhead: number;
   GapToLeader: number;
+  // For lapped cars the gaps are in laps and the times are 0
+  IntervalLaps: number;
+  GapToLeaderLaps: number;
+  // The lap the leader is on, only set for the leader
+  LeaderLap: number;
   PreviousSegmentIndex: number;
   Segment: number[];
   Sector1: number;
@@ -27,6 +32,12 @@ export type Driver = {
   FastestLap: number;
   OverallFastestLap: boolean;
   KnockedOutOfQualifying: boolean;
+  // Best lap in each part of qualifying
+  QualifyingBestLaps: number[];
+  // Would be knocked out if this part of qualifying ended now
+  InDropZone: boolean;
+  // How much faster than their best lap in this part the driver needs to go, 0 when not in the drop zone
+  TimeToProgress: number;
   ChequeredFlag: boolean;
   Tire: number;
   LapsOnTire: number;
@@ -56,3 +67,24 @@ export type PitStop = {
   PitlaneExit: number;
   PitlaneTime: number;
 };
+
+// A driver from the driver list
+export type DriverInfo = {
+  StartPosition: number;
+  Line: number;
+  Name: string;
+  ShortName: string;
+  Number: number;
+  Team: string;
+  HexColor: string;
+  Color: { R: number; G: number; B: number; A: number };
+};
+
+export type Drivers = {
+  Timestamp: Date;
+  // Every driver so far
+  Drivers: DriverInfo[];
+  // Driver numbers that are new or whose details changed
+  Added: number[] | null;
+  Changed: number[] | null;
+};
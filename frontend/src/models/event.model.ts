@@ -19,6 +19,9 @@ export type Event = {
   SessionStartTime: Date;
   ClockStopped: boolean;
   DRSEnabled: number;
+  // For qualifying, the number of cars that progress from this part and the slowest lap that does
+  ProgressingCars: number;
+  KnockoutCutoff: number;
 };
 
 export type Meeting = {
@@ -8,3 +8,31 @@ export type Historical = {
   TrackYearCreated: number;
   TimeLostInPitlane: number;
 };
+
+export enum CalendarSessionStatus {
+  Completed = 0,
+  Live = 1,
+  Upcoming = 2
+}
+
+export type CalendarSession = {
+  Type: number;
+  TypeName: string;
+  EventTime: Date;
+  Timezone: string;
+  Url: string;
+  Status: CalendarSessionStatus;
+  Cached: boolean;
+};
+
+export type Meeting = {
+  Name: string;
+  Country: string;
+  TrackName: string;
+  TrackYearCreated: number;
+  Year: number;
+  RaceTime: Date;
+  Timezone: string;
+  TimeLostInPitlane: number;
+  Sessions: Array<CalendarSession>;
+};
@@ -0,0 +1,32 @@
+// The telemetry for a point on a lap
+export type LapSample = {
+  // Metres from the finish line
+  Distance: number;
+  // Milliseconds since the start of the lap
+  Time: number;
+  RPM: number;
+  Speed: number;
+  Throttle: number;
+  Brake: number;
+  Gear: number;
+  DRS: boolean;
+};
+
+// Both laps at the same distance
+export type LapComparisonPoint = {
+  Distance: number;
+  Reference: LapSample;
+  Other: LapSample;
+  // Milliseconds the other lap is behind the reference, negative when it is ahead
+  Delta: number;
+};
+
+export type LapComparison = {
+  ReferenceDriver: number;
+  ReferenceLap: number;
+  ReferenceTime: number;
+  OtherDriver: number;
+  OtherLap: number;
+  OtherTime: number;
+  Points: LapComparisonPoint[] | null;
+};
@@ -18,7 +18,8 @@ export enum HistoricalSession {
 	QualifyingSession = 3,
 	SprintSession = 4,
 	RaceSession = 5,
-	PreSeasonSession = 6
+	PreSeasonSession = 6,
+	SprintQualifyingSession = 7
 }
 
 export enum Status {
@@ -1,9 +1,33 @@
 export type Telemetry = {
+  // 0 or 100, the feed only sends on or off
   Brake: number;
+  BrakeOn: boolean;
   DRS: boolean;
+  DRSState: CarDRS;
+  // The value from the feed the DRS state is worked out from
+  DRSValue: number;
   DriverNumber: number;
   Gear: number;
   RPM: number;
   Speed: number;
   Throttle: number;
 };
+
+export enum CarDRS {
+  Off = 0,
+  Eligible = 1,
+  Open = 2,
+  Unknown = 3,
+}
+
+// Describes a telemetry value, from /telemetry/channels
+export type TelemetryChannel = {
+  Name: string;
+  // The Telemetry field with the value
+  Field: keyof Telemetry;
+  FeedId: string;
+  Unit: string;
+  Min: number;
+  Max: number;
+  Discrete: boolean;
+};
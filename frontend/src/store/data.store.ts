@@ -1,4 +1,4 @@
-import { Driver } from "@/models/driver.model";
+import { Driver, Drivers } from "@/models/driver.model";
 import { Telemetry } from "@/models/telemetry.model";
 import { set } from "@vueuse/core";
 import { reactive } from "vue";
@@ -14,7 +14,28 @@ export const useDriverStore = reactive({
   drivers: [] as Driver[],
   increment(item: Driver) {
     const i = this.drivers.findIndex((e) => e.Number === item.Number);
-    set(this.drivers, i, item);
+    if (i === -1) {
+      this.drivers.push(item);
+    } else {
+      set(this.drivers, i, item);
+    }
+  },
+  // The driver list is sent again when someone is added or changes so keep the timing for drivers already known
+  addDrivers(item: Drivers) {
+    for (const info of item.Drivers) {
+      const driver = this.drivers.find((e) => e.Number === info.Number);
+      if (driver) {
+        Object.assign(driver, {
+          Name: info.Name,
+          ShortName: info.ShortName,
+          Team: info.Team,
+          HexColor: info.HexColor,
+          Color: info.Color,
+        });
+      } else {
+        this.drivers.push({ ...info, Position: info.Line } as unknown as Driver);
+      }
+    }
   },
 });
 
@@ -26,7 +26,7 @@ export const parseData = (data: ServerResponse) => {
       break;
 
     case "DRIVERS":
-      useDriverStore.drivers = data.data.Drivers;
+      useDriverStore.addDrivers(data.data);
       break;
 
     case "EVENT":
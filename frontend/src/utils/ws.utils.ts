@@ -1,16 +1,42 @@
 import { ref } from "vue";
+import { LapComparison } from "@/models/lap-comparison.model";
 import { parseData } from "./parse-data.utils";
 
 let wsUrl = '';
+let actionsUrl = '';
+let compareUrl = '';
+
+// The key is passed as a query parameter because browsers can't set headers when opening a websocket
+export const apiKey = () => localStorage.getItem("apiKey") ?? ""
+
+export const authHeaders = (): Record<string, string> => {
+  const key = apiKey()
+  return key ? { 'X-API-Key': key } : {}
+}
 
 let ws = ref<WebSocket | null>(null)
 const connected = ref<boolean>(false)
 
-export const generateWsUrl = (ops?: string | string[]) => {
+export const generateWsUrl = (ops?: string | string[], session?: string | string[], year?: string | string[]) => {
   // if (ops) wsUrl = `wss://stunning-system-j4wxj4p5v4j3555p-3000.app.github.dev/historical/${ops}`
   // else wsUrl = `wss://stunning-system-j4wxj4p5v4j3555p-3000.app.github.dev/ws`
   if (ops) wsUrl = `ws://localhost:3000/historical/${ops}`
   else wsUrl = `ws://localhost:3000/ws`
+
+  // The year and session pick the session, meetings have the same name every year
+  const sessionParams = new URLSearchParams()
+  if (year) sessionParams.append("year", year.toString())
+  if (session) sessionParams.append("session", session.toString())
+
+  const params = new URLSearchParams(sessionParams)
+  if (apiKey()) params.append("token", apiKey())
+  if (params.size > 0) wsUrl += `?${params}`
+
+  actionsUrl = `http://localhost:3000/historical/${ops}/actions`
+  if (sessionParams.size > 0) actionsUrl += `?${sessionParams}`
+
+  compareUrl = `http://localhost:3000/historical/${ops}/compare`
+  if (sessionParams.size > 0) compareUrl += `?${sessionParams}`
 }
 
 export const initWs = () => {
@@ -42,3 +68,29 @@ export const getWs = () => {
   if (ws.value !== null) return ws.value
   return null
 }
+
+export const sendAction = async (action: Record<string, boolean>) => {
+  return await fetch(actionsUrl, {
+    headers: {
+      'Content-type': 'application/json',
+      ...authHeaders()
+    },
+    method: 'POST',
+    body: JSON.stringify(action)
+  })
+}
+
+// Lines up two laps that have been played in the session being watched
+export const fetchLapComparison = async (driver: number, lap: number, otherDriver: number, otherLap: number): Promise<LapComparison> => {
+  const url = new URL(compareUrl)
+  url.searchParams.append("driver", driver.toString())
+  url.searchParams.append("lap", lap.toString())
+  url.searchParams.append("otherDriver", otherDriver.toString())
+  url.searchParams.append("otherLap", otherLap.toString())
+
+  const response = await fetch(url, { headers: authHeaders() })
+  if (!response.ok) {
+    throw new Error((await response.json())?.message ?? response.statusText)
+  }
+  return await response.json()
+}